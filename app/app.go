@@ -2,6 +2,8 @@ package app
 
 import "C"
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -15,13 +17,17 @@ import (
 	"github.com/wieku/danser-go/app/discord"
 	"github.com/wieku/danser-go/app/ffmpeg"
 	"github.com/wieku/danser-go/app/input"
+	"github.com/wieku/danser-go/app/liveserver"
 	"github.com/wieku/danser-go/app/settings"
 	"github.com/wieku/danser-go/app/states"
+	"github.com/wieku/danser-go/app/status"
+	"github.com/wieku/danser-go/app/thumbnail"
 	"github.com/wieku/danser-go/app/utils"
 	"github.com/wieku/danser-go/build"
 	"github.com/wieku/danser-go/framework/assets"
 	"github.com/wieku/danser-go/framework/bass"
 	"github.com/wieku/danser-go/framework/env"
+	"github.com/wieku/danser-go/framework/files"
 	"github.com/wieku/danser-go/framework/frame"
 	"github.com/wieku/danser-go/framework/goroutines"
 	batch2 "github.com/wieku/danser-go/framework/graphics/batch"
@@ -38,10 +44,14 @@ import (
 	"io/ioutil"
 	"log"
 	"math"
+	"math/rand"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"runtime"
 	"slices"
 	"strings"
+	"syscall"
 	"time"
 )
 
@@ -75,6 +85,8 @@ var screenshotTime float64
 
 var preciseProgress bool
 
+var segmentCount int
+
 var monitorHz int
 
 func run() {
@@ -147,8 +159,18 @@ func run() {
 
 		flag.BoolVar(&preciseProgress, "preciseprogress", false, "Show rendering progress in 1% increments")
 
+		jobsFile := flag.String("jobs", "", "Render jobs listed in a JSON file one after another, reusing loaded beatmaps and skins. Implies -record, see README for the format")
+
+		segments := flag.Int("segments", 1, "Split the recording into the given number of parts rendered by separate processes at the same time, see README")
+
+		statusTarget := flag.String("status", "", "Report stages, recording progress and errors as JSON lines. \"stdout\" moves logs to stderr, \"pipe:name\" writes to a named pipe (.ro2dname on Unix, \\\\.\\pipe\\ro2dname on Windows)")
+
 		flag.Parse()
 
+		if *statusTarget != "" {
+			startStatus(*statusTarget)
+		}
+
 		if *mods != "" && *mods2 != "" {
 			panic("You can't specify classic and lazer mods at the same time")
 		}
@@ -167,6 +189,15 @@ func run() {
 			checkForUpdates()
 		}
 
+		if *jobsFile != "" {
+			if *play || *replay != "" || *knockout || !math.IsNaN(*ss) {
+				panic("Flag -jobs can't be combined with -play, -replay, -knockout or -ss")
+			}
+
+			jobList = loadJobs(*jobsFile, *settingsVersion, *skin)
+			*record = true
+		}
+
 		if *out != "" {
 			output = *out
 			if math.IsNaN(*ss) {
@@ -190,49 +221,28 @@ func run() {
 			panic("Incompatible flags selected: -ss, -play")
 		} else if screenshotMode && recordMode {
 			panic("Incompatible flags selected: -ss, -record")
+		} else if *segments > 1 && (!recordMode || jobList != nil) {
+			panic("Flag -segments can only be used when recording a single video")
 		}
 
-		modsParsed := difficulty2.ParseMods(*mods)
-		var modsNew []rplpa.ModInfo = nil
-
-		if *replay != "" {
-			bytes, err := ioutil.ReadFile(*replay)
-			if err != nil {
-				panic(err)
+		if segment != nil {
+			if !recordMode {
+				panic("Segment process has to record")
 			}
 
-			rp, err := rplpa.ParseReplay(bytes)
-			if err != nil {
-				panic(err)
-			}
+			// All segments have to make the same random choices, e.g. in random slider dance
+			rand.Seed(1)
+		}
 
-			if rp.PlayMode != 0 {
-				panic("Modes other than osu!standard are not supported")
-			}
+		modsParsed := difficulty2.ParseMods(*mods)
+		var modsNew []rplpa.ModInfo = nil
 
-			if rp.ReplayData == nil || len(rp.ReplayData) < 2 {
-				panic("Replay is missing input data")
-			}
+		if *replay != "" {
+			rp := loadReplay(*replay)
 
 			*md5 = rp.BeatmapMD5
 			*id = -1
-			modsParsed = difficulty2.Modifier(rp.Mods)
-
-			if rp.ScoreInfo != nil && rp.ScoreInfo.Mods != nil && len(rp.ScoreInfo.Mods) > 0 {
-				modsNew = make([]rplpa.ModInfo, 0, len(rp.ScoreInfo.Mods))
-
-				for _, mod := range rp.ScoreInfo.Mods {
-					modsNew = append(modsNew, *mod)
-				}
-			}
-
-			if rp.OsuVersion >= 30000000 { // Lazer is 1000 years in the future
-				modsParsed |= difficulty2.Lazer
-
-				if modsNew != nil {
-					modsNew = append(modsNew, rplpa.ModInfo{Acronym: "LZ"})
-				}
-			}
+			modsParsed, modsNew = getReplayMods(rp)
 
 			*knockout = true
 			settings.REPLAY = *replay
@@ -259,8 +269,9 @@ func run() {
 		}
 
 		closeAfterSettingsLoad := false
+		exitCode := status.ExitOK
 
-		if (*md5+*artist+*title+*difficulty+*creator) == "" && *id < 0 {
+		if (*md5+*artist+*title+*difficulty+*creator) == "" && *id < 0 && jobList == nil {
 			log.Println("No beatmap specified, closing...")
 			closeAfterSettingsLoad = true
 		}
@@ -296,13 +307,24 @@ func run() {
 		var beatMap *beatmap.BeatMap = nil
 
 		if !closeAfterSettingsLoad {
+			status.SetStage(status.StageDatabase)
+
 			err := database.Init()
 			if err != nil {
 				log.Println("Failed to initialize database:", err)
 			} else {
-				beatmaps := database.LoadBeatmaps(*noDbCheck, nil)
+				var beatmaps []*beatmap.BeatMap
+
+				// Jobs are checked separately
+				if jobList != nil || canWatchOtherModes() {
+					beatmaps = database.LoadWatchableBeatmaps(*noDbCheck, nil)
+				} else {
+					beatmaps = database.LoadBeatmaps(*noDbCheck, nil)
+				}
 
-				if *id > -1 {
+				if jobList != nil {
+					jobBeatmaps = beatmaps
+				} else if *id > -1 {
 					for _, b := range beatmaps {
 						if b.ID == *id {
 							beatMap = b
@@ -346,17 +368,30 @@ func run() {
 				}
 			}
 
-			if beatMap == nil {
+			if jobList != nil {
+				// beatmaps are looked up separately for each job
+			} else if beatMap == nil {
 				log.Println("Beatmap not found, closing...")
+				status.Failed(status.ExitBeatmapNotFound, "Beatmap not found")
 				closeAfterSettingsLoad = true
+				exitCode = status.ExitBeatmapNotFound
 			} else {
-				beatMap.UpdatePlayStats()
-				database.UpdatePlayStats(beatMap)
+				checkPlayMode(beatMap)
+
+				if segment == nil { // Stats were updated by the parent process
+					beatMap.UpdatePlayStats()
+					database.UpdatePlayStats(beatMap)
+				}
 			}
 
 			database.Close()
 		}
 
+		if *segments > 1 && !closeAfterSettingsLoad {
+			segmentCount = *segments
+			return
+		}
+
 		assets.Init(build.Stream == "Dev")
 
 		if !closeAfterSettingsLoad {
@@ -389,7 +424,8 @@ func run() {
 		}
 
 		if closeAfterSettingsLoad {
-			os.Exit(0)
+			status.Stop()
+			os.Exit(exitCode)
 		}
 
 		allowDA := false
@@ -415,14 +451,7 @@ func run() {
 		}
 
 		if settings.RECORD {
-			//HACK: some in-app variables depend on these settings so we force them here
-			settings.Graphics.VSync = false
-			settings.Graphics.ShowFPS = false
-			settings.DEBUG = false
-			settings.Graphics.Fullscreen = false
-			settings.Graphics.WindowWidth = int64(settings.Recording.FrameWidth)
-			settings.Graphics.WindowHeight = int64(settings.Recording.FrameHeight)
-			settings.Playfield.LeadInTime = 0
+			applyRecordSettings()
 		}
 
 		if screenshotMode {
@@ -455,7 +484,10 @@ func run() {
 			})
 		}
 
-		win.SetTitle("danser " + build.VERSION + " - " + beatMap.Artist + " - " + beatMap.Name + " [" + beatMap.Difficulty + "]")
+		if beatMap != nil {
+			win.SetTitle("danser " + build.VERSION + " - " + beatMap.Artist + " - " + beatMap.Name + " [" + beatMap.Difficulty + "]")
+		}
+
 		input.Win = win
 
 		if cTime := time.Now(); cTime.Month() == 12 && cTime.Day() >= 6 {
@@ -475,6 +507,7 @@ func run() {
 
 		if !settings.RECORD {
 			discord.Connect()
+			liveserver.Start()
 			win.Show()
 		}
 
@@ -506,6 +539,10 @@ func run() {
 		bass.Init(settings.RECORD)
 		audio.LoadSamples()
 
+		if jobList != nil {
+			return
+		}
+
 		if settings.PLAY || !settings.KNOCKOUT || allowDA {
 			if modsNew == nil {
 				modsNew = modsParsed.ConvertToModInfoList()
@@ -587,15 +624,25 @@ func run() {
 			beatMap.Diff.SetMods(modsParsed)
 		}
 
+		status.SetStage(status.StageBeatmap)
+
 		beatmap.ParseTimingPointsAndPauses(beatMap)
-		beatmap.ParseObjects(beatMap, false, true)
+		beatmap.ParseObjects(beatMap, false, true, false)
 		beatMap.LoadCustomSamples()
+
+		setupJudgementLog()
+
 		player = states.NewPlayer(beatMap)
 
 		limiter = frame.NewLimiter(int(settings.Graphics.FPSCap))
 	})
 
-	if recordMode {
+	if segmentCount > 1 {
+		runSegments(segmentCount)
+	} else if jobList != nil {
+		runJobs(jobList)
+	} else if recordMode {
+		handleAbort()
 		mainLoopRecord()
 	} else if screenshotMode {
 		mainLoopSS()
@@ -604,6 +651,9 @@ func run() {
 	}
 }
 
+// recordWarmup is how many seconds are rendered without encoding before the first frame of a segment or a resumed recording
+const recordWarmup = 2.0
+
 func mainLoopRecord() {
 	count := int64(0)
 
@@ -618,12 +668,50 @@ func mainLoopRecord() {
 
 	var fbo *buffer.Framebuffer
 
-	goroutines.CallMain(func() {
+	callMain(func() {
 		fbo = buffer.NewFrameMultisampleScreen(w, h, false, 0)
 	})
 
+	defer goroutines.CallMain(fbo.Dispose)
+
+	p, _ := player.(*states.Player)
+
+	oversample := int64(1)
+	if settings.Recording.MotionBlur.Enabled {
+		oversample = int64(settings.Recording.MotionBlur.OversampleMultiplier)
+	}
+
+	totalFrames := int64(p.RunningTime / 1000 * float64(settings.Recording.FPS))
+
+	// Range of frames (including motion blur subframes) that get encoded, segments encode only their part
+	firstFrame, endFrame := int64(0), int64(math.MaxInt64)
+
+	// Frame that progress is counted from
+	progressFrame := int64(0)
+
+	if segment != nil {
+		firstFrame, endFrame = segment.getSubframes(totalFrames, oversample)
+		progressFrame = firstFrame
+
+		totalFrames = min(endFrame/oversample, totalFrames) - firstFrame/oversample
+
+		ffmpeg.SetSegment(segment.index, firstFrame/oversample, segment.isLast())
+	}
+
+	ffmpeg.SetResumeKey(getRecordingKey(p, totalFrames))
 	ffmpeg.StartFFmpeg(int(fps), w, h, audioFPS, output)
 
+	if resumeFrame := ffmpeg.GetResumeFrame(); resumeFrame > 0 {
+		firstFrame = resumeFrame * oversample
+
+		log.Println("Resuming interrupted recording at", util.FormatSeconds(int(resumeFrame)/settings.Recording.FPS))
+	}
+
+	// Frames before the first encoded one are only simulated, the last few of them are rendered so cursor trails and motion blur catch up
+	drawFrom := max(0, firstFrame-max(int64(recordWarmup*fps), int64(settings.Recording.MotionBlur.BlendFrames)))
+
+	status.SetStage(status.StageEncoding)
+
 	updateFPS := max(fps, 1000)
 	updateDelta := 1000 / updateFPS
 	fpsDelta := 1000 / fps
@@ -632,11 +720,12 @@ func mainLoopRecord() {
 	deltaSumF := fpsDelta
 	deltaSumA := 0.0
 
-	p, _ := player.(*states.Player)
-
 	lastCount := int64(0)
 	lastRealTime := qpc.GetMilliTimeF()
 
+	lastStatusCount := int64(0)
+	lastStatusTime := lastRealTime
+
 	var lastProgress, progress int
 
 	if preciseProgress {
@@ -653,7 +742,20 @@ func mainLoopRecord() {
 
 		deltaSumF += updateDelta
 		if deltaSumF >= fpsDelta {
-			goroutines.CallMain(func() {
+			if count >= endFrame {
+				break // The rest is rendered by other segments
+			}
+
+			if count < drawFrom {
+				ffmpeg.SkipFrame()
+
+				count++
+				deltaSumF -= fpsDelta
+
+				continue
+			}
+
+			callMain(func() {
 				fbo.Bind()
 
 				ffmpeg.PreFrame()
@@ -662,19 +764,49 @@ func mainLoopRecord() {
 				pushFrame()
 				viewport.Pop()
 
+				if count < firstFrame {
+					ffmpeg.DiscardFrame()
+
+					fbo.Unbind()
+
+					count++
+
+					return
+				}
+
 				ffmpeg.MakeFrame()
 
 				fbo.Unbind()
 
+				if firstFrame > 0 && count == firstFrame { // Don't count the time spent simulating up to the first frame
+					lastRealTime = qpc.GetMilliTimeF()
+					lastStatusTime = lastRealTime
+					lastCount, lastStatusCount = count, count
+				}
+
 				count++
 
-				timeOffset := p.GetTimeOffset()
-				progress = int(math.Round(timeOffset / p.RunningTime * 100))
+				// Progress of segments is relative to their part of the video
+				timeOffset, runningTime := p.GetTimeOffset(), p.RunningTime
+				if segment != nil {
+					timeOffset, runningTime = float64(count-progressFrame)*fpsDelta, float64(totalFrames*oversample)*fpsDelta
+				}
+
+				progress = int(math.Round(timeOffset / runningTime * 100))
+
+				if now := qpc.GetMilliTimeF(); now-lastStatusTime >= 1000 {
+					speed := float64(count-lastStatusCount) * (1000 / fps) / (now - lastStatusTime)
+
+					status.Progress(timeOffset/runningTime*100, (count-progressFrame)/oversample, totalFrames, float64(count-lastStatusCount)/float64(oversample)*1000/(now-lastStatusTime), speed, (runningTime-timeOffset)/1000/speed)
+
+					lastStatusCount = count
+					lastStatusTime = now
+				}
 
 				if (preciseProgress || progress%5 == 0) && lastProgress != progress {
 					speed := float64(count-lastCount) * (1000 / fps) / (qpc.GetMilliTimeF() - lastRealTime)
 
-					eta := int((p.RunningTime - timeOffset) / 1000 / speed)
+					eta := int((runningTime - timeOffset) / 1000 / speed)
 
 					etaText := util.FormatSeconds(eta)
 
@@ -695,9 +827,13 @@ func mainLoopRecord() {
 		}
 	}
 
-	goroutines.CallMain(func() {
-		ffmpeg.StopFFmpeg()
-	})
+	if segment == nil || segment.isLast() {
+		saveThumbnail(p, settings.Recording.GetOutputDir())
+	}
+
+	p.Dispose()
+
+	callMain(ffmpeg.StopFFmpeg)
 }
 
 func mainLoopSS() {
@@ -711,6 +847,10 @@ func mainLoopSS() {
 
 	p, _ := player.(*states.Player)
 
+	if output == "" { // Thumbnail needs the same name
+		output = "danser_" + time.Now().Format("2006-01-02_15-04-05")
+	}
+
 	for !p.Update(1) {
 		if p.GetTime() >= screenshotTime*1000 {
 			log.Println("Scheduling screenshot")
@@ -726,9 +866,23 @@ func mainLoopSS() {
 				fbo.Unbind()
 			})
 
+			saveThumbnail(p, filepath.Join(env.DataDir(), "screenshots"))
+
 			break
 		}
 	}
+
+	p.Dispose()
+}
+
+func saveThumbnail(p *states.Player, dir string) {
+	if !settings.Recording.Thumbnail.Enabled {
+		return
+	}
+
+	goroutines.CallMain(func() {
+		thumbnail.Render(p.GetThumbnailInfo(), filepath.Join(dir, output+"_thumbnail.png"))
+	})
 }
 
 func mainLoopNormal() {
@@ -843,7 +997,12 @@ func pushFrame() {
 		screenFBO.Bind()
 	}
 
-	gl.ClearColor(0, 0, 0, 1)
+	if settings.TRANSPARENT {
+		gl.ClearColor(0, 0, 0, 0)
+	} else {
+		gl.ClearColor(0, 0, 0, 1)
+	}
+
 	gl.Clear(gl.COLOR_BUFFER_BIT)
 
 	if player != nil {
@@ -878,7 +1037,182 @@ func checkForUpdates() {
 	}
 }
 
+func getReplayMods(rp *rplpa.Replay) (mods difficulty2.Modifier, modsNew []rplpa.ModInfo) {
+	mods = difficulty2.Modifier(rp.Mods)
+
+	if rp.ScoreInfo != nil && rp.ScoreInfo.Mods != nil && len(rp.ScoreInfo.Mods) > 0 {
+		modsNew = make([]rplpa.ModInfo, 0, len(rp.ScoreInfo.Mods))
+
+		for _, mod := range rp.ScoreInfo.Mods {
+			modsNew = append(modsNew, *mod)
+		}
+	}
+
+	if rp.OsuVersion >= 30000000 { // Lazer is 1000 years in the future
+		mods |= difficulty2.Lazer
+
+		if modsNew != nil {
+			modsNew = append(modsNew, rplpa.ModInfo{Acronym: "LZ"})
+		}
+	}
+
+	return
+}
+
+func loadReplay(path string) *rplpa.Replay {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		panic(status.Fail(status.ExitReplayError, err))
+	}
+
+	rp, err := rplpa.ParseReplay(bytes)
+	if err != nil {
+		panic(status.Fail(status.ExitReplayError, err))
+	}
+
+	if rp.PlayMode == rplpa.CTB {
+		panic(status.Failf(status.ExitReplayError, "osu!catch is not supported"))
+	}
+
+	settings.PLAYMODE = int(rp.PlayMode)
+
+	if rp.ReplayData == nil || len(rp.ReplayData) < 2 {
+		panic(status.Failf(status.ExitReplayError, "Replay is missing input data"))
+	}
+
+	return rp
+}
+
+func checkPlayMode(beatMap *beatmap.BeatMap) {
+	if settings.REPLAY == "" {
+		settings.PLAYMODE = int(beatMap.Mode)
+	} else if beatMap.Mode != rplpa.OSU && int64(settings.PLAYMODE) != beatMap.Mode {
+		panic("Replay's game mode doesn't match the beatmap")
+	}
+
+	if settings.PLAYMODE == rplpa.MANIA && beatMap.Mode != rplpa.MANIA {
+		panic("osu!mania conversions are not supported")
+	}
+
+	if settings.PLAYMODE != rplpa.OSU && !canWatchOtherModes() {
+		panic("osu!taiko and osu!mania maps can be only watched with autoplay or a single replay")
+	}
+}
+
+// canWatchOtherModes returns whether osu!taiko and osu!mania maps can be launched with current settings.
+// Without a replay and outside of play and knockout modes they are played with autoplay.
+func canWatchOtherModes() bool {
+	return !settings.PLAY && (!settings.KNOCKOUT || settings.REPLAY != "")
+}
+
+func applyRecordSettings() {
+	//HACK: some in-app variables depend on these settings so we force them here
+	settings.Graphics.VSync = false
+	settings.Graphics.ShowFPS = false
+	settings.DEBUG = false
+	settings.Graphics.Fullscreen = false
+	settings.Graphics.WindowWidth = int64(settings.Recording.FrameWidth)
+	settings.Graphics.WindowHeight = int64(settings.Recording.FrameHeight)
+	settings.Playfield.LeadInTime = 0
+
+	settings.TRANSPARENT = recordMode && settings.Recording.Transparent && settings.Recording.HasAlpha()
+}
+
+// getRecordingKey identifies what's being recorded, parts of an interrupted recording are reused only if it didn't change
+func getRecordingKey(p *states.Player, totalFrames int64) string {
+	bMap := p.GetBeatMap()
+
+	hash := sha256.New()
+
+	_, _ = fmt.Fprintln(hash, build.VERSION, bMap.MD5, bMap.Diff.GetModStringFull(), totalFrames)
+	_, _ = fmt.Fprintln(hash, settings.REPLAY, settings.KNOCKOUT, settings.KNOCKOUTREPLAYS, settings.START, settings.END, settings.SKIP, settings.SPEED, settings.PITCH, settings.DIVIDES, settings.TAG, settings.LOCALOFFSET)
+	_, _ = fmt.Fprintln(hash, settings.GetCompressedString())
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func setupJudgementLog() {
+	settings.JUDGEMENTLOG = ""
+
+	if !settings.Recording.ExportJudgements || (!recordMode && !screenshotMode) || (segment != nil && !segment.isLast()) {
+		return
+	}
+
+	if strings.TrimSpace(output) == "" {
+		output = "danser_" + time.Now().Format("2006-01-02_15-04-05")
+	}
+
+	logDir := settings.Recording.GetOutputDir()
+	if screenshotMode {
+		logDir = filepath.Join(env.DataDir(), "screenshots")
+	}
+
+	settings.JUDGEMENTLOG = filepath.Join(logDir, output+".jsonl")
+}
+
+// startStatus sets where status messages are sent, see -status flag
+func startStatus(target string) {
+	if target == "stdout" {
+		status.Start(os.Stdout)
+
+		// stdout is reserved for status messages, logs and ffmpeg's output go to stderr
+		os.Stdout = os.Stderr
+		platform.SetLogConsole(os.Stderr)
+
+		return
+	}
+
+	if name, ok := strings.CutPrefix(target, "pipe:"); ok {
+		pipe, err := files.NewNamedPipe(name)
+		if err != nil {
+			panic(fmt.Sprintf("Failed to create status pipe: %s", err))
+		}
+
+		log.Println("Sending status to:", pipe.Name())
+
+		status.Start(pipe)
+
+		return
+	}
+
+	panic(fmt.Sprintf("Invalid status target: %s", target))
+}
+
+// handleAbort ends recording with a distinct exit code when danser is interrupted
+func handleAbort() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	goroutines.Run(func() {
+		<-signals
+
+		log.Println("Recording aborted!")
+
+		killSegments()
+
+		status.Failed(status.ExitAborted, "Aborted by user")
+		status.Stop()
+
+		os.Exit(status.ExitAborted)
+	})
+}
+
+// commands are run headless, without initializing GLFW and BASS. Returned value is used as the exit code
+var commands = map[string]func(args []string) int{
+	"calc":    runCalc,
+	"export":  runExport,
+	"strains": runStrains,
+	"verify":  runVerify,
+}
+
 func Run() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			runCommand(os.Args[1], command)
+			return
+		}
+	}
+
 	defer func() {
 		var err any
 		var stackTrace []string
@@ -894,16 +1228,50 @@ func Run() {
 
 	goroutines.SetCrashHandler(closeHandler)
 
-	platform.StartLogging("danser")
+	logName := "danser"
+
+	if value := os.Getenv(segmentEnv); value != "" {
+		segment = parseSegment(value)
+		logName = fmt.Sprintf("danser-segment%d", segment.index+1)
+	}
+
+	platform.StartLogging(logName)
 
 	platform.DisableQuickEdit()
 
 	goroutines.RunMain(run)
 }
 
+func runCommand(name string, command func(args []string) int) {
+	exitCode := 0
+
+	defer func() {
+		var err any
+		var stackTrace []string
+
+		if err = recover(); err != nil {
+			stackTrace = goroutines.GetStackTrace(4)
+		}
+
+		closeHandler(err, stackTrace)
+
+		os.Exit(exitCode)
+	}()
+
+	runtime.GOMAXPROCS(runtime.NumCPU())
+
+	goroutines.SetCrashHandler(closeHandler)
+
+	// stdout is reserved for command's output
+	platform.StartLoggingTo("danser-"+name, os.Stderr)
+
+	exitCode = command(os.Args[2:])
+}
+
 func closeHandler(err any, stackTrace []string) {
 	settings.CloseWatcher()
 	discord.Disconnect()
+	liveserver.Stop()
 	platform.EnableQuickEdit()
 
 	if err != nil {
@@ -913,8 +1281,15 @@ func closeHandler(err any, stackTrace []string) {
 			log.Println(s)
 		}
 
-		os.Exit(1)
+		code := status.GetExitCode(err)
+
+		status.Failed(code, fmt.Sprint(err))
+		status.Stop()
+
+		os.Exit(code)
 	}
 
+	status.Stop()
+
 	log.Println("Exiting normally.")
 }
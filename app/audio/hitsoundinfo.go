@@ -5,6 +5,7 @@ type HitSoundInfo struct {
 	AdditionSet  int
 	CustomIndex  int
 	CustomVolume float64
+	Filename     string // custom sample replacing all hitsounds of the object, not played by danser
 }
 
 type HitSound struct {
@@ -36,29 +36,29 @@ func AddListener(function func(sampleSet int, hitsoundIndex, index int, volume f
 }
 
 func LoadSamples() {
-	Samples[0][0] = LoadSample("normal-hitnormal")
-	Samples[0][1] = LoadSample("normal-hitwhistle")
-	Samples[0][2] = LoadSample("normal-hitfinish")
-	Samples[0][3] = LoadSample("normal-hitclap")
-	Samples[0][4] = LoadSample("normal-slidertick")
-	Samples[0][5] = LoadSample("normal-sliderslide")
-	Samples[0][6] = LoadSample("normal-sliderwhistle")
-
-	Samples[1][0] = LoadSample("soft-hitnormal")
-	Samples[1][1] = LoadSample("soft-hitwhistle")
-	Samples[1][2] = LoadSample("soft-hitfinish")
-	Samples[1][3] = LoadSample("soft-hitclap")
-	Samples[1][4] = LoadSample("soft-slidertick")
-	Samples[1][5] = LoadSample("soft-sliderslide")
-	Samples[1][6] = LoadSample("soft-sliderwhistle")
-
-	Samples[2][0] = LoadSample("drum-hitnormal")
-	Samples[2][1] = LoadSample("drum-hitwhistle")
-	Samples[2][2] = LoadSample("drum-hitfinish")
-	Samples[2][3] = LoadSample("drum-hitclap")
-	Samples[2][4] = LoadSample("drum-slidertick")
-	Samples[2][5] = LoadSample("drum-sliderslide")
-	Samples[2][6] = LoadSample("drum-sliderwhistle")
+	Samples[0][0] = LoadHitSample("normal-hitnormal")
+	Samples[0][1] = LoadHitSample("normal-hitwhistle")
+	Samples[0][2] = LoadHitSample("normal-hitfinish")
+	Samples[0][3] = LoadHitSample("normal-hitclap")
+	Samples[0][4] = LoadHitSample("normal-slidertick")
+	Samples[0][5] = LoadHitSample("normal-sliderslide")
+	Samples[0][6] = LoadHitSample("normal-sliderwhistle")
+
+	Samples[1][0] = LoadHitSample("soft-hitnormal")
+	Samples[1][1] = LoadHitSample("soft-hitwhistle")
+	Samples[1][2] = LoadHitSample("soft-hitfinish")
+	Samples[1][3] = LoadHitSample("soft-hitclap")
+	Samples[1][4] = LoadHitSample("soft-slidertick")
+	Samples[1][5] = LoadHitSample("soft-sliderslide")
+	Samples[1][6] = LoadHitSample("soft-sliderwhistle")
+
+	Samples[2][0] = LoadHitSample("drum-hitnormal")
+	Samples[2][1] = LoadHitSample("drum-hitwhistle")
+	Samples[2][2] = LoadHitSample("drum-hitfinish")
+	Samples[2][3] = LoadHitSample("drum-hitclap")
+	Samples[2][4] = LoadHitSample("drum-slidertick")
+	Samples[2][5] = LoadHitSample("drum-sliderslide")
+	Samples[2][6] = LoadHitSample("drum-sliderwhistle")
 }
 
 func PlaySample(sampleSet, additionSet, hitsound, index int, volume float64, objNum int64, xPos float64) {
@@ -202,6 +202,9 @@ func PlaySliderTick(sampleSet, index int, volume float64, objNum int64, xPos flo
 }
 
 func LoadBeatmapSamples(fMap map[string]string) {
+	// Drop samples of the previously loaded map
+	MapSamples = [3][7]map[int]*bass.Sample{}
+
 	splitBeforeDigit := func(name string) []string {
 		for i, r := range name {
 			if unicode.IsDigit(r) {
@@ -250,7 +253,7 @@ func LoadBeatmapSamples(fMap map[string]string) {
 				MapSamples[setID-1][hitSoundID-1] = make(map[int]*bass.Sample)
 			}
 
-			MapSamples[setID-1][hitSoundID-1][hitSoundIndex] = bass.NewSample(fName)
+			MapSamples[setID-1][hitSoundID-1][hitSoundIndex] = bass.NewSample(fName).WithStem(bass.StemHitsounds)
 		}
 	}
 }
@@ -259,6 +262,11 @@ func LoadSample(name string) *bass.Sample {
 	return skin.GetSample(name)
 }
 
+// LoadHitSample loads a sample played by hit objects, it's recorded in the hitsound stem
+func LoadHitSample(name string) *bass.Sample {
+	return skin.GetSample(name).WithStem(bass.StemHitsounds)
+}
+
 func PlayFailSound() {
 	sample := LoadSample("failsound")
 	if sample != nil {
@@ -7,6 +7,7 @@ import (
 	"github.com/wieku/danser-go/app/beatmap/objects"
 	"github.com/wieku/danser-go/app/settings"
 	"github.com/wieku/danser-go/framework/files"
+	"github.com/wieku/danser-go/framework/math/color"
 	"math"
 	"path/filepath"
 	"slices"
@@ -57,6 +58,7 @@ type BeatMap struct {
 	Timings    *objects.Timings
 	HitObjects []objects.IHitObject
 	Pauses     []*Pause
+	Colors     []color.Color
 	Queue      []objects.IHitObject
 	processed  []objects.IHitObject
 	Version    int
@@ -94,9 +96,26 @@ func (beatMap *BeatMap) Reset() {
 	}
 }
 
+// Copy returns an unparsed copy of the beatmap sharing only the metadata, so the same beatmap can be played more than once
+func (beatMap *BeatMap) Copy() *BeatMap {
+	bMap := *beatMap
+
+	bMap.Diff = beatMap.Diff.Clone()
+	bMap.Timings = objects.NewTimings()
+	bMap.HitObjects = nil
+	bMap.Pauses = nil
+	bMap.Colors = nil
+	bMap.Queue = nil
+	bMap.processed = nil
+	bMap.stackCalcCache = make(map[int64]bool)
+
+	return &bMap
+}
+
 func (beatMap *BeatMap) Clear() {
 	beatMap.HitObjects = make([]objects.IHitObject, 0)
 	beatMap.Timings.Clear()
+	beatMap.stackCalcCache = make(map[int64]bool)
 }
 
 func (beatMap *BeatMap) Update(time float64) {
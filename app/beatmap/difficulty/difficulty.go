@@ -59,6 +59,10 @@ type Difficulty struct {
 
 	modSettings map[reflect.Type]any
 	adjustPitch bool
+
+	// Wind Up and Wind Down change the rate between those times
+	rampStart float64
+	rampEnd   float64
 }
 
 func NewDifficulty(hp, cs, od, ar float64) *Difficulty {
@@ -150,6 +154,17 @@ func (diff *Difficulty) calculate() {
 		diff.adjustPitch = s.AdjustPitch
 	}
 
+	// Like in lazer, difficulty values of variable rate mods are calculated with the initial rate
+	if s, ok := diff.modSettings[rfType[TimeRampSettings]()].(TimeRampSettings); ok {
+		diff.Speed = s.InitialRate
+		diff.BaseModSpeed = s.InitialRate
+		diff.adjustPitch = s.AdjustPitch
+	} else if s, ok := diff.modSettings[rfType[AdaptiveSpeedSettings]()].(AdaptiveSpeedSettings); ok {
+		diff.Speed = s.InitialRate
+		diff.BaseModSpeed = s.InitialRate
+		diff.adjustPitch = s.AdjustPitch
+	}
+
 	diff.ARReal = DiffFromRate(diff.GetModifiedTime(diff.PreemptU), 1800, 1200, 450)
 	diff.ODReal = DiffFromRate(diff.GetModifiedTime(diff.Hit300U), 80, 50, 20)
 }
@@ -187,6 +202,16 @@ func (diff *Difficulty) AddMod(mods Modifier) {
 		diff.modSettings[rfType[ClassicSettings]()] = NewClassicSettings()
 	}
 
+	if mods.Active(WindUp) {
+		diff.modSettings[rfType[TimeRampSettings]()] = NewTimeRampSettings(1.5)
+	} else if mods.Active(WindDown) {
+		diff.modSettings[rfType[TimeRampSettings]()] = NewTimeRampSettings(0.75)
+	}
+
+	if mods.Active(AdaptiveSpeed) {
+		diff.modSettings[rfType[AdaptiveSpeedSettings]()] = NewAdaptiveSpeedSettings()
+	}
+
 	diff.calculate()
 }
 
@@ -225,6 +250,14 @@ func (diff *Difficulty) RemoveMod(mods Modifier) {
 		delete(diff.modSettings, rfType[ClassicSettings]())
 	}
 
+	if mods.Active(WindUp | WindDown) {
+		delete(diff.modSettings, rfType[TimeRampSettings]())
+	}
+
+	if mods.Active(AdaptiveSpeed) {
+		delete(diff.modSettings, rfType[AdaptiveSpeedSettings]())
+	}
+
 	diff.calculate()
 }
 
@@ -260,6 +293,16 @@ func (diff *Difficulty) SetMods2(mods []rplpa.ModInfo) {
 			if mod.Active(Classic) {
 				diff.modSettings[rfType[ClassicSettings]()] = parseConfig(NewClassicSettings(), mInfo.Settings)
 			}
+
+			if mod.Active(WindUp) {
+				diff.modSettings[rfType[TimeRampSettings]()] = parseConfig(NewTimeRampSettings(1.5), mInfo.Settings)
+			} else if mod.Active(WindDown) {
+				diff.modSettings[rfType[TimeRampSettings]()] = parseConfig(NewTimeRampSettings(0.75), mInfo.Settings)
+			}
+
+			if mod.Active(AdaptiveSpeed) {
+				diff.modSettings[rfType[AdaptiveSpeedSettings]()] = parseConfig(NewAdaptiveSpeedSettings(), mInfo.Settings)
+			}
 		}
 	}
 
@@ -329,6 +372,71 @@ func (diff *Difficulty) GetSpeed() float64 {
 	return diff.Speed
 }
 
+// SetRampTimes sets the part of the map where Wind Up and Wind Down change the rate.
+// Like in lazer, the final rate is reached at 75% of the way between the first object and the end of the last one.
+func (diff *Difficulty) SetRampTimes(firstObjectStart, lastObjectEnd float64) {
+	diff.rampStart = firstObjectStart
+	diff.rampEnd = firstObjectStart + 0.75*(lastObjectEnd-firstObjectStart)
+}
+
+// HasVariableRate returns true if the rate changes during the map
+func (diff *Difficulty) HasVariableRate() bool {
+	return diff.Mods.Active(WindUp | WindDown | AdaptiveSpeed)
+}
+
+// GetSpeedAt returns the rate at given map time. Adaptive Speed depends on player's hits, so its initial rate is returned.
+func (diff *Difficulty) GetSpeedAt(time float64) float64 {
+	s, ok := diff.modSettings[rfType[TimeRampSettings]()].(TimeRampSettings)
+	if !ok {
+		return diff.Speed
+	}
+
+	amount := (time - diff.rampStart) / max(1, diff.rampEnd-diff.rampStart)
+
+	return s.InitialRate + (s.FinalRate-s.InitialRate)*mutils.Clamp(amount, 0, 1)
+}
+
+// GetModifiedTimeAt is GetModifiedTime using the rate at given map time
+func (diff *Difficulty) GetModifiedTimeAt(duration, time float64) float64 {
+	return duration / diff.GetSpeedAt(time)
+}
+
+// GetAdjustedTime converts map time to the time passed while playing, i.e. map time divided by the rate integrated since 0
+func (diff *Difficulty) GetAdjustedTime(time float64) float64 {
+	s, ok := diff.modSettings[rfType[TimeRampSettings]()].(TimeRampSettings)
+	if !ok || s.InitialRate == s.FinalRate {
+		return time / diff.Speed
+	}
+
+	rampLength := max(1, diff.rampEnd-diff.rampStart)
+
+	if time <= diff.rampStart {
+		return time / s.InitialRate
+	}
+
+	// The rate changes linearly, so the integral of 1/rate is logarithmic
+	slope := (s.FinalRate - s.InitialRate) / rampLength
+
+	rampTime := min(time, diff.rampStart+rampLength)
+
+	adjusted := diff.rampStart/s.InitialRate + math.Log(diff.GetSpeedAt(rampTime)/s.InitialRate)/slope
+
+	if time > rampTime {
+		adjusted += (time - rampTime) / s.FinalRate
+	}
+
+	return adjusted
+}
+
+// GetAdjustedDuration returns the time passed while playing between two points of map time
+func (diff *Difficulty) GetAdjustedDuration(start, end float64) float64 {
+	if _, ok := diff.modSettings[rfType[TimeRampSettings]()]; !ok {
+		return (end - start) / diff.Speed
+	}
+
+	return diff.GetAdjustedTime(end) - diff.GetAdjustedTime(start)
+}
+
 func (diff *Difficulty) AdjustsPitch() bool {
 	return diff.adjustPitch
 }
@@ -458,6 +566,13 @@ func (diff *Difficulty) getModStringBase(mod Modifier) string {
 		mods += fmt.Sprintf("S%sx", mutils.FormatWOZeros(cSpeed, 2))
 	}
 
+	// Variable rate mods set Speed and BaseModSpeed to the initial rate, so rates have to be added separately
+	if s, ok := diff.modSettings[rfType[TimeRampSettings]()].(TimeRampSettings); ok && mod.Active(WindUp|WindDown) {
+		mods += fmt.Sprintf("S%s-%sx", mutils.FormatWOZeros(s.InitialRate, 2), mutils.FormatWOZeros(s.FinalRate, 2))
+	} else if s, ok := diff.modSettings[rfType[AdaptiveSpeedSettings]()].(AdaptiveSpeedSettings); ok && mod.Active(AdaptiveSpeed) {
+		mods += fmt.Sprintf("S%sx", mutils.FormatWOZeros(s.InitialRate, 2))
+	}
+
 	return mods
 }
 
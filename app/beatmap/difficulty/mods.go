@@ -41,10 +41,13 @@ const (
 	Lazer
 	Classic
 	DifficultyAdjust
+	WindUp
+	WindDown
+	AdaptiveSpeed
 
 	// DifficultyAdjustMask is outdated, use GetDiffMaskedMods instead
 	DifficultyAdjustMask    = HardRock | Easy | DoubleTime | Nightcore | HalfTime | Daycore | Flashlight | Relax
-	difficultyAdjustMaskNew = HardRock | Easy | DoubleTime | HalfTime | Flashlight | Relax | TouchDevice
+	difficultyAdjustMaskNew = HardRock | Easy | DoubleTime | HalfTime | Flashlight | Relax | TouchDevice | WindUp | WindDown | AdaptiveSpeed
 )
 
 // GetDiffMaskedMods should be used instead of DifficultyAdjustMask. In 220930 deployment, HDFL is a separate mod difficulty wise
@@ -103,6 +106,9 @@ var modsString = [...]string{
 	"LZ",
 	"CL",
 	"DA",
+	"WU",
+	"WD",
+	"AS",
 }
 
 var modsStringFull = [...]string{
@@ -141,6 +147,9 @@ var modsStringFull = [...]string{
 	"Lazer",
 	"Classic",
 	"DifficultyAdjust",
+	"WindUp",
+	"WindDown",
+	"AdaptiveSpeed",
 }
 
 func (mods Modifier) GetScoreMultiplier() float64 {
@@ -198,6 +207,10 @@ func (mods Modifier) GetScoreMultiplier() float64 {
 		multiplier *= 0.5
 	}
 
+	if mods&(WindUp|WindDown|AdaptiveSpeed) > 0 {
+		multiplier *= 0.5
+	}
+
 	return multiplier
 }
 
@@ -334,9 +347,21 @@ func (mods Modifier) Compatible() bool {
 		((mods.Active(Perfect) || mods.Active(SuddenDeath)) && mods.Active(NoFail)) ||
 		(mods.Active(Relax) && mods.Active(Relax2)) ||
 		((mods.Active(Relax) || mods.Active(Relax2)) && (mods.Active(SuddenDeath) || mods.Active(Perfect) || mods.Active(Autoplay) || mods.Active(NoFail))) ||
-		(mods.Active(Relax2) && mods.Active(SpunOut)) {
+		(mods.Active(Relax2) && mods.Active(SpunOut)) ||
+		rateModCount(mods) > 1 {
 		return false
 	}
 
 	return true
 }
+
+// rateModCount returns the number of mods changing the rate, only one of them can be active
+func rateModCount(mods Modifier) (count int) {
+	for _, m := range []Modifier{DoubleTime | Nightcore, HalfTime | Daycore, WindUp, WindDown, AdaptiveSpeed} {
+		if mods.Active(m) {
+			count++
+		}
+	}
+
+	return
+}
@@ -18,6 +18,9 @@ func init() {
 		Classic:          rfType[ClassicSettings](),
 		Flashlight:       rfType[FlashlightSettings](),
 		DifficultyAdjust: rfType[DiffAdjustSettings](),
+		WindUp:           rfType[TimeRampSettings](),
+		WindDown:         rfType[TimeRampSettings](),
+		AdaptiveSpeed:    rfType[AdaptiveSpeedSettings](),
 	}
 }
 
@@ -116,3 +119,38 @@ func (diffAdjust DiffAdjustSettings) postLoad() DiffAdjustSettings {
 
 	return diffAdjust
 }
+
+// TimeRampSettings are settings of Wind Up and Wind Down, the rate changes linearly from InitialRate to FinalRate
+type TimeRampSettings struct {
+	InitialRate float64 `json:"initial_rate"`
+	FinalRate   float64 `json:"final_rate"`
+	AdjustPitch bool    `json:"adjust_pitch"`
+}
+
+func NewTimeRampSettings(finalRate float64) TimeRampSettings {
+	return TimeRampSettings{
+		InitialRate: 1,
+		FinalRate:   finalRate,
+		AdjustPitch: true,
+	}
+}
+
+func (s TimeRampSettings) postLoad() TimeRampSettings {
+	return s
+}
+
+// AdaptiveSpeedSettings are settings of Adaptive Speed, the rate starts at InitialRate and then follows player's hits
+type AdaptiveSpeedSettings struct {
+	InitialRate float64 `json:"initial_rate"`
+	AdjustPitch bool    `json:"adjust_pitch"`
+}
+
+func NewAdaptiveSpeedSettings() AdaptiveSpeedSettings {
+	return AdaptiveSpeedSettings{
+		InitialRate: 1,
+	}
+}
+
+func (s AdaptiveSpeedSettings) postLoad() AdaptiveSpeedSettings {
+	return s
+}
@@ -0,0 +1,331 @@
+package beatmap
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/wieku/danser-go/app/audio"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/beatmap/objects"
+	"github.com/wieku/danser-go/framework/math/curves"
+	"github.com/wieku/danser-go/framework/math/mutils"
+	"github.com/wieku/danser-go/framework/math/vector"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const encoderVersion = 14
+
+type encoder struct {
+	*bufio.Writer
+
+	rate float64
+	flip bool
+}
+
+// EncodeBeatMap writes beatmap as osu file format v14. Timing points and hit objects have to be loaded first.
+// Only data kept by the parser is written, so storyboard, video and editor settings are lost.
+func EncodeBeatMap(beatMap *BeatMap, w io.Writer) error {
+	return encodeBeatMap(beatMap, nil, w)
+}
+
+// EncodeBeatMapWithMods writes beatmap with mods baked in: times are scaled by the speed rate, objects are flipped
+// if HardRock is active and difficulty settings are replaced with effective ones, capped at 10.
+func EncodeBeatMapWithMods(beatMap *BeatMap, diff *difficulty.Difficulty, w io.Writer) error {
+	return encodeBeatMap(beatMap, diff, w)
+}
+
+// SaveBeatMap encodes beatmap to a file, missing directories are created. Mods are baked in if diff is not nil.
+func SaveBeatMap(beatMap *BeatMap, diff *difficulty.Difficulty, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err = encodeBeatMap(beatMap, diff, file); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func encodeBeatMap(beatMap *BeatMap, diff *difficulty.Difficulty, w io.Writer) error {
+	writer := &encoder{
+		Writer: bufio.NewWriter(w),
+		rate:   1,
+	}
+
+	hp, cs, od, ar := formatFloat(beatMap.Diff.GetBaseHP()), formatFloat(beatMap.Diff.GetBaseCS()), formatFloat(beatMap.Diff.GetBaseOD()), formatFloat(beatMap.Diff.GetBaseAR())
+
+	if diff != nil {
+		writer.rate = diff.GetSpeed()
+		writer.flip = diff.CheckModActive(difficulty.HardRock)
+
+		hp = formatDifficulty(diff.HPMod)
+		cs = formatDifficulty(difficulty.DiffFromRate(diff.CircleRadiusU, 54.4, 32, 9.6))
+		od = formatDifficulty(diff.ODReal)
+		ar = formatDifficulty(diff.ARReal)
+	}
+
+	fmt.Fprintf(writer, "osu file format v%d\n", encoderVersion)
+
+	writer.section("General")
+	writer.value("AudioFilename", beatMap.Audio)
+	writer.value("PreviewTime", strconv.FormatInt(writer.previewTime(beatMap.PreviewTime), 10))
+	writer.value("SampleSet", sampleSetName(beatMap.Timings.BaseSet))
+	writer.value("StackLeniency", formatFloat(beatMap.StackLeniency))
+	writer.value("Mode", strconv.FormatInt(beatMap.Mode, 10))
+
+	writer.section("Metadata")
+	writer.value("Title", beatMap.Name)
+	writer.value("TitleUnicode", beatMap.NameUnicode)
+	writer.value("Artist", beatMap.Artist)
+	writer.value("ArtistUnicode", beatMap.ArtistUnicode)
+	writer.value("Creator", beatMap.Creator)
+	writer.value("Version", beatMap.Difficulty)
+	writer.value("Source", beatMap.Source)
+	writer.value("Tags", beatMap.Tags)
+	writer.value("BeatmapID", strconv.FormatInt(beatMap.ID, 10))
+	writer.value("BeatmapSetID", strconv.FormatInt(beatMap.SetID, 10))
+
+	writer.section("Difficulty")
+	writer.value("HPDrainRate", hp)
+	writer.value("CircleSize", cs)
+	writer.value("OverallDifficulty", od)
+	writer.value("ApproachRate", ar)
+	writer.value("SliderMultiplier", formatFloat(beatMap.SliderMultiplier))
+	writer.value("SliderTickRate", formatFloat(beatMap.Timings.TickRate))
+
+	writer.section("Events")
+	writer.WriteString("//Background and Video events\n")
+
+	if beatMap.Bg != "" {
+		fmt.Fprintf(writer, "0,0,\"%s\",0,0\n", beatMap.Bg)
+	}
+
+	writer.WriteString("//Break Periods\n")
+
+	for _, pause := range beatMap.Pauses {
+		fmt.Fprintf(writer, "2,%s,%s\n", formatFloat(writer.time(pause.StartTime)), formatFloat(writer.time(pause.EndTime)))
+	}
+
+	writer.section("TimingPoints")
+
+	for _, point := range beatMap.Timings.GetPoints() {
+		writer.timingPoint(point)
+	}
+
+	if len(beatMap.Colors) > 0 {
+		writer.section("Colours") //nolint:misspell
+
+		for i, c := range beatMap.Colors {
+			fmt.Fprintf(writer, "Combo%d : %d,%d,%d\n", i+1, colorComponent(c.R), colorComponent(c.G), colorComponent(c.B))
+		}
+	}
+
+	writer.section("HitObjects")
+
+	for _, obj := range beatMap.HitObjects {
+		writer.hitObject(obj)
+	}
+
+	return writer.Flush()
+}
+
+// time scales object's time by the rate, rounding it like osu! does when saving
+func (writer *encoder) time(time float64) float64 {
+	if writer.rate == 1 {
+		return time
+	}
+
+	return math.Round(time / writer.rate)
+}
+
+func (writer *encoder) previewTime(time int64) int64 {
+	if time < 0 {
+		return time
+	}
+
+	return int64(writer.time(float64(time)))
+}
+
+func (writer *encoder) position(pos vector.Vector2f) string {
+	if writer.flip {
+		pos.Y = 384 - pos.Y
+	}
+
+	return formatFloat32(pos.X) + ":" + formatFloat32(pos.Y)
+}
+
+func (writer *encoder) section(name string) {
+	fmt.Fprintf(writer, "\n[%s]\n", name)
+}
+
+func (writer *encoder) value(key, value string) {
+	fmt.Fprintf(writer, "%s:%s\n", key, value)
+}
+
+func (writer *encoder) timingPoint(point objects.TimingPoint) {
+	uninherited := 1
+	if point.Inherited {
+		uninherited = 0
+	}
+
+	// Inherited points are relative to the uninherited ones so only the latter need to be scaled
+	beatLength := point.GetRawBeatLength()
+	if !point.Inherited {
+		beatLength /= writer.rate
+	}
+
+	effects := 0
+
+	if point.Kiai {
+		effects |= 1
+	}
+
+	if point.OmitFirstBarLine {
+		effects |= 8
+	}
+
+	fmt.Fprintf(writer, "%s,%s,%d,%d,%d,%d,%d,%d\n",
+		formatFloat(writer.time(point.Time)), // Rounded the same way as objects, so objects placed on a timing point stay on it
+		formatFloat(beatLength),
+		point.Signature,
+		point.SampleSet,
+		point.SampleIndex,
+		int(math.Round(point.SampleVolume*100)),
+		uninherited,
+		effects,
+	)
+}
+
+func (writer *encoder) hitObject(obj objects.IHitObject) {
+	objType := obj.GetColorOffset() << 4
+	if obj.IsNewCombo() {
+		objType |= int64(objects.NEWCOMBO)
+	}
+
+	prefix := fmt.Sprintf("%s,%s", strings.Replace(writer.position(obj.GetStartPosition()), ":", ",", 1), formatFloat(writer.time(obj.GetStartTime())))
+
+	switch o := obj.(type) {
+	case *objects.Circle:
+		// osu!mania hold notes are parsed as circles lasting until the end of the note
+		if o.GetEndTime() > o.GetStartTime() {
+			fmt.Fprintf(writer, "%s,%d,%d,%s:%s\n", prefix, objType|int64(objects.LONGNOTE), o.GetSample(), formatFloat(writer.time(o.GetEndTime())), formatExtras(o.BasicHitSound))
+		} else {
+			fmt.Fprintf(writer, "%s,%d,%d,%s\n", prefix, objType|int64(objects.CIRCLE), o.GetSample(), formatExtras(o.BasicHitSound))
+		}
+	case *objects.Spinner:
+		fmt.Fprintf(writer, "%s,%d,%d,%s,%s\n", prefix, objType|int64(objects.SPINNER), o.GetSample(), formatFloat(writer.time(o.GetEndTime())), formatExtras(o.BasicHitSound))
+	case *objects.Slider:
+		samples, sampleSets, additionSets := o.GetEdgeSamples()
+
+		edgeSounds := make([]string, len(samples))
+		edgeSets := make([]string, len(samples))
+
+		for i := range samples {
+			edgeSounds[i] = strconv.Itoa(samples[i])
+			edgeSets[i] = fmt.Sprintf("%d:%d", sampleSets[i], additionSets[i])
+		}
+
+		fmt.Fprintf(writer, "%s,%d,%d,%s,%d,%s,%s,%s,%s\n",
+			prefix,
+			objType|int64(objects.SLIDER),
+			o.GetBaseSample(),
+			writer.curve(o.GetCurveDefs(), o.StartPosRaw),
+			o.RepeatCount,
+			formatFloat(o.GetPixelLength()),
+			strings.Join(edgeSounds, "|"),
+			strings.Join(edgeSets, "|"),
+			formatExtras(o.BasicHitSound),
+		)
+	}
+}
+
+// curve writes curve segments in lazer's multi-type format. Points shared by two segments are written once,
+// after the type of the next segment.
+func (writer *encoder) curve(defs []curves.CurveDef, start vector.Vector2f) string {
+	var builder strings.Builder
+
+	for i, def := range defs {
+		if i > 0 {
+			builder.WriteString("|")
+		}
+
+		builder.WriteString(curveTypeName(def.CurveType))
+
+		points := def.Points
+
+		if i == 0 {
+			points = points[1:]
+
+			// Parser skips the first point if it's equal to slider's start position, so write it twice to keep it
+			if len(points) > 0 && points[0] == start {
+				points = append([]vector.Vector2f{start}, points...)
+			}
+		}
+
+		if i < len(defs)-1 && len(points) > 0 {
+			points = points[:len(points)-1]
+		}
+
+		for _, p := range points {
+			builder.WriteString("|" + writer.position(p))
+		}
+	}
+
+	return builder.String()
+}
+
+func curveTypeName(cType curves.CType) string {
+	switch cType {
+	case curves.CLine:
+		return "L"
+	case curves.CBezier:
+		return "B"
+	case curves.CCirArc:
+		return "P"
+	default:
+		return "C"
+	}
+}
+
+func sampleSetName(set int) string {
+	switch set {
+	case 2:
+		return "Soft"
+	case 3:
+		return "Drum"
+	default:
+		return "Normal"
+	}
+}
+
+func formatExtras(info audio.HitSoundInfo) string {
+	return fmt.Sprintf("%d:%d:%d:%d:%s", info.SampleSet, info.AdditionSet, info.CustomIndex, int(math.Round(info.CustomVolume*100)), info.Filename)
+}
+
+func colorComponent(v float32) int {
+	return int(math.Round(float64(v) * 255))
+}
+
+// formatDifficulty caps effective difficulty settings to values allowed in .osu files
+func formatDifficulty(v float64) string {
+	return mutils.FormatWOZeros(mutils.Clamp(v, 0, 10), 2)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func formatFloat32(v float32) string {
+	return strconv.FormatFloat(float64(v), 'f', -1, 32)
+}
@@ -0,0 +1,290 @@
+package beatmap
+
+import (
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/beatmap/objects"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/framework/env"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// encoderFixture has every curve type, a spinner, a break, inherited timing points and a malformed combo colour
+const encoderFixture = `osu file format v14
+
+[General]
+AudioFilename: audio.mp3
+PreviewTime: 1200
+SampleSet: Soft
+StackLeniency: 0.5
+Mode: 0
+
+[Metadata]
+Title:Encoder Fixture
+TitleUnicode:Encoder Fixture
+Artist:danser
+ArtistUnicode:danser
+Creator:danser
+Version:Round Trip
+Source:
+Tags:test fixture
+BeatmapID:0
+BeatmapSetID:-1
+
+[Difficulty]
+HPDrainRate:5
+CircleSize:4.2
+OverallDifficulty:8
+ApproachRate:9.3
+SliderMultiplier:1.6
+SliderTickRate:1
+
+[Events]
+//Background and Video events
+//Break Periods
+2,4200,7000
+
+[TimingPoints]
+500,400,4,2,1,60,1,0
+1300,-80,4,2,1,60,0,1
+2900,-133.333333333333,4,1,0,45,0,0
+8100,300,3,2,0,80,1,8
+
+[Colours]
+Combo1 : 255,128,0
+Combo2 : 0,200,255
+Combo3 : 12,x,40
+Combo4 : 90,90,90
+
+[HitObjects]
+64,96,500,5,2,0:0:0:0:
+120,180,900,2,8,L|260:180,1,140,2|8,1:2|0:0,0:0:0:0:
+300,200,1300,6,0,B|340:120|400:220|400:220|460:120,2,160,0|2|4,0:0|2:1|0:0,2:0:3:70:
+200,300,2100,2,0,P|240:260|300:300,1,120
+420,120,2900,1,4,1:2:0:0:
+256,192,3300,12,0,3900,0:0:0:0:
+150,150,7200,22,0,C|200:100|260:150|310:100,1,200
+300,60,7800,1,0,0:0:5:40:hit.wav
+`
+
+// rateFixture has objects placed on timing points at times that aren't divisible by DT rate
+const rateFixture = `osu file format v14
+
+[General]
+AudioFilename: audio.mp3
+Mode: 0
+
+[Metadata]
+Title:Rate Fixture
+Artist:danser
+Creator:danser
+Version:Rate
+
+[Difficulty]
+HPDrainRate:5
+CircleSize:4
+OverallDifficulty:8
+ApproachRate:9
+SliderMultiplier:1.4
+SliderTickRate:1
+
+[TimingPoints]
+500,300,4,2,0,60,1,0
+1301,-50,4,2,0,60,0,0
+2000.5,-200,4,2,0,60,0,0
+
+[HitObjects]
+100,100,500,1,0,0:0:0:0:
+200,200,1301,2,0,L|400:200,1,140
+300,100,2001,2,0,L|300:300,1,140
+`
+
+func TestMain(m *testing.M) {
+	env.Init("danser")
+
+	songsDir, err := os.MkdirTemp("", "danser-encoder")
+	if err != nil {
+		panic(err)
+	}
+
+	// Beatmap paths are relative to the Songs directory
+	settings.General.OsuSongsDir = songsDir
+
+	code := m.Run()
+
+	_ = os.RemoveAll(songsDir)
+
+	os.Exit(code)
+}
+
+// TestEncoderRoundTrip checks that an encoded map parses back to the same objects, timing points and colours
+func TestEncoderRoundTrip(t *testing.T) {
+	source := loadFixture(t, "source.osu", []byte(encoderFixture))
+
+	if len(source.Colors) != 3 {
+		t.Fatalf("expected malformed colour to be skipped, got %d colours", len(source.Colors))
+	}
+
+	encoded := encodeFixture(t, source)
+
+	if source.Name != encoded.Name || source.Difficulty != encoded.Difficulty || source.Tags != encoded.Tags || source.PreviewTime != encoded.PreviewTime {
+		t.Errorf("metadata differs")
+	}
+
+	if source.Diff.GetBaseAR() != encoded.Diff.GetBaseAR() || source.Diff.GetBaseCS() != encoded.Diff.GetBaseCS() || source.SliderMultiplier != encoded.SliderMultiplier {
+		t.Errorf("difficulty settings differ")
+	}
+
+	if !reflect.DeepEqual(source.Colors, encoded.Colors) {
+		t.Errorf("expected colours %v, got %v", source.Colors, encoded.Colors)
+	}
+
+	if len(source.Pauses) != len(encoded.Pauses) || source.Pauses[0].StartTime != encoded.Pauses[0].StartTime || source.Pauses[0].EndTime != encoded.Pauses[0].EndTime {
+		t.Errorf("breaks differ")
+	}
+
+	if !reflect.DeepEqual(source.Timings.GetPoints(), encoded.Timings.GetPoints()) {
+		t.Errorf("timing points differ")
+	}
+
+	compareObjects(t, source.HitObjects, encoded.HitObjects)
+
+	if last := encoded.HitObjects[len(encoded.HitObjects)-1].(*objects.Circle); last.BasicHitSound.Filename != "hit.wav" {
+		t.Errorf("expected custom sample hit.wav, got %q", last.BasicHitSound.Filename)
+	}
+}
+
+// TestEncoderRateChange checks that objects placed on timing points keep their slider velocity when rate is baked in
+func TestEncoderRateChange(t *testing.T) {
+	source := loadFixture(t, "rate.osu", []byte(rateFixture))
+
+	diff := source.Diff.Clone()
+	diff.SetMods(difficulty.DoubleTime)
+
+	path := filepath.Join(settings.General.GetSongsDir(), "rate-encoded.osu")
+
+	if err := SaveBeatMap(source, diff, path); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encoded := loadFixture(t, "rate-encoded.osu", data)
+
+	if len(source.HitObjects) != len(encoded.HitObjects) {
+		t.Fatalf("expected %d objects, got %d", len(source.HitObjects), len(encoded.HitObjects))
+	}
+
+	for i, o := range source.HitObjects {
+		e := source.Timings.GetPointAt(o.GetStartTime())
+		a := encoded.Timings.GetPointAt(encoded.HitObjects[i].GetStartTime())
+
+		if e.GetRatio() != a.GetRatio() {
+			t.Errorf("object %d: expected slider velocity ratio %v, got %v", i, e.GetRatio(), a.GetRatio())
+		}
+	}
+}
+
+// loadFixture writes map data to the Songs directory and parses it the same way as the calc command
+func loadFixture(t *testing.T, name string, data []byte) *BeatMap {
+	t.Helper()
+
+	path := filepath.Join(settings.General.GetSongsDir(), name)
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	bMap := ParseBeatMapFile(file)
+	if bMap == nil {
+		t.Fatalf("Failed to parse %s", name)
+	}
+
+	ParseTimingPointsAndPauses(bMap)
+	ParseObjects(bMap, true, false, false)
+
+	return bMap
+}
+
+func encodeFixture(t *testing.T, bMap *BeatMap) *BeatMap {
+	t.Helper()
+
+	path := filepath.Join(settings.General.GetSongsDir(), "encoded.osu")
+
+	if err := SaveBeatMap(bMap, nil, path); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return loadFixture(t, "encoded.osu", data)
+}
+
+func compareObjects(t *testing.T, expected, actual []objects.IHitObject) {
+	t.Helper()
+
+	if len(expected) != len(actual) {
+		t.Fatalf("expected %d objects, got %d", len(expected), len(actual))
+	}
+
+	for i, e := range expected {
+		a := actual[i]
+
+		if e.GetType() != a.GetType() || e.GetStartTime() != a.GetStartTime() || e.GetEndTime() != a.GetEndTime() {
+			t.Errorf("object %d: expected %v at %v-%v, got %v at %v-%v", i, e.GetType(), e.GetStartTime(), e.GetEndTime(), a.GetType(), a.GetStartTime(), a.GetEndTime())
+			continue
+		}
+
+		if e.GetStartPosition() != a.GetStartPosition() || e.GetEndPosition() != a.GetEndPosition() {
+			t.Errorf("object %d: positions differ", i)
+		}
+
+		if e.IsNewCombo() != a.IsNewCombo() || e.GetComboSet() != a.GetComboSet() || e.GetColorOffset() != a.GetColorOffset() {
+			t.Errorf("object %d: combo differs", i)
+		}
+
+		switch eo := e.(type) {
+		case *objects.Circle:
+			ao := a.(*objects.Circle)
+
+			if eo.GetSample() != ao.GetSample() || eo.BasicHitSound != ao.BasicHitSound {
+				t.Errorf("object %d: hitsounds differ", i)
+			}
+		case *objects.Spinner:
+			ao := a.(*objects.Spinner)
+
+			if eo.GetSample() != ao.GetSample() || eo.BasicHitSound != ao.BasicHitSound {
+				t.Errorf("object %d: hitsounds differ", i)
+			}
+		case *objects.Slider:
+			ao := a.(*objects.Slider)
+
+			if !reflect.DeepEqual(eo.GetCurveDefs(), ao.GetCurveDefs()) || eo.RepeatCount != ao.RepeatCount || eo.GetPixelLength() != ao.GetPixelLength() {
+				t.Errorf("object %d: slider paths differ", i)
+			}
+
+			eSamples, eSets, eAdditions := eo.GetEdgeSamples()
+			aSamples, aSets, aAdditions := ao.GetEdgeSamples()
+
+			if eo.GetBaseSample() != ao.GetBaseSample() || eo.BasicHitSound != ao.BasicHitSound ||
+				!reflect.DeepEqual(eSamples, aSamples) || !reflect.DeepEqual(eSets, aSets) || !reflect.DeepEqual(eAdditions, aAdditions) {
+				t.Errorf("object %d: hitsounds differ", i)
+			}
+		}
+	}
+}
@@ -13,7 +13,9 @@ import (
 	color2 "github.com/wieku/danser-go/framework/math/color"
 	"github.com/wieku/danser-go/framework/math/vector"
 	"math"
+	"slices"
 	"strconv"
+	"strings"
 )
 
 const defaultCircleName = "hit"
@@ -63,6 +65,30 @@ func NewCircle(data []string) *Circle {
 	return circle
 }
 
+// NewLongNote creates a circle lasting until osu!mania's hold note end. It's used only by mania ruleset.
+func NewLongNote(data []string) *Circle {
+	// Hold note's extras are prefixed with its end time, so strip it to parse them like circle's
+	extras := strings.SplitN(data[5], ":", 2)
+
+	data = slices.Clone(data)
+	data[5] = ""
+
+	if len(extras) > 1 {
+		data[5] = extras[1]
+	}
+
+	circle := NewCircle(data)
+
+	endTime, _ := strconv.ParseFloat(extras[0], 64)
+	circle.EndTime = max(circle.StartTime, endTime)
+
+	return circle
+}
+
+func (circle *Circle) GetSample() int {
+	return circle.sample
+}
+
 func DummyCircle(pos vector.Vector2f, time float64) *Circle {
 	return DummyCircleInherit(pos, time, false, false, false)
 }
@@ -120,6 +146,13 @@ func (circle *Circle) PlaySound() {
 		return
 	}
 
+	hitSound := circle.GetHitSound()
+
+	audio.PlaySample(hitSound.Info.SampleSet, hitSound.Info.AdditionSet, hitSound.Sample, hitSound.Info.CustomIndex, hitSound.Info.CustomVolume, circle.HitObjectID, circle.GetStackedStartPositionMod(circle.diff).X64())
+}
+
+// GetHitSound returns circle's hit sound with sample sets, index and volume resolved from the timing point
+func (circle *Circle) GetHitSound() audio.HitSound {
 	point := circle.Timings.GetPointAt(circle.StartTime)
 
 	index := circle.BasicHitSound.CustomIndex
@@ -133,7 +166,20 @@ func (circle *Circle) PlaySound() {
 		sampleSet = point.SampleSet
 	}
 
-	audio.PlaySample(sampleSet, circle.BasicHitSound.AdditionSet, circle.sample, index, point.SampleVolume, circle.HitObjectID, circle.GetStackedStartPositionMod(circle.diff).X64())
+	additionSet := circle.BasicHitSound.AdditionSet
+	if additionSet == 0 {
+		additionSet = sampleSet
+	}
+
+	return audio.HitSound{
+		Sample: circle.sample,
+		Info: audio.HitSoundInfo{
+			SampleSet:    sampleSet,
+			AdditionSet:  additionSet,
+			CustomIndex:  index,
+			CustomVolume: point.SampleVolume,
+		},
+	}
 }
 
 func (circle *Circle) SetTiming(timings *Timings, _ int, _ bool) {
@@ -226,6 +272,10 @@ func (circle *Circle) SetDifficulty(diff *difficulty.Difficulty) {
 }
 
 func (circle *Circle) Arm(clicked bool, time float64) {
+	if circle.diff == nil { // sprites are not initialized in headless mode
+		return
+	}
+
 	circle.hitCircle.ClearTransformations()
 	circle.hitCircleOverlay.ClearTransformations()
 	circle.comboText.ClearTransformations()
@@ -53,6 +53,7 @@ type Slider struct {
 	*HitObject
 
 	multiCurve  *curves.MultiCurve
+	curveDefs   []curves.CurveDef
 	scorePath   []PathLine
 	Timings     *Timings
 	TPoint      TimingPoint
@@ -249,6 +250,8 @@ func (slider *Slider) parseCurve(curveData string) *curves.MultiCurve {
 		}
 	}
 
+	slider.curveDefs = defs
+
 	return curves.NewMultiCurveT(defs, slider.pixelLength)
 }
 
@@ -267,6 +270,24 @@ func tryGetType(str string) curves.CType {
 	}
 }
 
+// GetCurveDefs returns curve segments as they were defined in .osu file, first point of the first segment is slider's start position
+func (slider *Slider) GetCurveDefs() []curves.CurveDef {
+	return slider.curveDefs
+}
+
+func (slider *Slider) GetPixelLength() float64 {
+	return slider.pixelLength
+}
+
+func (slider *Slider) GetBaseSample() int {
+	return slider.baseSample
+}
+
+// GetEdgeSamples returns hit sounds and sample/addition sets of every slider's edge
+func (slider *Slider) GetEdgeSamples() (samples, sampleSets, additionSets []int) {
+	return slider.samples, slider.sampleSets, slider.additionSets
+}
+
 func (slider *Slider) GetLength() float32 {
 	return slider.multiCurve.GetLength()
 }
@@ -738,6 +759,10 @@ func (slider *Slider) Update(time float64) bool {
 }
 
 func (slider *Slider) ArmStart(clicked bool, time float64) {
+	if slider.diff == nil { // sprites are not initialized in headless mode
+		return
+	}
+
 	slider.startCircle.Arm(clicked, time)
 
 	slider.ball.AddTransform(animation.NewSingleTransform(animation.Fade, easing.Linear, slider.StartTime, slider.StartTime, 1, 1))
@@ -840,7 +865,7 @@ func (slider *Slider) initSnake() {
 }
 
 func (slider *Slider) InitSlide(time float64) {
-	if time > slider.EndTime {
+	if time > slider.EndTime || slider.diff == nil {
 		return
 	}
 
@@ -890,6 +915,10 @@ func (slider *Slider) InitSlide(time float64) {
 }
 
 func (slider *Slider) KillSlide(time float64) {
+	if slider.diff == nil { // sprites are not initialized in headless mode
+		return
+	}
+
 	slider.follower.ClearTransformations()
 
 	nextPoint := slider.EndTime
@@ -935,20 +964,46 @@ func (slider *Slider) PlayEdgeSample(index int) {
 		return
 	}
 
+	hitSound := slider.GetEdgeHitSound(index)
+	pos := slider.GetStackedPositionAtMod(slider.StartTime+math.Floor(float64(index)*slider.partLen), slider.diff)
+
+	audio.PlaySample(hitSound.Info.SampleSet, hitSound.Info.AdditionSet, hitSound.Sample, hitSound.Info.CustomIndex, hitSound.Info.CustomVolume, slider.HitObjectID, pos.X64())
+}
+
+// GetEdgeHitSound returns hit sound of slider's head (0), repeat or tail (RepeatCount) with sample sets, index and volume resolved from the timing point
+func (slider *Slider) GetEdgeHitSound(index int) audio.HitSound {
+	point := slider.Timings.GetPointAt(slider.StartTime + math.Floor(float64(index)*slider.partLen) + 5)
+
 	sampleSet := slider.sampleSets[index]
 	if sampleSet == 0 && index == 0 {
 		sampleSet = slider.BasicHitSound.SampleSet
 	}
 
-	slider.playSampleT(sampleSet, slider.additionSets[index], slider.samples[index], slider.Timings.GetPointAt(slider.StartTime+math.Floor(float64(index)*slider.partLen)+5), slider.GetStackedPositionAtMod(slider.StartTime+math.Floor(float64(index)*slider.partLen), slider.diff))
+	if sampleSet == 0 {
+		sampleSet = point.SampleSet
+	}
+
+	additionSet := slider.additionSets[index]
+	if additionSet == 0 {
+		additionSet = sampleSet
+	}
+
+	return audio.HitSound{
+		Sample: slider.samples[index],
+		Info: audio.HitSoundInfo{
+			SampleSet:    sampleSet,
+			AdditionSet:  additionSet,
+			CustomIndex:  point.SampleIndex,
+			CustomVolume: point.SampleVolume,
+		},
+	}
 }
 
 func (slider *Slider) HitEdge(index int, time float64, isHit bool) {
 	if index == 0 {
 		slider.ArmStart(isHit, time)
-	} else {
-		e := slider.edges[index]
-		e.Arm(isHit, time)
+	} else if index < len(slider.edges) {
+		slider.edges[index].Arm(isHit, time)
 	}
 
 	if isHit && (index == 0 || index == slider.RepeatCount || !slider.IsRetarded()) {
@@ -964,18 +1019,6 @@ func (slider *Slider) PlayTick() {
 	audio.PlaySliderTick(slider.Timings.Current.SampleSet, slider.Timings.Current.SampleIndex, slider.Timings.Current.SampleVolume, slider.HitObjectID, slider.Pos.X64())
 }
 
-func (slider *Slider) playSampleT(sampleSet, additionSet, sample int, point TimingPoint, pos vector.Vector2f) {
-	if sampleSet == 0 {
-		sampleSet = point.SampleSet
-	}
-
-	if additionSet == 0 {
-		additionSet = sampleSet
-	}
-
-	audio.PlaySample(sampleSet, additionSet, sample, point.SampleIndex, point.SampleVolume, slider.HitObjectID, pos.X64())
-}
-
 func (slider *Slider) GetPosition() vector.Vector2f {
 	return slider.Pos
 }
@@ -92,6 +92,10 @@ func NewDummySpinner(startTime, endTime float64) *Spinner {
 	}
 }
 
+func (spinner *Spinner) GetSample() int {
+	return spinner.sample
+}
+
 func (spinner *Spinner) GetPosition() vector.Vector2f {
 	return spinner.pos
 }
@@ -169,7 +173,7 @@ func (spinner *Spinner) SetDifficulty(diff *difficulty.Difficulty) {
 
 	spinner.frontSprites.Add(spinner.spin)
 
-	spinner.spinnerbonus = audio.LoadSample("spinnerbonus")
+	spinner.spinnerbonus = audio.LoadHitSample("spinnerbonus")
 	spinner.bonusFade = animation.NewGlider(0.0)
 	spinner.bonusScale = animation.NewGlider(0.0)
 
@@ -313,6 +317,13 @@ func (spinner *Spinner) Hit(_ float64, isHit bool) {
 		return
 	}
 
+	hitSound := spinner.GetHitSound()
+
+	audio.PlaySample(hitSound.Info.SampleSet, hitSound.Info.AdditionSet, hitSound.Sample, hitSound.Info.CustomIndex, hitSound.Info.CustomVolume, spinner.HitObjectID, spinner.StartPosRaw.X64())
+}
+
+// GetHitSound returns spinner's end hit sound with sample sets, index and volume resolved from the timing point
+func (spinner *Spinner) GetHitSound() audio.HitSound {
 	point := spinner.Timings.GetPointAt(spinner.EndTime)
 
 	index := spinner.BasicHitSound.CustomIndex
@@ -325,7 +336,20 @@ func (spinner *Spinner) Hit(_ float64, isHit bool) {
 		sampleSet = point.SampleSet
 	}
 
-	audio.PlaySample(sampleSet, spinner.BasicHitSound.AdditionSet, spinner.sample, index, point.SampleVolume, spinner.HitObjectID, spinner.StartPosRaw.X64())
+	additionSet := spinner.BasicHitSound.AdditionSet
+	if additionSet == 0 {
+		additionSet = sampleSet
+	}
+
+	return audio.HitSound{
+		Sample: spinner.sample,
+		Info: audio.HitSoundInfo{
+			SampleSet:    sampleSet,
+			AdditionSet:  additionSet,
+			CustomIndex:  index,
+			CustomVolume: point.SampleVolume,
+		},
+	}
 }
 
 func (spinner *Spinner) SetRotation(f float64) {
@@ -345,6 +369,10 @@ func (spinner *Spinner) SetRPM(rpm float64) {
 }
 
 func (spinner *Spinner) UpdateCompletion(completion float64) {
+	if spinner.diff == nil { // sprites are not initialized in headless mode
+		return
+	}
+
 	if completion > 0 && spinner.completion == 0 {
 		spinner.spin.AddTransform(animation.NewSingleTransform(animation.Fade, easing.Linear, spinner.lastTime, spinner.lastTime+300, 1.0, 0.0))
 	}
@@ -382,7 +410,7 @@ func (spinner *Spinner) StartSpinSample() {
 	}
 
 	if spinner.loopSample == nil {
-		sample := audio.LoadSample("spinnerspin")
+		sample := audio.LoadHitSample("spinnerspin")
 		if sample != nil {
 			spinner.loopSample = sample.PlayLoop()
 		}
@@ -417,11 +445,19 @@ func (spinner *Spinner) StopSpinSample() {
 }
 
 func (spinner *Spinner) Clear() {
+	if spinner.diff == nil { // sprites are not initialized in headless mode
+		return
+	}
+
 	spinner.clear.AddTransform(animation.NewSingleTransform(animation.Scale, easing.OutBack, spinner.lastTime, spinner.lastTime+spinner.diff.TimeFadeIn, 2.0, 1.0))
 	spinner.clear.AddTransform(animation.NewSingleTransform(animation.Fade, easing.OutQuad, spinner.lastTime, spinner.lastTime+spinner.diff.TimeFadeIn, 0.0, 1.0))
 }
 
 func (spinner *Spinner) Bonus(bonusValue int) {
+	if spinner.diff == nil { // sprites are not initialized in headless mode
+		return
+	}
+
 	if spinner.glow != nil {
 		spinner.glow.AddTransform(animation.NewColorTransform(animation.Color3, easing.OutQuad, spinner.lastTime, spinner.lastTime+difficulty.HitFadeOut, color2.Color{R: 1, G: 1, B: 1, A: 1}, spinnerBlue))
 	}
@@ -36,6 +36,11 @@ func (t TimingPoint) GetBaseBeatLength() float64 {
 	return t.beatLengthBase
 }
 
+// GetRawBeatLength returns beat length as it was defined in .osu file, it's negative for inherited points
+func (t TimingPoint) GetRawBeatLength() float64 {
+	return t.beatLength
+}
+
 func (t TimingPoint) GetBeatLength() float64 {
 	return t.beatLengthBase * t.GetRatio()
 }
@@ -158,6 +163,10 @@ func (tim *Timings) GetTickDistance(point TimingPoint) float64 {
 	return tim.GetScoringDistance() / point.GetRatio()
 }
 
+func (tim *Timings) GetPoints() []TimingPoint {
+	return tim.points
+}
+
 func (tim *Timings) HasPoints() bool {
 	return len(tim.points) > 0
 }
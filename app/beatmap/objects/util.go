@@ -5,20 +5,23 @@ import (
 	"strconv"
 )
 
-func CreateObject(data []string) IHitObject {
+// CreateObject parses a hit object line, spinners are skipped if they are disabled in settings, unless forceSpinners is set
+func CreateObject(data []string, forceSpinners bool) IHitObject {
 	objTypeI, _ := strconv.Atoi(data[3])
 	objType := Type(objTypeI)
 
 	if (objType & CIRCLE) > 0 {
 		return NewCircle(data)
 	} else if (objType & SPINNER) > 0 {
-		if settings.Objects.LoadSpinners || settings.KNOCKOUT || settings.PLAY {
+		if forceSpinners || settings.Objects.LoadSpinners || settings.KNOCKOUT || settings.PLAY {
 			return NewSpinner(data)
 		}
 	} else if (objType & SLIDER) > 0 {
 		if sl := NewSlider(data); sl != nil {
 			return sl
 		}
+	} else if (objType&LONGNOTE) > 0 && len(data) > 5 {
+		return NewLongNote(data)
 	}
 
 	return nil
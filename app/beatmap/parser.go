@@ -7,6 +7,7 @@ import (
 	"github.com/wieku/danser-go/app/settings"
 	"github.com/wieku/danser-go/app/skin"
 	"github.com/wieku/danser-go/framework/files"
+	"github.com/wieku/danser-go/framework/math/color"
 	"github.com/wieku/danser-go/framework/math/mutils"
 	"math"
 	"os"
@@ -107,8 +108,8 @@ func parseEvents(line []string, beatMap *BeatMap) {
 	}
 }
 
-func parseHitObjects(line []string, beatMap *BeatMap) {
-	obj := objects.CreateObject(line)
+func parseHitObjects(line []string, beatMap *BeatMap, forceSpinners bool) {
+	obj := objects.CreateObject(line, forceSpinners)
 
 	if obj != nil {
 		beatMap.HitObjects = append(beatMap.HitObjects, obj)
@@ -300,7 +301,8 @@ func ParseTimingPointsAndPauses(beatMap *BeatMap) {
 	beatMap.FinalizePoints()
 }
 
-func ParseObjects(beatMap *BeatMap, diffCalcOnly, parseColors bool) {
+// ParseObjects loads hit objects of the beatmap. Spinners disabled in settings are loaded anyway if forceSpinners is set, e.g. when the map is exported.
+func ParseObjects(beatMap *BeatMap, diffCalcOnly, parseColors, forceSpinners bool) {
 	file, err := os.Open(filepath.Join(settings.General.GetSongsDir(), beatMap.Dir, beatMap.File))
 	if err != nil {
 		panic(err)
@@ -317,6 +319,13 @@ func ParseObjects(beatMap *BeatMap, diffCalcOnly, parseColors bool) {
 
 	var currentSection string
 
+	type comboColor struct {
+		index int
+		color color.Color
+	}
+
+	var colors []comboColor
+
 	for scanner.Scan() {
 		line := scanner.Text()
 
@@ -333,14 +342,20 @@ func ParseObjects(beatMap *BeatMap, diffCalcOnly, parseColors bool) {
 
 		switch currentSection {
 		case "Colours": //nolint:misspell
-			if parseColors {
-				if arr := tokenize(line, ":"); arr != nil {
+			if arr := tokenize(line, ":"); arr != nil {
+				if parseColors {
 					skin.AddBeatmapColor(arr)
 				}
+
+				if index, err := strconv.Atoi(strings.TrimPrefix(arr[0], "Combo")); err == nil {
+					if clr, ok := parseComboColor(arr[1]); ok {
+						colors = append(colors, comboColor{index, clr})
+					}
+				}
 			}
 		case "HitObjects":
 			if arr := tokenize(line, ","); arr != nil {
-				parseHitObjects(arr, beatMap)
+				parseHitObjects(arr, beatMap, forceSpinners)
 			}
 		}
 	}
@@ -353,6 +368,16 @@ func ParseObjects(beatMap *BeatMap, diffCalcOnly, parseColors bool) {
 		skin.FinishBeatmapColors()
 	}
 
+	slices.SortStableFunc(colors, func(a, b comboColor) int {
+		return cmp.Compare(a.index, b.index)
+	})
+
+	beatMap.Colors = beatMap.Colors[:0]
+
+	for _, c := range colors {
+		beatMap.Colors = append(beatMap.Colors, c.color)
+	}
+
 	num := 0
 	comboNumber := 1
 	comboSet := 0
@@ -385,11 +410,40 @@ func ParseObjects(beatMap *BeatMap, diffCalcOnly, parseColors bool) {
 		num++
 	}
 
+	lastObjectEnd := 0.0
+
 	for _, obj := range beatMap.HitObjects {
 		obj.SetTiming(beatMap.Timings, beatMap.Version, diffCalcOnly)
+
+		lastObjectEnd = max(lastObjectEnd, obj.GetEndTime())
+	}
+
+	if len(beatMap.HitObjects) > 0 {
+		beatMap.Diff.SetRampTimes(beatMap.HitObjects[0].GetStartTime(), lastObjectEnd)
 	}
 
 	if settings.Objects.StackEnabled || settings.KNOCKOUT || settings.PLAY || diffCalcOnly {
 		beatMap.CalculateStackLeniency(beatMap.Diff)
 	}
 }
+
+// parseComboColor parses a combo colour without panicking, malformed colours are skipped so the map can still be loaded
+func parseComboColor(text string) (color.Color, bool) {
+	divided := strings.Split(text, ",")
+	if len(divided) < 3 {
+		return color.Color{}, false
+	}
+
+	var components [3]float32
+
+	for i := range components {
+		v, err := strconv.ParseFloat(strings.TrimSpace(divided[i]), 32)
+		if err != nil {
+			return color.Color{}, false
+		}
+
+		components[i] = float32(v) / 255
+	}
+
+	return color.NewRGB(components[0], components[1], components[2]), true
+}
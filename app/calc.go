@@ -0,0 +1,388 @@
+package app
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/database"
+	"github.com/wieku/danser-go/app/rulesets/osu/performance"
+	"github.com/wieku/danser-go/app/rulesets/osu/performance/api"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/framework/goroutines"
+	"github.com/wieku/danser-go/framework/math/mutils"
+	"github.com/wieku/danser-go/framework/util"
+	"github.com/wieku/rplpa"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+type calcScore struct {
+	acc    float64
+	combo  int
+	misses int
+	n100   int
+	n50    int
+}
+
+type calcJob struct {
+	path    string
+	beatMap *beatmap.BeatMap
+	err     error
+}
+
+type calcResult struct {
+	File       string           `json:"file"`
+	MD5        string           `json:"md5"`
+	Artist     string           `json:"artist"`
+	Title      string           `json:"title"`
+	Difficulty string           `json:"difficulty"`
+	Creator    string           `json:"creator"`
+	Mods       string           `json:"mods"`
+	PPVersion  string           `json:"ppVersion"`
+	Score      *api.PerfScore   `json:"score,omitempty"`
+	Attributes *api.Attributes  `json:"attributes,omitempty"`
+	PP         *api.PPv2Results `json:"pp,omitempty"`
+	Error      string           `json:"error,omitempty"`
+}
+
+type ppCalculators struct {
+	version string
+	diff    api.IDifficultyCalculator
+	pp      func() api.IPerformanceCalculator
+}
+
+func runCalc(args []string) int {
+	fs := flag.NewFlagSet("calc", flag.ExitOnError)
+
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: danser calc [flags] [path/to/map.osu ...]")
+		fmt.Fprintln(fs.Output(), "Calculates star rating and pp of given maps without opening a window. Results are printed as JSON, one line per map and mod combination.")
+		fs.PrintDefaults()
+	}
+
+	md5s := fs.String("md5", "", "Comma separated list of beatmap md5 hashes, beatmaps are looked up in danser's database")
+	mods := fs.String("mods", "", "Comma separated list of mod combinations, e.g. -mods=NM,HD,HDDT")
+	mods2 := fs.String("mods2", "", "Mods, lazer style. Overrides -mods")
+	ppVersion := fs.String("ppversion", "", "PP version to use, \"all\" calculates all of them. Defaults to Gameplay.PPVersion setting. Available: "+strings.Join(performance.Versions, ", "))
+
+	acc := fs.Float64("acc", 100, "Accuracy in percent, used to estimate 100s and 50s if -n100 and -n50 are not given")
+	combo := fs.Int("combo", -1, "Max combo, defaults to map's max combo")
+	misses := fs.Int("misses", 0, "Amount of misses")
+	n100 := fs.Int("n100", -1, "Amount of 100s")
+	n50 := fs.Int("n50", -1, "Amount of 50s")
+
+	settingsVersion := fs.String("settings", "", "Specify settings version, used to locate the Songs directory")
+	noDbCheck := fs.Bool("nodbcheck", false, "Don't validate the database and only import new beatmap sets if there are any")
+
+	_ = fs.Parse(args)
+
+	if *md5s == "" && fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	settings.LoadSettings(*settingsVersion)
+
+	modList, err := parseCalcMods(*mods, *mods2)
+	if err != nil {
+		panic(err)
+	}
+
+	var versions []string
+
+	switch *ppVersion {
+	case "all":
+		versions = performance.Versions
+	case "", "latest":
+		versions = []string{performance.GetVersion(settings.Gameplay.PPVersion).ID}
+	default:
+		if !slices.Contains(performance.Versions, *ppVersion) {
+			panic(fmt.Sprintf("Unknown pp version: %s", *ppVersion))
+		}
+
+		versions = []string{*ppVersion}
+	}
+
+	calcs := make([]*ppCalculators, 0, len(versions))
+
+	for _, v := range versions {
+		diffInit, ppInit := performance.GetConstructors(v)
+
+		calcs = append(calcs, &ppCalculators{
+			version: v,
+			diff:    diffInit(),
+			pp:      ppInit,
+		})
+	}
+
+	score := calcScore{
+		acc:    *acc,
+		combo:  *combo,
+		misses: *misses,
+		n100:   *n100,
+		n50:    *n50,
+	}
+
+	jobs := make([]calcJob, 0)
+
+	for _, path := range fs.Args() {
+		jobs = append(jobs, loadCalcFile(path))
+	}
+
+	if *md5s != "" {
+		jobs = append(jobs, loadCalcMD5s(strings.Split(*md5s, ","), *noDbCheck)...)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+
+	receive := make(chan []calcResult, runtime.NumCPU())
+
+	goroutines.Run(func() {
+		util.BalanceChan(runtime.NumCPU(), jobs, receive, func(job calcJob) ([]calcResult, bool) {
+			return calculateJob(job, modList, calcs, score), true
+		})
+
+		close(receive)
+	})
+
+	for results := range receive {
+		for _, r := range results {
+			if err := encoder.Encode(r); err != nil {
+				panic(err)
+			}
+		}
+	}
+
+	return 0
+}
+
+func parseCalcMods(mods, mods2 string) ([][]rplpa.ModInfo, error) {
+	if mods2 != "" {
+		var modInfo []rplpa.ModInfo
+
+		if err := json.Unmarshal([]byte(mods2), &modInfo); err != nil {
+			return nil, fmt.Errorf("failed to parse mods: %w", err)
+		}
+
+		return [][]rplpa.ModInfo{modInfo}, nil
+	}
+
+	var modList [][]rplpa.ModInfo
+
+	for _, m := range strings.Split(mods, ",") {
+		m = strings.ToUpper(strings.TrimSpace(m))
+		if m == "NM" {
+			m = ""
+		}
+
+		parsed := difficulty.ParseMods(m)
+
+		if !parsed.Compatible() {
+			return nil, fmt.Errorf("incompatible mods: %s", m)
+		}
+
+		modList = append(modList, parsed.ConvertToModInfoList())
+	}
+
+	return modList, nil
+}
+
+func loadCalcFile(path string) calcJob {
+	// Beatmap paths are resolved relative to the Songs directory, so relative paths have to be made absolute
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return calcJob{path: path, err: err}
+	}
+
+	file, err := os.Open(absPath)
+	if err != nil {
+		return calcJob{path: path, err: err}
+	}
+
+	defer file.Close()
+
+	bMap := beatmap.ParseBeatMapFile(file)
+	if bMap == nil {
+		return calcJob{path: path, err: errors.New("failed to parse the beatmap")}
+	}
+
+	hash := md5.New()
+	if _, err = io.Copy(hash, file); err == nil {
+		bMap.MD5 = hex.EncodeToString(hash.Sum(nil))
+	}
+
+	return calcJob{path: path, beatMap: bMap}
+}
+
+func loadCalcMD5s(md5s []string, noDbCheck bool) []calcJob {
+	if err := database.Init(); err != nil {
+		panic(fmt.Sprintf("Failed to initialize database: %s", err))
+	}
+
+	beatmaps := database.LoadBeatmaps(noDbCheck, nil)
+
+	database.Close()
+
+	jobs := make([]calcJob, 0, len(md5s))
+
+	for _, hash := range md5s {
+		hash = strings.TrimSpace(hash)
+
+		i := slices.IndexFunc(beatmaps, func(b *beatmap.BeatMap) bool {
+			return strings.EqualFold(b.MD5, hash)
+		})
+
+		if i == -1 {
+			jobs = append(jobs, calcJob{err: fmt.Errorf("beatmap with md5 %s not found", hash)})
+			continue
+		}
+
+		jobs = append(jobs, calcJob{beatMap: beatmaps[i]})
+	}
+
+	return jobs
+}
+
+func calculateJob(job calcJob, modList [][]rplpa.ModInfo, calcs []*ppCalculators, score calcScore) (results []calcResult) {
+	if job.err != nil {
+		return []calcResult{{File: job.path, Error: job.err.Error()}}
+	}
+
+	bMap := job.beatMap
+
+	file := job.path
+	if file == "" {
+		file = bMap.Dir + "/" + bMap.File
+	}
+
+	base := calcResult{
+		File:       file,
+		MD5:        bMap.MD5,
+		Artist:     bMap.Artist,
+		Title:      bMap.Name,
+		Difficulty: bMap.Difficulty,
+		Creator:    bMap.Creator,
+	}
+
+	defer func() {
+		if err := recover(); err != nil {
+			log.Println("Failed to calculate \"", base.File, "\":", err)
+
+			base.Error = fmt.Sprint(err)
+			results = append(results, base)
+		}
+	}()
+
+	if bMap.Mode != 0 {
+		base.Error = "only osu!standard maps are supported"
+		return []calcResult{base}
+	}
+
+	defer bMap.Clear()
+
+	for _, mods := range modList {
+		bMap.Clear()
+		bMap.Pauses = nil
+		bMap.Diff.SetMods2(mods)
+
+		// Objects have to be parsed for every mod combination as stacking depends on difficulty
+		beatmap.ParseTimingPointsAndPauses(bMap)
+		beatmap.ParseObjects(bMap, true, false, false)
+
+		if len(bMap.HitObjects) == 0 {
+			base.Error = "beatmap doesn't have any hit objects"
+			return []calcResult{base}
+		}
+
+		for _, c := range calcs {
+			result := base
+			result.Mods = bMap.Diff.GetModString()
+			result.PPVersion = c.version
+
+			attr := c.diff.CalculateSingle(bMap.HitObjects, bMap.Diff)
+			perfScore := score.toPerfScore(attr)
+			pp := c.pp().Calculate(attr, perfScore, bMap.Diff)
+
+			result.Attributes = &attr
+			result.Score = &perfScore
+			result.PP = &pp
+
+			results = append(results, result)
+		}
+	}
+
+	return
+}
+
+// toPerfScore estimates hit counts from accuracy the same way as osu-tools
+func (score calcScore) toPerfScore(attr api.Attributes) api.PerfScore {
+	total := attr.ObjectCount
+
+	countMiss := mutils.Clamp(score.misses, 0, total)
+	countOk := score.n100
+	countMeh := score.n50
+
+	if countOk < 0 && countMeh < 0 {
+		relevantCount := total - countMiss
+
+		relevantAcc := 0.0
+		if relevantCount > 0 {
+			relevantAcc = mutils.Clamp(score.acc/100*float64(total)/float64(relevantCount), 0, 1)
+		}
+
+		if relevantAcc >= 0.25 {
+			ratio50To100 := math.Pow(1-(relevantAcc-0.25)/0.75, 2)
+
+			count100Estimate := 6 * float64(relevantCount) * (1 - relevantAcc) / (5*ratio50To100 + 4)
+			count50Estimate := count100Estimate * ratio50To100
+
+			countOk = int(math.Round(count100Estimate))
+			countMeh = int(math.Round(count100Estimate+count50Estimate)) - countOk
+		} else if relevantAcc >= 1.0/6 {
+			count100Estimate := 6*float64(relevantCount)*relevantAcc - float64(relevantCount)
+			count50Estimate := float64(relevantCount) - count100Estimate
+
+			countOk = int(math.Round(count100Estimate))
+			countMeh = int(math.Round(count100Estimate+count50Estimate)) - countOk
+		} else {
+			countOk = 0
+			countMeh = relevantCount
+		}
+	}
+
+	countOk = mutils.Clamp(countOk, 0, total-countMiss)
+	countMeh = mutils.Clamp(countMeh, 0, total-countMiss-countOk)
+
+	countGreat := total - countOk - countMeh - countMiss
+
+	accuracy := 1.0
+	if total > 0 {
+		accuracy = float64(countGreat*300+countOk*100+countMeh*50) / float64(total*300)
+	}
+
+	combo := score.combo
+	if combo < 0 {
+		combo = attr.MaxCombo
+	}
+
+	return api.PerfScore{
+		Accuracy:   accuracy,
+		MaxCombo:   min(combo, attr.MaxCombo),
+		CountGreat: countGreat,
+		CountOk:    countOk,
+		CountMeh:   countMeh,
+		CountMiss:  countMiss,
+		SliderEnd:  attr.Sliders,
+	}
+}
@@ -0,0 +1,221 @@
+package dance
+
+import (
+	"cmp"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/app/rulesets/mania"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/rplpa"
+	"log"
+	"os"
+	"slices"
+	"time"
+)
+
+type maniaFrame struct {
+	time float64
+	keys uint32
+}
+
+// ManiaController plays back osu!mania replay or generates an autoplay if replay was not given
+type ManiaController struct {
+	bMap    *beatmap.BeatMap
+	cursor  *graphics.Cursor
+	ruleset *mania.ManiaRuleSet
+	control *subControl
+
+	name     string
+	scoreID  int64
+	autoplay bool
+
+	frames     []maniaFrame
+	frameIndex int
+
+	lastTime float64
+}
+
+func NewManiaController() Controller {
+	return &ManiaController{lastTime: -200}
+}
+
+func (controller *ManiaController) SetBeatMap(beatMap *beatmap.BeatMap) {
+	controller.bMap = beatMap
+
+	controller.control = NewSubControl()
+	controller.control.diff = beatMap.Diff.Clone()
+
+	settings.PLAYERS = 1
+
+	if settings.REPLAY == "" {
+		controller.autoplay = true
+		controller.name = settings.Knockout.DanserName
+		controller.scoreID = -1
+
+		controller.control.diff.AddMod(difficulty.Autoplay)
+		beatMap.Diff.AddMod(difficulty.Autoplay)
+
+		return
+	}
+
+	log.Println("Loading: ", settings.REPLAY)
+
+	data, err := os.ReadFile(settings.REPLAY)
+	if err != nil {
+		panic(err)
+	}
+
+	replay, err := rplpa.ParseReplay(data)
+	if err != nil {
+		panic(err)
+	}
+
+	if replay.ReplayData == nil || len(replay.ReplayData) == 0 {
+		panic("Replay is missing input data")
+	}
+
+	log.Printf("Loading replay for \"%s\":", replay.Username)
+
+	controller.name = replay.Username
+	controller.scoreID = replay.ScoreID
+
+	if replay.ScoreInfo != nil && replay.ScoreInfo.Mods != nil && len(replay.ScoreInfo.Mods) > 0 {
+		modsNew := make([]rplpa.ModInfo, 0, len(replay.ScoreInfo.Mods))
+
+		for _, mod := range replay.ScoreInfo.Mods {
+			modsNew = append(modsNew, *mod)
+		}
+
+		controller.control.diff.SetMods2(modsNew)
+	} else {
+		controller.control.diff.SetMods(difficulty.Modifier(replay.Mods))
+	}
+
+	if !beatMap.Diff.Equals(controller.control.diff) {
+		controller.control.diff.SetMods2(beatMap.Diff.ExportMods2())
+		controller.control.modifiedMods = true
+	}
+
+	log.Println("\tMods:", controller.control.diff.GetModString())
+
+	loadFrames(controller.control, replay.ReplayData)
+
+	replayTime := 0.0
+
+	for _, frame := range controller.control.frames {
+		replayTime += frame.Time
+
+		// Pressed columns are stored as a bitmask in X position
+		controller.frames = append(controller.frames, maniaFrame{time: replayTime, keys: uint32(max(0, frame.MouseX))})
+	}
+
+	log.Println("\tExpected score:", replay.Score)
+	log.Println("\tReplay loaded!")
+}
+
+func (controller *ManiaController) InitCursors() {
+	controller.cursor = graphics.NewCursor()
+	controller.cursor.Name = controller.name
+	controller.cursor.ScoreID = controller.scoreID
+	controller.cursor.ScoreTime = time.Now()
+	controller.cursor.ModifiedMods = controller.control.modifiedMods
+	controller.cursor.IsReplay = !controller.autoplay
+	controller.cursor.IsAutoplay = controller.autoplay
+
+	controller.ruleset = mania.NewManiaRuleset(controller.bMap, []*graphics.Cursor{controller.cursor}, []*difficulty.Difficulty{controller.control.diff})
+
+	if controller.autoplay {
+		controller.frames = generateManiaAutoplay(controller.ruleset.GetNotes(), controller.ruleset.GetKeyCount())
+	}
+}
+
+func (controller *ManiaController) Update(time float64, _ float64) {
+	for nTime := controller.lastTime + 1; nTime <= time; nTime++ {
+		controller.updateMain(nTime)
+	}
+
+	if time > controller.lastTime {
+		controller.lastTime = time
+	}
+}
+
+func (controller *ManiaController) updateMain(nTime float64) {
+	for controller.frameIndex < len(controller.frames) && controller.frames[controller.frameIndex].time <= nTime {
+		frame := controller.frames[controller.frameIndex]
+
+		controller.ruleset.UpdateKeys(controller.cursor, int64(frame.time), frame.keys)
+
+		controller.frameIndex++
+	}
+
+	controller.ruleset.Update(int64(nTime))
+}
+
+func (controller *ManiaController) GetCursors() []*graphics.Cursor {
+	return []*graphics.Cursor{controller.cursor}
+}
+
+func (controller *ManiaController) GetRuleset() *mania.ManiaRuleSet {
+	return controller.ruleset
+}
+
+func generateManiaAutoplay(notes []*mania.Note, keys int) []maniaFrame {
+	type keyEvent struct {
+		time    float64
+		column  int
+		pressed bool
+	}
+
+	const releaseDelay = 40.0
+
+	lastInColumn := make([]*mania.Note, keys)
+	nextInColumn := make(map[*mania.Note]*mania.Note)
+
+	for _, n := range notes {
+		if last := lastInColumn[n.Column]; last != nil {
+			nextInColumn[last] = n
+		}
+
+		lastInColumn[n.Column] = n
+	}
+
+	events := make([]keyEvent, 0, len(notes)*2)
+
+	for _, n := range notes {
+		release := n.EndTime
+		if !n.Hold {
+			release = n.StartTime + releaseDelay
+		}
+
+		if next := nextInColumn[n]; next != nil {
+			release = min(release, next.StartTime-(next.StartTime-n.EndTime)/2)
+		}
+
+		events = append(events, keyEvent{n.StartTime, n.Column, true}, keyEvent{max(release, n.StartTime+1), n.Column, false})
+	}
+
+	slices.SortStableFunc(events, func(a, b keyEvent) int {
+		return cmp.Compare(a.time, b.time)
+	})
+
+	frames := make([]maniaFrame, 0, len(events))
+
+	var state uint32
+
+	for _, e := range events {
+		if e.pressed {
+			state |= 1 << e.column
+		} else {
+			state &= ^(1 << e.column)
+		}
+
+		if len(frames) > 0 && frames[len(frames)-1].time == e.time {
+			frames[len(frames)-1].keys = state
+		} else {
+			frames = append(frames, maniaFrame{time: e.time, keys: state})
+		}
+	}
+
+	return frames
+}
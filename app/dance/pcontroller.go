@@ -40,6 +40,8 @@ type PlayerController struct {
 
 	quickRestart     bool
 	quickRestartTime float64
+
+	recorder *ReplayRecorder
 }
 
 func NewPlayerController() Controller {
@@ -58,6 +60,10 @@ func (controller *PlayerController) InitCursors() {
 	controller.window = glfw.GetCurrentContext()
 	controller.ruleset = osu.NewOsuRuleset(controller.bMap, controller.cursors, []*difficulty.Difficulty{controller.bMap.Diff.Clone()})
 
+	if settings.Gameplay.SaveReplays {
+		controller.recorder = NewReplayRecorder(controller.bMap, controller.ruleset, controller.cursors[0])
+	}
+
 	if !controller.bMap.Diff.CheckModActive(difficulty.Relax) {
 		input2.RegisterListener(controller.KeyEvent)
 	} else {
@@ -165,11 +171,23 @@ func (controller *PlayerController) Update(time float64, delta float64) {
 		controller.cursors[0].IsReplayFrame = false
 	}
 
+	if controller.recorder != nil {
+		controller.recorder.Update(int64(time))
+	}
+
 	controller.ruleset.UpdateClickFor(controller.cursors[0], int64(time))
 	controller.ruleset.UpdateNormalFor(controller.cursors[0], int64(time), false)
 	controller.ruleset.UpdatePostFor(controller.cursors[0], int64(time), false)
 	controller.ruleset.Update(int64(time))
 
+	if controller.recorder != nil && !controller.recorder.IsSaved() && controller.ruleset.IsEnded() {
+		if controller.ruleset.IsFailed(controller.cursors[0]) {
+			log.Println("Player failed, replay won't be saved")
+		} else {
+			controller.recorder.Save()
+		}
+	}
+
 	controller.lastTime = time
 
 	controller.cursors[0].Update(delta)
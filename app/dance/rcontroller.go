@@ -46,6 +46,7 @@ type subControl struct {
 	replayIndex     int
 	replayTime      float64
 	frames          []*rplpa.ReplayData
+	loadedFrames    []*rplpa.ReplayData
 	newHandling     bool
 	lastTime        int64
 	oldSpinners     bool
@@ -79,7 +80,9 @@ func NewReplayController() Controller {
 func (controller *ReplayController) SetBeatMap(beatMap *beatmap.BeatMap) {
 	controller.bMap = beatMap
 
-	organizeReplays()
+	if !settings.HEADLESS {
+		organizeReplays()
+	}
 
 	candidates := make([]*rplpa.Replay, 0)
 
@@ -322,9 +325,42 @@ func loadFrames(subController *subControl, frames []*rplpa.ReplayData) {
 	log.Println(fmt.Sprintf("\tReplay duration: %dms", duration))
 
 	subController.frames = frames
+	subController.loadedFrames = frames
 }
 
 func (controller *ReplayController) InitCursors() {
+	controller.initCursors(nil)
+}
+
+// Rewind brings back replays to their beginning on a re-parsed beatmap. Replays are not loaded again and difficulty attributes of the previous ruleset are reused.
+func (controller *ReplayController) Rewind(beatMap *beatmap.BeatMap) {
+	controller.bMap = beatMap
+	controller.lastTime = -200
+
+	for i, c := range controller.controllers {
+		c.replayIndex = 0
+		c.replayTime = 0
+		c.lastTime = 0
+		c.frames = c.loadedFrames
+		c.relaxController = nil
+		c.mouseController = nil
+
+		if c.danceController != nil {
+			c.danceController.SetBeatMap(beatMap)
+		}
+
+		controller.replays[i].Accuracy = 100
+		controller.replays[i].Combo = 0
+		controller.replays[i].Grade = osu.NONE
+	}
+
+	controller.cursors = nil
+
+	controller.initCursors(controller.ruleset)
+}
+
+// initCursors creates cursors and the ruleset, difficulty attributes are taken from the previous ruleset if it's not nil
+func (controller *ReplayController) initCursors(previous *osu.OsuRuleSet) {
 	var diffs []*difficulty.Difficulty
 
 	for i, c := range controller.controllers {
@@ -353,7 +389,10 @@ func (controller *ReplayController) InitCursors() {
 			cursor.IsReplay = true
 
 			cursor.SetPos(vector.NewVec2d(c.frames[0].MouseX, c.frames[0].MouseY).Copy32())
-			cursor.Update(0)
+
+			if !settings.HEADLESS {
+				cursor.Update(0)
+			}
 
 			c.replayTime += c.frames[0].Time
 			c.frames = c.frames[1:]
@@ -368,7 +407,11 @@ func (controller *ReplayController) InitCursors() {
 		diffs = append(diffs, c.diff)
 	}
 
-	controller.ruleset = osu.NewOsuRuleset(controller.bMap, controller.cursors, diffs)
+	if previous != nil {
+		controller.ruleset = osu.NewOsuRulesetFrom(previous, controller.bMap, controller.cursors, diffs)
+	} else {
+		controller.ruleset = osu.NewOsuRuleset(controller.bMap, controller.cursors, diffs)
+	}
 
 	for i, c := range controller.controllers {
 		if controller.replays[i].ModsV.Active(difficulty.Relax) {
@@ -395,7 +438,7 @@ func (controller *ReplayController) Update(time float64, delta float64) {
 	controller.updateMain(time)
 
 	for i := range controller.controllers {
-		if controller.controllers[i].danceController == nil {
+		if controller.controllers[i].danceController == nil && !settings.HEADLESS {
 			controller.cursors[i].Update(delta)
 		}
 
@@ -407,7 +450,9 @@ func (controller *ReplayController) Update(time float64, delta float64) {
 }
 
 func (controller *ReplayController) updateMain(nTime float64) {
-	controller.bMap.Update(nTime)
+	if !settings.HEADLESS {
+		controller.bMap.Update(nTime)
+	}
 
 	for i, c := range controller.controllers {
 		if c.danceController != nil {
@@ -0,0 +1,372 @@
+package dance
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/itchio/lzma"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/app/rulesets/osu"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/framework/env"
+	"github.com/wieku/rplpa"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	stableReplayVersion = 20240501
+	lazerReplayVersion  = 30000016
+
+	lifeBarInterval = 2000
+
+	replaySeedTime = -12345
+)
+
+type recordedFrame struct {
+	time int64
+	x, y float32
+	keys int
+}
+
+// ReplayRecorder captures player input from PlayerController and saves it as .osr replay
+type ReplayRecorder struct {
+	bMap    *beatmap.BeatMap
+	ruleset *osu.OsuRuleSet
+	cursor  *graphics.Cursor
+
+	frames  []recordedFrame
+	lifeBar []rplpa.LifeBarGraph
+
+	lastLifeTime int64
+
+	saved bool
+}
+
+func NewReplayRecorder(bMap *beatmap.BeatMap, ruleset *osu.OsuRuleSet, cursor *graphics.Cursor) *ReplayRecorder {
+	return &ReplayRecorder{
+		bMap:         bMap,
+		ruleset:      ruleset,
+		cursor:       cursor,
+		frames:       make([]recordedFrame, 0, 1024),
+		lastLifeTime: math.MinInt64,
+	}
+}
+
+// Update records a frame from cursor's current state, ruleset is processed only once per millisecond so frames are as well
+func (recorder *ReplayRecorder) Update(time int64) {
+	if recorder.saved {
+		return
+	}
+
+	frame := recordedFrame{
+		time: time,
+		x:    recorder.cursor.RawPosition.X,
+		y:    recorder.cursor.RawPosition.Y,
+		keys: recorder.getKeys(),
+	}
+
+	if len(recorder.frames) > 0 && recorder.frames[len(recorder.frames)-1].time >= time {
+		// Ruleset sees only the last state in a given millisecond
+		recorder.frames[len(recorder.frames)-1] = frame
+	} else {
+		recorder.frames = append(recorder.frames, frame)
+	}
+
+	if time >= 0 && time-recorder.lastLifeTime >= lifeBarInterval {
+		recorder.lifeBar = append(recorder.lifeBar, rplpa.LifeBarGraph{
+			Time: int32(time),
+			HP:   float32(recorder.ruleset.GetHP(recorder.cursor)),
+		})
+
+		recorder.lastLifeTime = time
+	}
+}
+
+func (recorder *ReplayRecorder) getKeys() (keys int) {
+	if recorder.cursor.LeftButton {
+		keys |= rplpa.LEFTCLICK
+	}
+
+	if recorder.cursor.RightButton {
+		keys |= rplpa.RIGHTCLICK
+	}
+
+	if recorder.cursor.LeftKey {
+		keys |= rplpa.KEY1
+	}
+
+	if recorder.cursor.RightKey {
+		keys |= rplpa.KEY2
+	}
+
+	if recorder.cursor.SmokeKey {
+		keys |= rplpa.SMOKE
+	}
+
+	return
+}
+
+func (recorder *ReplayRecorder) IsSaved() bool {
+	return recorder.saved
+}
+
+// Save writes the replay to osu! replays directory, or to danser's replays directory if the former doesn't exist
+func (recorder *ReplayRecorder) Save() {
+	if recorder.saved || len(recorder.frames) == 0 {
+		return
+	}
+
+	recorder.saved = true
+
+	diff := recorder.ruleset.GetPlayerDifficulty(recorder.cursor)
+	score := recorder.ruleset.GetScore(recorder.cursor)
+
+	if len(recorder.lifeBar) == 0 || recorder.lifeBar[len(recorder.lifeBar)-1].Time != int32(recorder.frames[len(recorder.frames)-1].time) {
+		recorder.lifeBar = append(recorder.lifeBar, rplpa.LifeBarGraph{
+			Time: int32(recorder.frames[len(recorder.frames)-1].time),
+			HP:   float32(recorder.ruleset.GetHP(recorder.cursor)),
+		})
+	}
+
+	replay := &rplpa.Replay{
+		PlayMode:     rplpa.OSU,
+		OsuVersion:   stableReplayVersion,
+		BeatmapMD5:   recorder.bMap.MD5,
+		Username:     recorder.cursor.Name,
+		Count300:     uint16(min(score.Count300, math.MaxUint16)),
+		Count100:     uint16(min(score.Count100, math.MaxUint16)),
+		Count50:      uint16(min(score.Count50, math.MaxUint16)),
+		CountGeki:    uint16(min(score.CountGeki, math.MaxUint16)),
+		CountKatu:    uint16(min(score.CountKatu, math.MaxUint16)),
+		CountMiss:    uint16(min(score.CountMiss, math.MaxUint16)),
+		Score:        int32(min(score.Score, math.MaxInt32)),
+		MaxCombo:     uint16(min(score.Combo, math.MaxUint16)),
+		Fullcombo:    score.PerfectCombo,
+		Mods:         uint32(toStableMods(diff.Mods)),
+		LifebarGraph: recorder.lifeBar,
+		Timestamp:    time.Now(),
+	}
+
+	if diff.CheckModActive(difficulty.Lazer) {
+		replay.OsuVersion = lazerReplayVersion
+
+		replay.ScoreInfo = &rplpa.ScoreInfo{}
+
+		for _, mod := range diff.ExportMods2() {
+			if mod.Acronym == "LZ" { // Lazer flag is restored from OsuVersion
+				continue
+			}
+
+			replay.ScoreInfo.Mods = append(replay.ScoreInfo.Mods, &mod)
+		}
+	}
+
+	replay.ReplayMD5 = getReplayHash(replay, score.Grade)
+
+	data, err := encodeReplay(replay, recorder.frames)
+	if err != nil {
+		log.Println("Failed to encode the replay:", err)
+		return
+	}
+
+	replayDir := settings.General.GetReplaysDir()
+
+	if _, err = os.Stat(replayDir); err != nil {
+		replayDir = filepath.Join(env.DataDir(), replaysMaster)
+
+		if err = os.MkdirAll(replayDir, 0755); err != nil {
+			log.Println("Failed to create replay directory:", err)
+			return
+		}
+	}
+
+	fileName := fmt.Sprintf("%s - %s - %s [%s] (%s) Osu.osr", replay.Username, recorder.bMap.Artist, recorder.bMap.Name, recorder.bMap.Difficulty, replay.Timestamp.Format("2006-01-02_15-04-05"))
+
+	fileName = strings.Map(func(r rune) rune {
+		if strings.ContainsRune("<>:\"/\\|?*", r) {
+			return '_'
+		}
+
+		return r
+	}, fileName)
+
+	replayPath := filepath.Join(replayDir, fileName)
+
+	if err = os.WriteFile(replayPath, data, 0644); err != nil {
+		log.Println("Failed to save the replay:", err)
+		return
+	}
+
+	log.Println("Replay saved to:", replayPath)
+}
+
+func toStableMods(mods difficulty.Modifier) difficulty.Modifier {
+	if mods.Active(difficulty.Daycore) {
+		mods |= difficulty.HalfTime
+	}
+
+	return mods & (difficulty.LastMod - 1)
+}
+
+func getReplayHash(replay *rplpa.Replay, grade osu.Grade) string {
+	hashBase := fmt.Sprintf("%dp%do%do%dt%da%sr%de%ty%so%du%s%d%t",
+		replay.Count100+replay.Count300,
+		replay.Count50,
+		replay.CountGeki,
+		replay.CountKatu,
+		replay.CountMiss,
+		replay.BeatmapMD5,
+		replay.MaxCombo,
+		replay.Fullcombo,
+		replay.Username,
+		replay.Score,
+		grade.String(),
+		replay.Mods,
+		true,
+	)
+
+	hash := md5.Sum([]byte(hashBase + replay.Timestamp.String()))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// encodeReplay serializes the replay in osu!stable's format. rplpa's writer stores frame deltas as floats which stable can't read
+func encodeReplay(replay *rplpa.Replay, frames []recordedFrame) ([]byte, error) {
+	buf := new(bytes.Buffer)
+
+	_ = binary.Write(buf, binary.LittleEndian, replay.PlayMode)
+	_ = binary.Write(buf, binary.LittleEndian, replay.OsuVersion)
+
+	writeString(buf, replay.BeatmapMD5)
+	writeString(buf, replay.Username)
+	writeString(buf, replay.ReplayMD5)
+
+	_ = binary.Write(buf, binary.LittleEndian, []uint16{replay.Count300, replay.Count100, replay.Count50, replay.CountGeki, replay.CountKatu, replay.CountMiss})
+	_ = binary.Write(buf, binary.LittleEndian, replay.Score)
+	_ = binary.Write(buf, binary.LittleEndian, replay.MaxCombo)
+	_ = binary.Write(buf, binary.LittleEndian, replay.Fullcombo)
+	_ = binary.Write(buf, binary.LittleEndian, replay.Mods)
+
+	lifeBar := make([]string, 0, len(replay.LifebarGraph))
+	for _, l := range replay.LifebarGraph {
+		lifeBar = append(lifeBar, fmt.Sprintf("%d|%s", l.Time, strconv.FormatFloat(float64(l.HP), 'f', -1, 32)))
+	}
+
+	writeString(buf, strings.Join(lifeBar, ","))
+
+	_ = binary.Write(buf, binary.LittleEndian, toTicks(replay.Timestamp))
+
+	frameData, err := compress([]byte(serializeFrames(frames)))
+	if err != nil {
+		return nil, err
+	}
+
+	_ = binary.Write(buf, binary.LittleEndian, int32(len(frameData)))
+	buf.Write(frameData)
+
+	_ = binary.Write(buf, binary.LittleEndian, replay.ScoreID)
+
+	if replay.ScoreInfo != nil {
+		infoJson, err := json.Marshal(replay.ScoreInfo)
+		if err != nil {
+			return nil, err
+		}
+
+		infoData, err := compress(infoJson)
+		if err != nil {
+			return nil, err
+		}
+
+		_ = binary.Write(buf, binary.LittleEndian, int32(len(infoData)))
+		buf.Write(infoData)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func serializeFrames(frames []recordedFrame) string {
+	sb := new(strings.Builder)
+
+	// osu!stable's replays always start with those 2 frames, first one is skipped during playback
+	sb.WriteString("0|256|-500|0,-1|256|-500|0,")
+
+	lastTime := int64(-1)
+
+	for _, f := range frames {
+		sb.WriteString(strconv.FormatInt(f.time-lastTime, 10))
+		sb.WriteByte('|')
+		sb.WriteString(strconv.FormatFloat(float64(f.x), 'f', -1, 32))
+		sb.WriteByte('|')
+		sb.WriteString(strconv.FormatFloat(float64(f.y), 'f', -1, 32))
+		sb.WriteByte('|')
+		sb.WriteString(strconv.Itoa(f.keys))
+		sb.WriteByte(',')
+
+		lastTime = f.time
+	}
+
+	// Seed frame, only used by osu!mania but stable expects it to be present
+	sb.WriteString(fmt.Sprintf("%d|0|0|0,", replaySeedTime))
+
+	return sb.String()
+}
+
+func compress(data []byte) ([]byte, error) {
+	buf := new(bytes.Buffer)
+
+	writer := lzma.NewWriter(buf)
+
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	if s == "" {
+		buf.WriteByte(0)
+		return
+	}
+
+	buf.WriteByte(0x0b)
+
+	length := uint64(len(s))
+
+	for {
+		b := byte(length & 0x7f)
+		length >>= 7
+
+		if length != 0 {
+			b |= 0x80
+		}
+
+		buf.WriteByte(b)
+
+		if length == 0 {
+			break
+		}
+	}
+
+	buf.WriteString(s)
+}
+
+func toTicks(t time.Time) int64 {
+	base := time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+	return t.UnixNano()/100 - base*10000000
+}
@@ -0,0 +1,199 @@
+package dance
+
+import (
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/rulesets/osu"
+	"github.com/wieku/rplpa"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// recordedFixture has sub-pixel positions, every key and a pause between frames
+var recordedFixture = []recordedFrame{
+	{time: -1500, x: 256, y: 192, keys: 0},
+	{time: -1483, x: 256.5, y: 191.25, keys: 0},
+	{time: 0, x: 100.125, y: 50.75, keys: rplpa.KEY1 | rplpa.LEFTCLICK},
+	{time: 16, x: 101, y: 52, keys: rplpa.KEY1 | rplpa.LEFTCLICK},
+	{time: 33, x: 120, y: 80, keys: rplpa.KEY2 | rplpa.RIGHTCLICK},
+	{time: 34, x: 121, y: 81, keys: rplpa.LEFTCLICK | rplpa.SMOKE},
+	{time: 1034, x: -20.5, y: 400, keys: 0},
+}
+
+func TestReplayRoundTrip(t *testing.T) {
+	expected := &rplpa.Replay{
+		PlayMode:   rplpa.OSU,
+		OsuVersion: stableReplayVersion,
+		BeatmapMD5: "0123456789abcdef0123456789abcdef",
+		Username:   "danser",
+		Count300:   120,
+		Count100:   7,
+		Count50:    2,
+		CountGeki:  30,
+		CountKatu:  4,
+		CountMiss:  1,
+		Score:      1234567,
+		MaxCombo:   321,
+		Mods:       uint32(toStableMods(difficulty.Hidden | difficulty.DoubleTime | difficulty.Nightcore)),
+		LifebarGraph: []rplpa.LifeBarGraph{
+			{Time: 0, HP: 1},
+			{Time: 1000, HP: 0.8125},
+			{Time: 1034, HP: 0.5},
+		},
+		// rplpa reads fraction of a second in ticks as nanoseconds, so only whole seconds survive the round trip
+		Timestamp: time.Date(2024, 5, 1, 12, 30, 45, 0, time.UTC),
+	}
+
+	expected.ReplayMD5 = getReplayHash(expected, osu.A)
+
+	replay := encodeAndParse(t, expected)
+
+	if replay.PlayMode != expected.PlayMode || replay.OsuVersion != expected.OsuVersion || replay.BeatmapMD5 != expected.BeatmapMD5 || replay.Username != expected.Username || replay.ReplayMD5 != expected.ReplayMD5 {
+		t.Errorf("Header doesn't match: %+v", replay)
+	}
+
+	counts := []uint16{replay.Count300, replay.Count100, replay.Count50, replay.CountGeki, replay.CountKatu, replay.CountMiss, replay.MaxCombo}
+	if eCounts := []uint16{120, 7, 2, 30, 4, 1, 321}; !reflect.DeepEqual(counts, eCounts) {
+		t.Errorf("Expected counts %v, got %v", eCounts, counts)
+	}
+
+	if replay.Score != expected.Score || replay.Fullcombo != expected.Fullcombo {
+		t.Errorf("Expected score %d, got %d", expected.Score, replay.Score)
+	}
+
+	if replay.Mods != expected.Mods || replay.ScoreInfo != nil {
+		t.Errorf("Expected mods %d without lazer score info, got %d", expected.Mods, replay.Mods)
+	}
+
+	if !replay.Timestamp.Equal(expected.Timestamp) {
+		t.Errorf("Expected timestamp %s, got %s", expected.Timestamp, replay.Timestamp)
+	}
+
+	if !reflect.DeepEqual(replay.LifebarGraph, expected.LifebarGraph) {
+		t.Errorf("Expected life bar %v, got %v", expected.LifebarGraph, replay.LifebarGraph)
+	}
+
+	// osu!stable's lead-in frames, recorded frames and the seed frame
+	if len(replay.ReplayData) != len(recordedFixture)+3 {
+		t.Fatalf("Expected %d frames, got %d", len(recordedFixture)+3, len(replay.ReplayData))
+	}
+
+	if seed := replay.ReplayData[len(replay.ReplayData)-1]; seed.Time != replaySeedTime {
+		t.Errorf("Expected seed frame at the end, got %v", seed.Time)
+	}
+
+	lastTime := int64(-1)
+
+	for i, f := range recordedFixture {
+		frame := replay.ReplayData[i+2]
+
+		if frame.Time != float64(f.time-lastTime) || frame.MouseX != float64(f.x) || frame.MouseY != float64(f.y) || getFrameKeys(frame) != f.keys {
+			t.Errorf("Frame %d: expected %+v, got %v|%v|%v|%d", i, f, frame.Time, frame.MouseX, frame.MouseY, getFrameKeys(frame))
+		}
+
+		lastTime = f.time
+	}
+}
+
+// TestReplayRoundTripLazer checks that lazer mods with settings are stored in score info
+func TestReplayRoundTripLazer(t *testing.T) {
+	expected := &rplpa.Replay{
+		PlayMode:   rplpa.OSU,
+		OsuVersion: lazerReplayVersion,
+		BeatmapMD5: "0123456789abcdef0123456789abcdef",
+		Username:   "danser",
+		Mods:       uint32(toStableMods(difficulty.DoubleTime | difficulty.Classic)),
+		ScoreInfo: &rplpa.ScoreInfo{
+			Mods: []*rplpa.ModInfo{
+				{Acronym: "DT", Settings: map[string]any{"speed_change": 1.3}},
+				{Acronym: "CL"},
+			},
+		},
+		Timestamp: time.Date(2024, 5, 1, 12, 30, 45, 0, time.UTC),
+	}
+
+	replay := encodeAndParse(t, expected)
+
+	if replay.OsuVersion != lazerReplayVersion || replay.Mods != expected.Mods {
+		t.Errorf("Expected lazer version with mods %d, got %d with %d", expected.Mods, replay.OsuVersion, replay.Mods)
+	}
+
+	if replay.ScoreInfo == nil || !reflect.DeepEqual(replay.ScoreInfo.Mods, expected.ScoreInfo.Mods) {
+		t.Fatalf("Expected score info mods %v, got %+v", expected.ScoreInfo.Mods, replay.ScoreInfo)
+	}
+
+	// Same mods are used when the replay is loaded by the replay controller
+	diff := difficulty.NewDifficulty(5, 4, 8, 9)
+	diff.SetMods2([]rplpa.ModInfo{*replay.ScoreInfo.Mods[0], *replay.ScoreInfo.Mods[1]})
+
+	if !diff.CheckModActive(difficulty.DoubleTime|difficulty.Classic) || math.Abs(diff.GetSpeed()-1.3) > 1e-9 {
+		t.Errorf("Expected DTCL at 1.3x, got %s", diff.GetModString())
+	}
+}
+
+// TestReplayFrameTimes checks that frames loaded back by the replay controller are played at recorded times
+func TestReplayFrameTimes(t *testing.T) {
+	replay := encodeAndParse(t, &rplpa.Replay{PlayMode: rplpa.OSU, OsuVersion: stableReplayVersion, Timestamp: time.Now()})
+
+	control := NewSubControl()
+	control.diff = difficulty.NewDifficulty(5, 4, 8, 9)
+
+	loadFrames(control, replay.ReplayData)
+
+	// The first frame only sets initial cursor position, same as in ReplayController.InitCursors
+	replayTime := control.frames[0].Time
+
+	for i, f := range recordedFixture {
+		frame := control.frames[i+1]
+		replayTime += frame.Time
+
+		if replayTime != float64(f.time) || frame.MouseX != float64(f.x) || frame.MouseY != float64(f.y) {
+			t.Errorf("Frame %d: expected %+v, got %v|%v|%v", i, f, replayTime, frame.MouseX, frame.MouseY)
+		}
+	}
+
+	if len(control.frames) != len(recordedFixture)+1 {
+		t.Errorf("Expected %d frames, got %d", len(recordedFixture)+1, len(control.frames))
+	}
+}
+
+func encodeAndParse(t *testing.T, replay *rplpa.Replay) *rplpa.Replay {
+	t.Helper()
+
+	data, err := encodeReplay(replay, recordedFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := rplpa.ParseReplay(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return parsed
+}
+
+func getFrameKeys(frame *rplpa.ReplayData) (keys int) {
+	if frame.KeyPressed.LeftClick {
+		keys |= rplpa.LEFTCLICK
+	}
+
+	if frame.KeyPressed.RightClick {
+		keys |= rplpa.RIGHTCLICK
+	}
+
+	if frame.KeyPressed.Key1 {
+		keys |= rplpa.KEY1
+	}
+
+	if frame.KeyPressed.Key2 {
+		keys |= rplpa.KEY2
+	}
+
+	if frame.KeyPressed.Smoke {
+		keys |= rplpa.SMOKE
+	}
+
+	return
+}
@@ -0,0 +1,247 @@
+package dance
+
+import (
+	"cmp"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/app/rulesets/taiko"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/rplpa"
+	"log"
+	"os"
+	"slices"
+	"time"
+)
+
+type taikoFrame struct {
+	time float64
+	keys taiko.Action
+}
+
+// TaikoController plays back osu!taiko replay or generates an autoplay if replay was not given
+type TaikoController struct {
+	bMap    *beatmap.BeatMap
+	cursor  *graphics.Cursor
+	ruleset *taiko.TaikoRuleSet
+	control *subControl
+
+	name     string
+	scoreID  int64
+	autoplay bool
+
+	frames     []taikoFrame
+	frameIndex int
+
+	lastTime float64
+}
+
+func NewTaikoController() Controller {
+	return &TaikoController{lastTime: -200}
+}
+
+func (controller *TaikoController) SetBeatMap(beatMap *beatmap.BeatMap) {
+	controller.bMap = beatMap
+
+	controller.control = NewSubControl()
+	controller.control.diff = beatMap.Diff.Clone()
+
+	if settings.REPLAY == "" {
+		controller.autoplay = true
+		controller.name = settings.Knockout.DanserName
+		controller.scoreID = -1
+
+		controller.control.diff.AddMod(difficulty.Autoplay)
+		beatMap.Diff.AddMod(difficulty.Autoplay)
+
+		settings.PLAYERS = 1
+
+		return
+	}
+
+	log.Println("Loading: ", settings.REPLAY)
+
+	data, err := os.ReadFile(settings.REPLAY)
+	if err != nil {
+		panic(err)
+	}
+
+	replay, err := rplpa.ParseReplay(data)
+	if err != nil {
+		panic(err)
+	}
+
+	if replay.ReplayData == nil || len(replay.ReplayData) == 0 {
+		panic("Replay is missing input data")
+	}
+
+	log.Printf("Loading replay for \"%s\":", replay.Username)
+
+	controller.name = replay.Username
+	controller.scoreID = replay.ScoreID
+
+	if replay.ScoreInfo != nil && replay.ScoreInfo.Mods != nil && len(replay.ScoreInfo.Mods) > 0 {
+		modsNew := make([]rplpa.ModInfo, 0, len(replay.ScoreInfo.Mods))
+
+		for _, mod := range replay.ScoreInfo.Mods {
+			modsNew = append(modsNew, *mod)
+		}
+
+		controller.control.diff.SetMods2(modsNew)
+	} else {
+		controller.control.diff.SetMods(difficulty.Modifier(replay.Mods))
+	}
+
+	if !beatMap.Diff.Equals(controller.control.diff) {
+		controller.control.diff.SetMods2(beatMap.Diff.ExportMods2())
+		controller.control.modifiedMods = true
+	}
+
+	log.Println("\tMods:", controller.control.diff.GetModString())
+
+	loadFrames(controller.control, replay.ReplayData)
+
+	replayTime := 0.0
+
+	for _, frame := range controller.control.frames {
+		replayTime += frame.Time
+
+		var keys taiko.Action
+
+		if frame.KeyPressed != nil {
+			if frame.KeyPressed.LeftClick {
+				keys |= taiko.LeftCentre
+			}
+
+			if frame.KeyPressed.RightClick {
+				keys |= taiko.LeftRim
+			}
+
+			if frame.KeyPressed.Key1 {
+				keys |= taiko.RightCentre
+			}
+
+			if frame.KeyPressed.Key2 {
+				keys |= taiko.RightRim
+			}
+		}
+
+		controller.frames = append(controller.frames, taikoFrame{time: replayTime, keys: keys})
+	}
+
+	log.Println("\tExpected score:", replay.Score)
+	log.Println("\tReplay loaded!")
+
+	settings.PLAYERS = 1
+}
+
+func (controller *TaikoController) InitCursors() {
+	controller.cursor = graphics.NewCursor()
+	controller.cursor.Name = controller.name
+	controller.cursor.ScoreID = controller.scoreID
+	controller.cursor.ScoreTime = time.Now()
+	controller.cursor.ModifiedMods = controller.control.modifiedMods
+	controller.cursor.IsReplay = !controller.autoplay
+	controller.cursor.IsAutoplay = controller.autoplay
+
+	controller.ruleset = taiko.NewTaikoRuleset(controller.bMap, []*graphics.Cursor{controller.cursor}, []*difficulty.Difficulty{controller.control.diff})
+
+	if controller.autoplay {
+		controller.frames = generateTaikoAutoplay(controller.ruleset.GetObjects())
+	}
+}
+
+func (controller *TaikoController) Update(time float64, _ float64) {
+	for nTime := controller.lastTime + 1; nTime <= time; nTime++ {
+		controller.updateMain(nTime)
+	}
+
+	if time > controller.lastTime {
+		controller.lastTime = time
+	}
+}
+
+func (controller *TaikoController) updateMain(nTime float64) {
+	for controller.frameIndex < len(controller.frames) && controller.frames[controller.frameIndex].time <= nTime {
+		frame := controller.frames[controller.frameIndex]
+
+		controller.ruleset.UpdateKeys(controller.cursor, int64(frame.time), frame.keys)
+
+		controller.frameIndex++
+	}
+
+	controller.ruleset.Update(int64(nTime))
+}
+
+func (controller *TaikoController) GetCursors() []*graphics.Cursor {
+	return []*graphics.Cursor{controller.cursor}
+}
+
+func (controller *TaikoController) GetRuleset() *taiko.TaikoRuleSet {
+	return controller.ruleset
+}
+
+func generateTaikoAutoplay(objects []*taiko.Object) []taikoFrame {
+	presses := make([]taikoFrame, 0, len(objects))
+
+	left := true
+
+	nextKey := func(rim bool) taiko.Action {
+		left = !left
+
+		switch {
+		case rim && left:
+			return taiko.LeftRim
+		case rim:
+			return taiko.RightRim
+		case left:
+			return taiko.LeftCentre
+		}
+
+		return taiko.RightCentre
+	}
+
+	for _, o := range objects {
+		switch o.Type {
+		case taiko.Centre, taiko.Rim:
+			rim := o.Type == taiko.Rim
+
+			keys := nextKey(rim)
+			if o.Strong {
+				keys = taiko.Centres
+				if rim {
+					keys = taiko.Rims
+				}
+			}
+
+			presses = append(presses, taikoFrame{time: o.StartTime, keys: keys})
+		case taiko.DrumRoll:
+			for _, t := range o.Ticks {
+				presses = append(presses, taikoFrame{time: t, keys: nextKey(false)})
+			}
+		case taiko.Swell:
+			interval := (o.EndTime - o.StartTime) / float64(o.RequiredHits+1)
+
+			for i := 0; i < o.RequiredHits; i++ {
+				presses = append(presses, taikoFrame{time: o.StartTime + interval*float64(i+1), keys: nextKey(i%2 == 1)})
+			}
+		}
+	}
+
+	slices.SortStableFunc(presses, func(a, b taikoFrame) int {
+		return cmp.Compare(a.time, b.time)
+	})
+
+	frames := make([]taikoFrame, 0, len(presses)*2)
+
+	for i, press := range presses {
+		release := 30.0
+		if i < len(presses)-1 {
+			release = min(release, (presses[i+1].time-press.time)/2)
+		}
+
+		frames = append(frames, press, taikoFrame{time: press.time + release, keys: 0})
+	}
+
+	return frames
+}
@@ -0,0 +1,27 @@
+package database
+
+import (
+	"github.com/wieku/danser-go/app/beatmap"
+)
+
+type M20261018 struct{}
+
+func (m *M20261018) RequiredSections() []string {
+	return nil
+}
+
+func (m *M20261018) FieldsToMigrate() []string {
+	return nil
+}
+
+func (m *M20261018) GetValues(_ *beatmap.BeatMap) []interface{} {
+	return nil
+}
+
+func (m *M20261018) Date() int {
+	return 20261018
+}
+
+func (m *M20261018) GetMigrationStmts() string {
+	return "DROP TABLE IF EXISTS starRatings; CREATE TABLE starRatings (md5 TEXT, mods INTEGER, speed REAL, finalRate REAL, ppVersion TEXT, calcVersion INTEGER, total REAL, aim REAL, speedStars REAL, speedNoteCount REAL, aimDifficultStrainCount REAL, speedDifficultStrainCount REAL, flashlight REAL, sliderFactor REAL, objectCount INTEGER, circles INTEGER, sliders INTEGER, spinners INTEGER, maxCombo INTEGER, PRIMARY KEY (md5, mods, speed, finalRate, ppVersion));"
+}
@@ -27,7 +27,7 @@ import (
 
 var dbFile *sql.DB
 
-const databaseVersion = 20220622
+const databaseVersion = 20261018
 
 var currentPreVersion = databaseVersion
 var currentSchemaPreVersion = databaseVersion
@@ -74,6 +74,7 @@ func Init() error {
 		&M20210423{},
 		&M20220605{},
 		&M20220622{},
+		&M20261018{},
 	}
 
 	dbFile, err = sql.Open("sqlite3", filepath.Join(env.DataDir(), "danser.db"))
@@ -85,6 +86,7 @@ func Init() error {
 		CREATE TABLE IF NOT EXISTS beatmaps (dir TEXT, file TEXT, lastModified INTEGER, title TEXT, titleUnicode TEXT, artist TEXT, artistUnicode TEXT, creator TEXT, version TEXT, source TEXT, tags TEXT, cs REAL, ar REAL, sliderMultiplier REAL, sliderTickRate REAL, audioFile TEXT, previewTime INTEGER, sampleSet INTEGER, stackLeniency REAL, mode INTEGER, bg TEXT, md5 TEXT, dateAdded INTEGER, playCount INTEGER, lastPlayed INTEGER, hpdrain REAL, od REAL, stars REAL DEFAULT -1, bpmMin REAL, bpmMax REAL, circles INTEGER, sliders INTEGER, spinners INTEGER, endTime INTEGER, setID INTEGER, mapID INTEGER, starsVersion INTEGER DEFAULT 0, localOffset INTEGER DEFAULT 0);
 		CREATE INDEX IF NOT EXISTS idx ON beatmaps (dir, file);
 		CREATE TABLE IF NOT EXISTS info (key TEXT NOT NULL UNIQUE, value TEXT);
+		CREATE TABLE IF NOT EXISTS starRatings (md5 TEXT, mods INTEGER, speed REAL, finalRate REAL, ppVersion TEXT, calcVersion INTEGER, total REAL, aim REAL, speedStars REAL, speedNoteCount REAL, aimDifficultStrainCount REAL, speedDifficultStrainCount REAL, flashlight REAL, sliderFactor REAL, objectCount INTEGER, circles INTEGER, sliders INTEGER, spinners INTEGER, maxCombo INTEGER, PRIMARY KEY (md5, mods, speed, finalRate, ppVersion));
 	`)
 
 	if err != nil {
@@ -159,7 +161,18 @@ func Init() error {
 	return nil
 }
 
+// LoadBeatmaps returns osu!standard maps
 func LoadBeatmaps(skipDatabaseCheck bool, importListener ImportListener) []*beatmap.BeatMap {
+	return loadBeatmaps(skipDatabaseCheck, importListener, false)
+}
+
+// LoadWatchableBeatmaps returns osu!standard, osu!taiko and osu!mania maps.
+// osu!taiko and osu!mania maps can be only watched with autoplay or a single replay, so it should be used only where that's possible.
+func LoadWatchableBeatmaps(skipDatabaseCheck bool, importListener ImportListener) []*beatmap.BeatMap {
+	return loadBeatmaps(skipDatabaseCheck, importListener, true)
+}
+
+func loadBeatmaps(skipDatabaseCheck bool, importListener ImportListener, otherModes bool) []*beatmap.BeatMap {
 	var unpackedMaps []string
 	if settings.General.UnpackOszFiles {
 		unpackedMaps = unpackMaps()
@@ -171,17 +184,18 @@ func LoadBeatmaps(skipDatabaseCheck bool, importListener ImportListener) []*beat
 
 	allMaps := loadBeatmapsFromDatabase()
 
-	stdMaps := make([]*beatmap.BeatMap, 0, len(allMaps)/2)
+	playableMaps := make([]*beatmap.BeatMap, 0, len(allMaps)/2)
 
 	for _, b := range allMaps {
-		if b.Mode == 0 {
-			stdMaps = append(stdMaps, b)
+		// osu!catch is not supported
+		if b.Mode == 0 || (otherModes && (b.Mode == 1 || b.Mode == 3)) {
+			playableMaps = append(playableMaps, b)
 		}
 	}
 
-	log.Println("DatabaseManager: Loaded", len(stdMaps), "total.")
+	log.Println("DatabaseManager: Loaded", len(playableMaps), "total.")
 
-	return stdMaps
+	return playableMaps
 }
 
 func unpackMaps() (dirs []string) {
@@ -450,7 +464,7 @@ func UpdateStarRating(maps []*beatmap.BeatMap, progressListener func(processed,
 			}()
 
 			beatmap.ParseTimingPointsAndPauses(bMap)
-			beatmap.ParseObjects(bMap, true, false)
+			beatmap.ParseObjects(bMap, true, false, false)
 
 			if len(bMap.HitObjects) < 2 {
 				log.Println("DatabaseManager:", bMap.Dir+"/"+bMap.File, "doesn't have enough hitobjects")
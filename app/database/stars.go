@@ -0,0 +1,344 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/rulesets/osu/performance"
+	"github.com/wieku/danser-go/app/rulesets/osu/performance/api"
+	"github.com/wieku/danser-go/framework/goroutines"
+	"github.com/wieku/danser-go/framework/util"
+	"log"
+	"math"
+	"strings"
+	"sync"
+)
+
+const (
+	starsBatchSize = 200
+	starsWorkers   = 2 // Kept low for the same reason as in UpdateStarRating, many complex maps calculated at once can OOM
+)
+
+// StarKey identifies a set of cached star ratings. Other mod settings (e.g. DA's overrides) are not taken into account.
+type StarKey struct {
+	Mods  difficulty.Modifier
+	Speed float64
+
+	// FinalRate is the rate Wind Up and Wind Down end at, Speed is their initial rate. For other mods it's the same as Speed.
+	FinalRate float64
+
+	PPVersion string
+}
+
+// NewStarKey creates a key for mods and speed of given difficulty, "latest" or unknown pp versions resolve to the newest one
+func NewStarKey(diff *difficulty.Difficulty, ppVersion string) StarKey {
+	key := StarKey{
+		Mods:      difficulty.GetDiffMaskedMods(diff.Mods),
+		Speed:     math.Round(diff.GetSpeed()*1000) / 1000,
+		PPVersion: performance.GetVersion(ppVersion).ID,
+	}
+
+	key.FinalRate = key.Speed
+
+	if conf, ok := difficulty.GetModConfig[difficulty.TimeRampSettings](diff); ok && key.Mods.Active(difficulty.WindUp|difficulty.WindDown) {
+		key.FinalRate = math.Round(conf.FinalRate*1000) / 1000
+	}
+
+	return key
+}
+
+func (key StarKey) String() string {
+	mods := key.Mods.String()
+	if mods == "" {
+		mods = "NM"
+	}
+
+	if key.FinalRate != key.Speed {
+		return fmt.Sprintf("%s %.2f-%.2fx (%s)", mods, key.Speed, key.FinalRate, key.PPVersion)
+	}
+
+	return fmt.Sprintf("%s %.2fx (%s)", mods, key.Speed, key.PPVersion)
+}
+
+// diff creates a difficulty with key's mods applied on top of beatmap's base values
+func (key StarKey) diff(bMap *beatmap.BeatMap) *difficulty.Difficulty {
+	diff := bMap.Diff.Clone()
+
+	mods := key.Mods.ConvertToModInfoList()
+
+	for i := range mods {
+		switch m := difficulty.ParseFromAcronym(mods[i].Acronym); {
+		case m.Active(difficulty.DoubleTime | difficulty.HalfTime):
+			mods[i].Settings = map[string]any{"speed_change": key.Speed}
+		case m.Active(difficulty.WindUp | difficulty.WindDown):
+			mods[i].Settings = map[string]any{"initial_rate": key.Speed, "final_rate": key.FinalRate}
+		case m.Active(difficulty.AdaptiveSpeed):
+			mods[i].Settings = map[string]any{"initial_rate": key.Speed}
+		}
+	}
+
+	diff.SetMods2(mods)
+
+	return diff
+}
+
+type starSet struct {
+	calcVersion int
+	attributes  map[string]api.Attributes
+}
+
+var starMutex sync.Mutex
+var starSets = make(map[StarKey]*starSet)
+
+// starJob is the key being calculated in background, only one key is calculated at once
+var starJob *StarKey
+var starProgress, starTarget int
+var starGeneration int
+
+// GetStarAttributes returns cached attributes of a beatmap. Star ratings of the key are loaded from the database on first use.
+func GetStarAttributes(bMap *beatmap.BeatMap, key StarKey) (api.Attributes, bool) {
+	starMutex.Lock()
+	defer starMutex.Unlock()
+
+	attr, ok := getStarSet(key).attributes[strings.ToLower(bMap.MD5)]
+
+	return attr, ok
+}
+
+// GetStars returns star rating of a beatmap, nomod star ratings stored with beatmaps are used if they were calculated by the same calculator
+func GetStars(bMap *beatmap.BeatMap, key StarKey) (float64, bool) {
+	if hasBaseStars(bMap, key) {
+		return bMap.Stars, true
+	}
+
+	attr, ok := GetStarAttributes(bMap, key)
+
+	return attr.Total, ok
+}
+
+func hasBaseStars(bMap *beatmap.BeatMap, key StarKey) bool {
+	return key.Mods == difficulty.None && key.Speed == 1 && bMap.Stars >= 0 && bMap.StarsVersion == performance.GetDifficultyCalculatorFor(key.PPVersion).GetVersion()
+}
+
+// GetStarProgress returns the progress of background star rating calculation, key is nil if nothing is calculated.
+// generation changes each time new star ratings become available.
+func GetStarProgress() (key *StarKey, processed, target, generation int) {
+	starMutex.Lock()
+	defer starMutex.Unlock()
+
+	return starJob, starProgress, starTarget, starGeneration
+}
+
+// RequestStarRatings calculates missing star ratings of osu!standard maps in background.
+// Calculation of a previously requested key stops after its current batch.
+func RequestStarRatings(maps []*beatmap.BeatMap, key StarKey) {
+	starMutex.Lock()
+	defer starMutex.Unlock()
+
+	if starJob != nil && *starJob == key {
+		return
+	}
+
+	set := getStarSet(key)
+
+	var toCalculate []*beatmap.BeatMap
+
+	for _, b := range maps {
+		if _, ok := set.attributes[strings.ToLower(b.MD5)]; b.Mode == 0 && !ok && !hasBaseStars(b, key) {
+			toCalculate = append(toCalculate, b)
+		}
+	}
+
+	if len(toCalculate) == 0 {
+		starJob = nil
+		return
+	}
+
+	starJob = &key
+	starProgress, starTarget = 0, len(toCalculate)
+
+	log.Println(fmt.Sprintf("DatabaseManager: Calculating %d star ratings for %s...", len(toCalculate), key))
+
+	goroutines.Run(func() {
+		calculateStarRatings(toCalculate, key)
+	})
+}
+
+// getStarSet returns star ratings of a key, loading them from the database if needed. starMutex has to be locked.
+func getStarSet(key StarKey) *starSet {
+	calcVersion := performance.GetDifficultyCalculatorFor(key.PPVersion).GetVersion()
+
+	if set, ok := starSets[key]; ok && set.calcVersion == calcVersion {
+		return set
+	}
+
+	set := &starSet{
+		calcVersion: calcVersion,
+		attributes:  make(map[string]api.Attributes),
+	}
+
+	starSets[key] = set
+
+	if dbFile == nil {
+		return set
+	}
+
+	res, err := dbFile.Query("SELECT md5, total, aim, speedStars, speedNoteCount, aimDifficultStrainCount, speedDifficultStrainCount, flashlight, sliderFactor, objectCount, circles, sliders, spinners, maxCombo FROM starRatings WHERE mods = ? AND speed = ? AND finalRate = ? AND ppVersion = ? AND calcVersion = ?", int64(key.Mods), key.Speed, key.FinalRate, key.PPVersion, calcVersion)
+	if err != nil {
+		log.Println("DatabaseManager: Failed to load star ratings:", err)
+		return set
+	}
+
+	defer res.Close()
+
+	for res.Next() {
+		var md5 string
+		var attr api.Attributes
+
+		err = res.Scan(
+			&md5,
+			&attr.Total,
+			&attr.Aim,
+			&attr.Speed,
+			&attr.SpeedNoteCount,
+			&attr.AimDifficultStrainCount,
+			&attr.SpeedDifficultStrainCount,
+			&attr.Flashlight,
+			&attr.SliderFactor,
+			&attr.ObjectCount,
+			&attr.Circles,
+			&attr.Sliders,
+			&attr.Spinners,
+			&attr.MaxCombo,
+		)
+
+		if err == nil {
+			set.attributes[md5] = attr
+		}
+	}
+
+	return set
+}
+
+type starResult struct {
+	md5  string
+	attr api.Attributes
+}
+
+func calculateStarRatings(maps []*beatmap.BeatMap, key StarKey) {
+	diffCalc := performance.GetDifficultyCalculatorFor(key.PPVersion)
+
+	for i := 0; i < len(maps); i += starsBatchSize {
+		batch := maps[i:min(i+starsBatchSize, len(maps))]
+
+		results := util.Balance(starsWorkers, batch, func(bMap *beatmap.BeatMap) (ret starResult, ok bool) {
+			ret.md5 = strings.ToLower(bMap.MD5)
+			ok = true
+
+			defer func() {
+				if err := recover(); err != nil { // Failed maps are stored with 0 stars, so they are not calculated again
+					ret.attr = api.Attributes{}
+					log.Println("DatabaseManager: Failed to load \"", bMap.Dir+"/"+bMap.File, "\":", err)
+				}
+			}()
+
+			bMapC := bMap.Copy()
+			bMapC.Diff = key.diff(bMap)
+
+			defer bMapC.Clear()
+
+			beatmap.ParseTimingPointsAndPauses(bMapC)
+			beatmap.ParseObjects(bMapC, true, false, false)
+
+			if len(bMapC.HitObjects) >= 2 {
+				ret.attr = diffCalc.CalculateSingle(bMapC.HitObjects, bMapC.Diff)
+			}
+
+			return
+		})
+
+		starMutex.Lock()
+
+		if starJob == nil || *starJob != key { // Another key was requested in the meantime
+			starMutex.Unlock()
+			return
+		}
+
+		set := getStarSet(key)
+
+		for _, r := range results {
+			set.attributes[r.md5] = r.attr
+		}
+
+		pushStarsToDB(key, set.calcVersion, results)
+
+		starProgress += len(batch)
+		starGeneration++
+
+		if starProgress >= starTarget {
+			starJob = nil
+
+			log.Println("DatabaseManager: Star ratings for", key, "calculated!")
+		}
+
+		starMutex.Unlock()
+	}
+}
+
+// pushStarsToDB saves calculated star ratings. starMutex has to be locked.
+func pushStarsToDB(key StarKey, calcVersion int, results []starResult) {
+	if dbFile == nil {
+		return
+	}
+
+	tx, err := dbFile.Begin()
+	if err != nil {
+		log.Println("DatabaseManager: Failed to save star ratings:", err)
+		return
+	}
+
+	var st *sql.Stmt
+
+	st, err = tx.Prepare("REPLACE INTO starRatings VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		_ = tx.Rollback()
+
+		log.Println("DatabaseManager: Failed to save star ratings:", err)
+		return
+	}
+
+	for _, r := range results {
+		_, err1 := st.Exec(
+			r.md5,
+			int64(key.Mods),
+			key.Speed,
+			key.FinalRate,
+			key.PPVersion,
+			calcVersion,
+			r.attr.Total,
+			r.attr.Aim,
+			r.attr.Speed,
+			r.attr.SpeedNoteCount,
+			r.attr.AimDifficultStrainCount,
+			r.attr.SpeedDifficultStrainCount,
+			r.attr.Flashlight,
+			r.attr.SliderFactor,
+			r.attr.ObjectCount,
+			r.attr.Circles,
+			r.attr.Sliders,
+			r.attr.Spinners,
+			r.attr.MaxCombo,
+		)
+
+		if err1 != nil {
+			log.Println(err1)
+		}
+	}
+
+	_ = st.Close()
+
+	if err = tx.Commit(); err != nil {
+		log.Println("DatabaseManager: Failed to save star ratings:", err)
+	}
+}
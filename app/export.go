@@ -0,0 +1,320 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/ffmpeg"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/framework/bass"
+	"github.com/wieku/danser-go/framework/math/mutils"
+	"github.com/wieku/rplpa"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+type exportResult struct {
+	Directory  string  `json:"directory"`
+	File       string  `json:"file"`
+	Audio      string  `json:"audio"`
+	Difficulty string  `json:"difficulty"`
+	Speed      float64 `json:"speed"`
+	AR         float64 `json:"ar"`
+	OD         float64 `json:"od"`
+	CS         float64 `json:"cs"`
+	HP         float64 `json:"hp"`
+}
+
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: danser export [flags] [path/to/map.osu]")
+		fmt.Fprintln(fs.Output(), "Writes a new osu!standard difficulty with given mods baked in: objects are flipped with HardRock, times and audio are scaled by the speed rate and difficulty settings are replaced with effective ones. Result is printed as JSON.")
+		fs.PrintDefaults()
+	}
+
+	md5 := fs.String("md5", "", "Beatmap md5 hash, beatmap is looked up in danser's database")
+	mods := fs.String("mods", "", "Mods to bake in, e.g. -mods=HRDT")
+	mods2 := fs.String("mods2", "", "Mods to bake in, lazer style. Overrides -mods")
+
+	ar := fs.Float64("ar", math.NaN(), "Override map's AR")
+	od := fs.Float64("od", math.NaN(), "Override map's OD")
+	cs := fs.Float64("cs", math.NaN(), "Override map's CS")
+	hp := fs.Float64("hp", math.NaN(), "Override map's HP")
+	speed := fs.Float64("speed", math.NaN(), "Override speed rate, e.g. -speed=1.2. Changes the rate of DT/NC/HT/DC if they are selected, DT is used otherwise")
+
+	format := fs.String("format", "ogg", "Audio format of the new difficulty: ogg or mp3")
+	out := fs.String("out", "", "Output directory, defaults to a new folder next to the original beatmap set")
+
+	settingsVersion := fs.String("settings", "", "Specify settings version, used to locate the Songs directory")
+	noDbCheck := fs.Bool("nodbcheck", false, "Don't validate the database and only import new beatmap sets if there are any")
+
+	_ = fs.Parse(args)
+
+	if (*md5 == "") == (fs.NArg() == 0) || fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	if *format != "ogg" && *format != "mp3" {
+		panic(fmt.Sprintf("Unsupported audio format: %s", *format))
+	}
+
+	settings.LoadSettings(*settingsVersion)
+
+	settings.HEADLESS = true
+
+	modList, err := parseCalcMods(*mods, *mods2)
+	if err != nil {
+		panic(err)
+	}
+
+	var job calcJob
+
+	if *md5 != "" {
+		job = loadCalcMD5s([]string{*md5}, *noDbCheck)[0]
+	} else {
+		job = loadCalcFile(fs.Arg(0))
+	}
+
+	if job.err != nil {
+		panic(job.err)
+	}
+
+	bMap := job.beatMap
+
+	if bMap.Mode != rplpa.OSU {
+		panic(errors.New("only osu!standard maps can be exported"))
+	}
+
+	diff := bMap.Diff.Clone()
+	diff.SetMods2(applyDiffOverrides(modList[0], *ar, *od, *cs, *hp, *speed))
+
+	if diff.HasVariableRate() {
+		panic(errors.New("mods with variable rate can't be baked in"))
+	}
+
+	beatmap.ParseTimingPointsAndPauses(bMap)
+	beatmap.ParseObjects(bMap, true, false, true)
+
+	if len(bMap.HitObjects) == 0 {
+		panic(errors.New("beatmap doesn't have any hit objects"))
+	}
+
+	if diff.ARReal > 10 || diff.ODReal > 10 {
+		log.Printf("Effective AR%.2f OD%.2f exceed osu!'s limits, they will be capped at 10", diff.ARReal, diff.ODReal)
+	}
+
+	outDir := *out
+	if outDir == "" {
+		outDir = filepath.Join(settings.General.GetSongsDir(), bMap.Dir+" (practice)")
+	}
+
+	if err = os.MkdirAll(outDir, 0755); err != nil {
+		panic(err)
+	}
+
+	srcDir := filepath.Join(settings.General.GetSongsDir(), bMap.Dir)
+
+	label := getExportLabel(diff)
+
+	audioName := strings.TrimSuffix(bMap.Audio, filepath.Ext(bMap.Audio)) + " " + label + "." + *format
+
+	if diff.GetSpeed() == 1 {
+		audioName = bMap.Audio
+
+		err = copyExportFile(filepath.Join(srcDir, bMap.Audio), filepath.Join(outDir, audioName))
+	} else {
+		err = exportAudio(filepath.Join(srcDir, bMap.Audio), filepath.Join(outDir, audioName), diff)
+	}
+
+	if err != nil {
+		panic(fmt.Sprintf("Failed to export audio: %s", err))
+	}
+
+	exportBeatmapFiles(bMap, srcDir, outDir)
+
+	bMap.Difficulty += " (" + label + ")"
+	bMap.Audio = audioName
+	bMap.ID = 0 // it's a new difficulty, osu! shouldn't look for it online
+
+	fileName := sanitizeFileName(fmt.Sprintf("%s - %s (%s) [%s].osu", bMap.Artist, bMap.Name, bMap.Creator, bMap.Difficulty))
+
+	if err = beatmap.SaveBeatMap(bMap, diff, filepath.Join(outDir, fileName)); err != nil {
+		panic(fmt.Sprintf("Failed to save the beatmap: %s", err))
+	}
+
+	log.Println("Exported:", filepath.Join(outDir, fileName))
+
+	err = json.NewEncoder(os.Stdout).Encode(exportResult{
+		Directory:  outDir,
+		File:       fileName,
+		Audio:      audioName,
+		Difficulty: bMap.Difficulty,
+		Speed:      diff.GetSpeed(),
+		AR:         min(diff.ARReal, 10),
+		OD:         min(diff.ODReal, 10),
+		CS:         difficulty.DiffFromRate(diff.CircleRadiusU, 54.4, 32, 9.6),
+		HP:         diff.HPMod,
+	})
+
+	if err != nil {
+		panic(err)
+	}
+
+	return 0
+}
+
+// applyDiffOverrides adds DA with given difficulty settings and sets the speed rate like in the main mode
+func applyDiffOverrides(mods []rplpa.ModInfo, ar, od, cs, hp, speed float64) []rplpa.ModInfo {
+	daMap := make(map[string]any)
+
+	if !math.IsNaN(ar) {
+		daMap["approach_rate"] = ar
+	}
+
+	if !math.IsNaN(od) {
+		daMap["overall_difficulty"] = od
+	}
+
+	if !math.IsNaN(cs) {
+		daMap["circle_size"] = cs
+	}
+
+	if !math.IsNaN(hp) {
+		daMap["drain_rate"] = hp
+	}
+
+	if len(daMap) > 0 && !slices.ContainsFunc(mods, func(info rplpa.ModInfo) bool { return info.Acronym == "DA" }) {
+		mods = append(mods, rplpa.ModInfo{
+			Acronym:  "DA",
+			Settings: daMap,
+		})
+	}
+
+	if !math.IsNaN(speed) {
+		i := slices.IndexFunc(mods, func(info rplpa.ModInfo) bool {
+			return info.Acronym == "DT" || info.Acronym == "NC" || info.Acronym == "HT" || info.Acronym == "DC"
+		})
+
+		if i == -1 {
+			acr := "HT"
+			if speed >= 1 {
+				acr = "DT"
+			}
+
+			mods = append(mods, rplpa.ModInfo{Acronym: acr})
+			i = len(mods) - 1
+		}
+
+		if mods[i].Settings == nil {
+			mods[i].Settings = make(map[string]any)
+		}
+
+		mods[i].Settings["speed_change"] = speed
+	}
+
+	return mods
+}
+
+// getExportLabel describes baked mods in difficulty's name, e.g. "HR 1.2x AR9.8 OD9.2"
+func getExportLabel(diff *difficulty.Difficulty) string {
+	var parts []string
+
+	if m := (diff.Mods & (difficulty.HardRock | difficulty.Easy)).String(); m != "" {
+		parts = append(parts, m)
+	}
+
+	if diff.GetSpeed() != 1 {
+		parts = append(parts, mutils.FormatWOZeros(diff.GetSpeed(), 2)+"x")
+	}
+
+	// Same precision as the values written to the file
+	parts = append(parts, "AR"+mutils.FormatWOZeros(min(diff.ARReal, 10), 2), "OD"+mutils.FormatWOZeros(min(diff.ODReal, 10), 2))
+
+	return strings.Join(parts, " ")
+}
+
+func exportAudio(src, dst string, diff *difficulty.Difficulty) error {
+	bass.Init(true)
+
+	track := bass.NewTrack(src)
+	if track == nil {
+		return fmt.Errorf("failed to load %s", src)
+	}
+
+	if diff.AdjustsPitch() {
+		track.SetRelativeFrequency(diff.GetSpeed())
+	} else {
+		track.SetTempo(diff.GetSpeed())
+	}
+
+	log.Println("Encoding audio:", dst)
+
+	return ffmpeg.EncodeAudio(dst, track.GetSampleRate(), track.GetChannels(), track.GetData)
+}
+
+// exportBeatmapFiles copies background and custom hit sounds to the new folder
+func exportBeatmapFiles(bMap *beatmap.BeatMap, srcDir, dstDir string) {
+	entries, err := os.ReadDir(srcDir)
+	if err != nil {
+		log.Println("Failed to read beatmap directory:", err)
+		return
+	}
+
+	for _, entry := range entries {
+		name := strings.ToLower(entry.Name())
+
+		if entry.IsDir() || (!strings.EqualFold(entry.Name(), bMap.Bg) && !strings.HasPrefix(name, "normal-") && !strings.HasPrefix(name, "soft-") && !strings.HasPrefix(name, "drum-")) {
+			continue
+		}
+
+		if err = copyExportFile(filepath.Join(srcDir, entry.Name()), filepath.Join(dstDir, entry.Name())); err != nil {
+			log.Println("Failed to copy", entry.Name(), ":", err)
+		}
+	}
+}
+
+func copyExportFile(src, dst string) error {
+	if filepath.Clean(src) == filepath.Clean(dst) {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+
+	defer in.Close()
+
+	outFile, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(outFile, in); err != nil {
+		outFile.Close()
+		return err
+	}
+
+	return outFile.Close()
+}
+
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune("<>:\"/\\|?*", r) || r < 32 {
+			return '_'
+		}
+
+		return r
+	}, name)
+}
@@ -1,8 +1,8 @@
 package ffmpeg
 
 import (
-	"fmt"
 	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/app/status"
 	"github.com/wieku/danser-go/framework/bass"
 	"github.com/wieku/danser-go/framework/files"
 	"github.com/wieku/danser-go/framework/goroutines"
@@ -18,18 +18,117 @@ import (
 
 const MaxAudioBuffers = 2000
 
-var cmdAudio *exec.Cmd
+const (
+	stemsOff    = "off"
+	stemsTracks = "tracks"
+	stemsWAV    = "wav"
+)
+
+// audioCmds and audioPipes hold the final mix at index 0 followed by stems
+var audioCmds []*exec.Cmd
 
-var audioPipe io.WriteCloser
+var audioPipes []io.WriteCloser
 
-var audioPool chan []byte
+var audioPool chan [][]byte
 
-var audioWriteQueue chan []byte
+var audioWriteQueue chan [][]byte
 var endSyncAudio *sync.WaitGroup
 
+var discardBuffer []byte
+
+func getStemsMode() string {
+	switch strings.ToLower(strings.TrimSpace(settings.Recording.AudioStems)) {
+	case stemsTracks:
+		if getOutputMode() == modeVideo && settings.Recording.Container == "mkv" {
+			return stemsTracks
+		}
+
+		return stemsWAV
+	case stemsWAV:
+		return stemsWAV
+	}
+
+	return stemsOff
+}
+
+func getStemPath(stem bass.Stem) string {
+	if getStemsMode() == stemsTracks {
+		return filepath.Join(settings.Recording.GetOutputDir(), output+"_temp", "audio_"+stem.String()+"."+settings.Recording.Container)
+	}
+
+	return filepath.Join(settings.Recording.GetOutputDir(), output+"_"+stem.String()+".wav")
+}
+
+func getStemCodec() string {
+	if getStemsMode() == stemsTracks {
+		return getAudioCodec()
+	}
+
+	return "pcm_f32le"
+}
+
 func startAudio(audioFPS float64) {
+	audioCmds = nil
+	audioPipes = nil
+
+	if normalizesLoudness() { // Loudness of the whole mix has to be known before it's encoded
+		startAudioProcess(getUnnormalizedAudioPath(), "pcm_f32le", true)
+	} else {
+		startAudioProcess(getAudioPath(), getAudioCodec(), true)
+	}
+
+	stems := getStemsMode()
+
+	if stems != stemsOff {
+		if stems != strings.ToLower(strings.TrimSpace(settings.Recording.AudioStems)) {
+			log.Println("Audio stems can be added as tracks only to MKV videos, writing separate WAV files instead")
+		}
+
+		for stem := range bass.StemCount {
+			startAudioProcess(getStemPath(stem), getStemCodec(), false)
+		}
+	}
+
+	audioBufSize := bass.GetMixerRequiredBufferSize(1 / audioFPS)
+
+	audioPool = make(chan [][]byte, MaxAudioBuffers)
+
+	for i := 0; i < MaxAudioBuffers; i++ {
+		data := make([][]byte, len(audioPipes))
+
+		for j := range data {
+			data[j] = make([]byte, audioBufSize)
+		}
+
+		audioPool <- data
+	}
+
+	audioWriteQueue = make(chan [][]byte, MaxAudioBuffers)
+
+	endSyncAudio = &sync.WaitGroup{}
+	endSyncAudio.Add(1)
+
+	goroutines.RunOS(func() {
+		for data := range audioWriteQueue {
+			for i, pipe := range audioPipes {
+				if _, err := pipe.Write(data[i]); err != nil {
+					panic(status.Failf(status.ExitFFmpegError, "ffmpeg's audio process finished abruptly! Please check if you have enough storage or audio parameters are entered correctly. Error: %s", err))
+				}
+			}
+
+			audioPool <- data
+		}
+
+		endSyncAudio.Done()
+	})
+}
+
+// startAudioProcess starts ffmpeg that encodes audio written to the returned pipe. Audio filters are applied only to the final mix.
+func startAudioProcess(path, codec string, mix bool) {
 	inputName := "-"
 
+	var audioPipe io.WriteCloser
+
 	if runtime.GOOS != "windows" {
 		pipe, err := files.NewNamedPipe("")
 		if err != nil {
@@ -54,24 +153,20 @@ func startAudio(audioFPS float64) {
 	}
 
 	audioFilters := strings.TrimSpace(settings.Recording.AudioFilters)
-	if len(audioFilters) > 0 {
+	if mix && len(audioFilters) > 0 {
 		options = append(options, "-af", audioFilters)
 	}
 
-	options = append(options, "-c:a", settings.Recording.AudioCodec, "-strict", "-2")
-
-	encOptions, err := settings.Recording.GetAudioOptions().GenerateFFmpegArgs()
-	if err != nil {
-		panic(fmt.Sprintf("encoder \"%s\": %s", settings.Recording.AudioCodec, err))
-	} else if encOptions != nil {
-		options = append(options, encOptions...)
-	}
+	options = append(options, "-c:a", codec, "-strict", "-2")
 
-	options = append(options, filepath.Join(settings.Recording.GetOutputDir(), output+"_temp", "audio."+settings.Recording.Container))
+	options = append(options, getAudioEncoderOptions(codec)...)
+	options = append(options, path)
 
 	log.Println("Running ffmpeg with options:", options)
 
-	cmdAudio = exec.Command(ffmpegExec, options...)
+	cmdAudio := exec.Command(ffmpegExec, options...)
+
+	var err error
 
 	if runtime.GOOS == "windows" {
 		audioPipe, err = cmdAudio.StdinPipe()
@@ -87,33 +182,25 @@ func startAudio(audioFPS float64) {
 
 	err = cmdAudio.Start()
 	if err != nil {
-		panic(fmt.Sprintf("ffmpeg's audio process failed to start! Please check if audio parameters are entered correctly or audio codec is supported by provided container. Error: %s", err))
+		panic(status.Failf(status.ExitFFmpegError, "ffmpeg's audio process failed to start! Please check if audio parameters are entered correctly or audio codec is supported by provided container. Error: %s", err))
 	}
 
-	audioBufSize := bass.GetMixerRequiredBufferSize(1 / audioFPS)
-
-	audioPool = make(chan []byte, MaxAudioBuffers)
+	audioCmds = append(audioCmds, cmdAudio)
+	audioPipes = append(audioPipes, audioPipe)
+}
 
-	for i := 0; i < MaxAudioBuffers; i++ {
-		audioPool <- make([]byte, audioBufSize)
+// getAudioEncoderOptions returns options of the audio encoder chosen in settings, other codecs use their defaults
+func getAudioEncoderOptions(codec string) []string {
+	if getOutputMode() != modeVideo || codec != settings.Recording.AudioCodec {
+		return nil
 	}
 
-	audioWriteQueue = make(chan []byte, MaxAudioBuffers)
-
-	endSyncAudio = &sync.WaitGroup{}
-	endSyncAudio.Add(1)
-
-	goroutines.RunOS(func() {
-		for data := range audioWriteQueue {
-			if _, err := audioPipe.Write(data); err != nil {
-				panic(fmt.Sprintf("ffmpeg's audio process finished abruptly! Please check if you have enough storage or audio parameters are entered correctly. Error: %s", err))
-			}
-
-			audioPool <- data
-		}
+	encOptions, err := settings.Recording.GetAudioOptions().GenerateFFmpegArgs()
+	if err != nil {
+		panic(status.Failf(status.ExitFFmpegError, "encoder \"%s\": %s", settings.Recording.AudioCodec, err))
+	}
 
-		endSyncAudio.Done()
-	})
+	return encOptions
 }
 
 func stopAudio() {
@@ -123,19 +210,32 @@ func stopAudio() {
 
 	endSyncAudio.Wait()
 
-	_ = audioPipe.Close()
+	for _, pipe := range audioPipes {
+		_ = pipe.Close()
+	}
 
 	log.Println("Audio pipe closed. Waiting for audio ffmpeg process to finish...")
 
-	_ = cmdAudio.Wait()
+	for _, cmd := range audioCmds {
+		_ = cmd.Wait()
+	}
 
 	log.Println("Audio process finished.")
 }
 
 func PushAudio() {
+	if segment >= 0 && !segmentAudio { // Mixer still has to run so finished channels get freed
+		bass.ProcessMixer(discardBuffer)
+		return
+	}
+
 	data := <-audioPool
 
-	bass.ProcessMixer(data)
+	if len(data) > 1 {
+		bass.ProcessStems(data[0], data[1:])
+	} else {
+		bass.ProcessMixer(data[0])
+	}
 
 	audioWriteQueue <- data
 }
@@ -0,0 +1,73 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/wieku/danser-go/framework/files"
+	"log"
+	"os/exec"
+	"strconv"
+)
+
+// EncodeAudio encodes raw 32-bit float samples returned by source until it returns a non-positive value.
+// Codec is picked by ffmpeg based on output's extension.
+func EncodeAudio(outputPath string, sampleRate, channels int, source func(buffer []byte) int) error {
+	ffmpeg, err := files.GetCommandExec("ffmpeg", "ffmpeg")
+	if err != nil {
+		return errors.New("ffmpeg not found! Please make sure it's installed in danser directory or in PATH. Follow download instructions at https://github.com/Wieku/danser-go/wiki/FFmpeg")
+	}
+
+	options := []string{
+		"-y",
+		"-loglevel", "error",
+
+		"-f", "f32le",
+		"-acodec", "pcm_f32le",
+		"-ar", strconv.Itoa(sampleRate),
+		"-ac", strconv.Itoa(channels),
+		"-i", "-",
+
+		"-ar", "44100",
+		"-b:a", "192k",
+		outputPath,
+	}
+
+	log.Println("Running ffmpeg with options:", options)
+
+	cmd := exec.Command(ffmpeg, options...)
+
+	pipe, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	output := &bytes.Buffer{}
+
+	cmd.Stderr = output
+
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+
+	buffer := make([]byte, 64*1024)
+
+	for {
+		n := source(buffer)
+		if n <= 0 {
+			break
+		}
+
+		if _, err = pipe.Write(buffer[:n]); err != nil {
+			break
+		}
+	}
+
+	_ = pipe.Close()
+
+	if wErr := cmd.Wait(); wErr != nil {
+		return fmt.Errorf("ffmpeg failed: %w: %s", wErr, output.String())
+	}
+
+	return err
+}
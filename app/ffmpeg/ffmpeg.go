@@ -3,6 +3,8 @@ package ffmpeg
 import (
 	"fmt"
 	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/app/status"
+	"github.com/wieku/danser-go/framework/bass"
 	"github.com/wieku/danser-go/framework/files"
 	"log"
 	"os"
@@ -16,13 +18,84 @@ var ffmpegExec string
 
 var output string
 
+// videoRunning and audioRunning are set while encoding processes of the current recording are running
+var videoRunning, audioRunning bool
+
+const (
+	modeVideo  = "video"
+	modeFFV1   = "ffv1"
+	modeProRes = "prores"
+	modePNG    = "png"
+	modeVP9    = "vp9"
+)
+
+func getOutputMode() string {
+	mode := strings.ToLower(strings.TrimSpace(settings.Recording.OutputMode))
+
+	switch mode {
+	case "", modeVideo:
+		return modeVideo
+	case modeFFV1, modeProRes, modePNG, modeVP9:
+		return mode
+	}
+
+	panic(status.Failf(status.ExitFFmpegError, "Unknown output mode %q", settings.Recording.OutputMode))
+}
+
+func getVideoCodec() string {
+	switch getOutputMode() {
+	case modeFFV1:
+		return "ffv1"
+	case modeProRes:
+		return "prores_ks"
+	case modePNG:
+		return "png"
+	case modeVP9:
+		return "libvpx-vp9"
+	}
+
+	return settings.Recording.Encoder
+}
+
+func getAudioCodec() string {
+	if getOutputMode() != modeVideo {
+		return "pcm_f32le"
+	}
+
+	return settings.Recording.AudioCodec
+}
+
+// getVideoPath returns where ffmpeg writes the video stream, in video mode it's muxed with audio later
+func getVideoPath() string {
+	switch getOutputMode() {
+	case modeFFV1:
+		return filepath.Join(settings.Recording.GetOutputDir(), output+".mkv")
+	case modeProRes:
+		return filepath.Join(settings.Recording.GetOutputDir(), output+".mov")
+	case modePNG:
+		return filepath.Join(settings.Recording.GetOutputDir(), output, "%06d.png")
+	case modeVP9:
+		return filepath.Join(settings.Recording.GetOutputDir(), output+".webm")
+	}
+
+	return filepath.Join(settings.Recording.GetOutputDir(), output+"_temp", "video."+settings.Recording.Container)
+}
+
+func getAudioPath() string {
+	if getOutputMode() != modeVideo {
+		return filepath.Join(settings.Recording.GetOutputDir(), output+".wav")
+	}
+
+	return filepath.Join(settings.Recording.GetOutputDir(), output+"_temp", "audio."+settings.Recording.Container)
+}
+
 // check used encoders exist
 func preCheck() {
 	var err error
 
 	ffmpegExec, err = files.GetCommandExec("ffmpeg", "ffmpeg")
 	if err != nil {
-		panic("ffmpeg not found! Please make sure it's installed in danser directory or in PATH. Follow download instructions at https://github.com/Wieku/danser-go/wiki/FFmpeg")
+		panic(status.Failf(status.ExitFFmpegError, "ffmpeg not found! Please make sure it's installed in danser directory or in PATH. Follow download instructions at https://github.com/Wieku/danser-go/wiki/FFmpeg"))
 	}
 
 	log.Println("FFmpeg exec location:", ffmpegExec)
@@ -30,10 +103,10 @@ func preCheck() {
 	out, err := exec.Command(ffmpegExec, "-encoders").Output()
 	if err != nil {
 		if strings.Contains(err.Error(), "127") || strings.Contains(strings.ToLower(err.Error()), "0xc0000135") {
-			panic(fmt.Sprintf("ffmpeg was installed incorrectly! Please make sure needed libraries (libs/*.so or bin/*.dll) are installed as well. Follow download instructions at https://github.com/Wieku/danser-go/wiki/FFmpeg. Error: %s", err))
+			panic(status.Failf(status.ExitFFmpegError, "ffmpeg was installed incorrectly! Please make sure needed libraries (libs/*.so or bin/*.dll) are installed as well. Follow download instructions at https://github.com/Wieku/danser-go/wiki/FFmpeg. Error: %s", err))
 		}
 
-		panic(fmt.Sprintf("Failed to get encoder info. Error: %s", err))
+		panic(status.Failf(status.ExitFFmpegError, "Failed to get encoder info. Error: %s", err))
 	}
 
 	encoders := strings.Split(string(out[:]), "\n")
@@ -44,8 +117,8 @@ func preCheck() {
 		}
 	}
 
-	vcodec := settings.Recording.Encoder
-	acodec := settings.Recording.AudioCodec
+	vcodec := getVideoCodec()
+	acodec := getAudioCodec()
 	vfound := false
 	afound := false
 
@@ -65,11 +138,11 @@ func preCheck() {
 	}
 
 	if !vfound {
-		panic(fmt.Sprintf("Video codec %q does not exist", vcodec))
+		panic(status.Failf(status.ExitFFmpegError, "Video codec %q does not exist", vcodec))
 	}
 
 	if !afound {
-		panic(fmt.Sprintf("Audio codec %q does not exist", acodec))
+		panic(status.Failf(status.ExitFFmpegError, "Audio codec %q does not exist", acodec))
 	}
 }
 
@@ -84,35 +157,166 @@ func StartFFmpeg(fps, _w, _h int, audioFPS float64, _output string) {
 
 	log.Println("Starting encoding!")
 
-	_ = os.RemoveAll(filepath.Join(settings.Recording.GetOutputDir(), output+"_temp"))
+	partFrames = 0
+
+	if segment < 0 { // Directories of segmented recordings are prepared by the parent process
+		prepareOutput()
 
-	err := os.MkdirAll(filepath.Join(settings.Recording.GetOutputDir(), output+"_temp"), 0755)
-	if err != nil && !os.IsExist(err) {
-		panic(err)
+		if usesParts() {
+			loadManifest()
+		}
 	}
 
 	startVideo(fps, _w, _h)
-	startAudio(audioFPS)
+
+	videoRunning = true
+
+	if segment < 0 || segmentAudio {
+		startAudio(audioFPS)
+
+		audioRunning = true
+	} else {
+		discardBuffer = make([]byte, bass.GetMixerRequiredBufferSize(1/audioFPS))
+	}
+}
+
+func prepareOutput() {
+	switch getOutputMode() {
+	case modeVideo:
+		if !usesParts() { // Parts of an interrupted recording may be reused
+			_ = os.RemoveAll(filepath.Join(settings.Recording.GetOutputDir(), output+"_temp"))
+		}
+
+		err := os.MkdirAll(filepath.Join(settings.Recording.GetOutputDir(), output+"_temp"), 0755)
+		if err != nil && !os.IsExist(err) {
+			panic(err)
+		}
+	case modePNG:
+		frameDir := filepath.Join(settings.Recording.GetOutputDir(), output)
+
+		// Frames of an older recording would get mixed with new ones
+		if entries, err := os.ReadDir(frameDir); err == nil && len(entries) > 0 {
+			panic(status.Failf(status.ExitFFmpegError, "Frame directory %q already exists and is not empty", frameDir))
+		}
+
+		if err := os.MkdirAll(frameDir, 0755); err != nil {
+			panic(err)
+		}
+	default:
+		if err := os.MkdirAll(settings.Recording.GetOutputDir(), 0755); err != nil {
+			panic(err)
+		}
+	}
 }
 
 func StopFFmpeg() {
 	log.Println("Finishing rendering...")
 
+	videoRunning = false
+
 	stopVideo()
-	stopAudio()
+
+	if audioRunning {
+		audioRunning = false
+
+		stopAudio()
+
+		// Only a finished recording is normalized, stopAudio is also used to abort one
+		if normalizesLoudness() {
+			normalizeLoudness()
+		}
+	}
 
 	log.Println("Ffmpeg finished.")
 
-	combine()
+	if segment >= 0 { // Parent process joins the segments
+		return
+	}
+
+	if partFrames > 0 {
+		concatParts()
+	}
+
+	if getOutputMode() == modeVideo {
+		combine()
+		return
+	}
+
+	if partFrames > 0 {
+		cleanup()
+	}
+
+	finish()
+}
+
+// AbortFFmpeg stops encoding processes of a failed recording without creating the output, so another recording can be started.
+// Intermediate files are removed unless they belong to a segment or can be used to resume the recording. It has to be called on the main thread.
+func AbortFFmpeg() {
+	if !videoRunning && !audioRunning {
+		return
+	}
+
+	log.Println("Aborting encoding...")
+
+	if videoRunning {
+		videoRunning = false
+
+		stopVideo()
+	}
+
+	if audioRunning {
+		audioRunning = false
+
+		stopAudio()
+	}
+
+	// Segments share the temp directory, the parent process removes it. Parts are kept for resuming.
+	if segment < 0 && !usesParts() {
+		cleanup()
+	}
+}
+
+// finish reports where files of output modes other than video are
+func finish() {
+	finalOutputPath := getVideoPath()
+	if getOutputMode() == modePNG {
+		finalOutputPath = filepath.Dir(finalOutputPath)
+	}
+
+	log.Println("Frames are available at:", finalOutputPath)
+	log.Println("Audio is available at:", getAudioPath())
+
+	logStems()
+
+	status.Finished(finalOutputPath)
 }
 
 func combine() {
 	options := []string{
 		"-y",
-		"-i", filepath.Join(settings.Recording.GetOutputDir(), output+"_temp", "video."+settings.Recording.Container),
-		"-i", filepath.Join(settings.Recording.GetOutputDir(), output+"_temp", "audio."+settings.Recording.Container),
+		"-i", getVideoPath(),
+		"-i", getAudioPath(),
+	}
+
+	stemTracks := getStemsMode() == stemsTracks
+
+	if stemTracks {
+		for stem := range bass.StemCount {
+			options = append(options, "-i", getStemPath(stem))
+		}
+	}
+
+	options = append(options,
 		"-c:v", "copy",
 		"-c:a", "copy", "-strict", "-2",
+	)
+
+	if stemTracks { // Final mix stays the first audio track, so players pick it by default
+		options = append(options, "-map", "0:v", "-map", "1:a", "-metadata:s:a:0", "title=mix")
+
+		for stem := range bass.StemCount {
+			options = append(options, "-map", fmt.Sprintf("%d:a", stem+2), fmt.Sprintf("-metadata:s:a:%d", stem+1), "title="+stem.String())
+		}
 	}
 
 	if settings.Recording.Container == "mp4" {
@@ -123,6 +327,8 @@ func combine() {
 
 	options = append(options, finalOutputPath)
 
+	status.SetStage(status.StageMuxing)
+
 	log.Println("Starting composing audio and video into one file...")
 	log.Println("Running ffmpeg with options:", options)
 	cmd2 := exec.Command(ffmpegExec, options...)
@@ -136,14 +342,28 @@ func combine() {
 		log.Println("Failed to start ffmpeg:", err)
 	} else {
 		if err = cmd2.Wait(); err != nil {
-			panic(fmt.Sprintf("ffmpeg finished abruptly! Please check if you have enough storage. Error: %s", err))
+			panic(status.Failf(status.ExitFFmpegError, "ffmpeg finished abruptly! Please check if you have enough storage. Error: %s", err))
 		} else {
 			log.Println("Finished!")
 			log.Println("Video is available at:", finalOutputPath)
 		}
 	}
 
+	logStems()
+
 	cleanup()
+
+	status.Finished(finalOutputPath)
+}
+
+func logStems() {
+	if getStemsMode() != stemsWAV {
+		return
+	}
+
+	for stem := range bass.StemCount {
+		log.Println(fmt.Sprintf("Audio stem \"%s\" is available at: %s", stem, getStemPath(stem)))
+	}
 }
 
 func cleanup() {
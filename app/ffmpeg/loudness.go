@@ -0,0 +1,123 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/app/status"
+	"log"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// loudnessStats are values measured by ffmpeg's loudnorm filter in the first pass
+type loudnessStats struct {
+	InputI       string `json:"input_i"`
+	InputTP      string `json:"input_tp"`
+	InputLRA     string `json:"input_lra"`
+	InputThresh  string `json:"input_thresh"`
+	TargetOffset string `json:"target_offset"`
+}
+
+func normalizesLoudness() bool {
+	return settings.Recording.Loudness.Enabled
+}
+
+// getUnnormalizedAudioPath returns where the mix is saved before its loudness is measured
+func getUnnormalizedAudioPath() string {
+	path := getAudioPath()
+
+	return strings.TrimSuffix(path, filepath.Ext(path)) + "_unnormalized.wav"
+}
+
+// normalizeLoudness measures loudness of the whole mix and encodes it with adjusted volume to getAudioPath
+func normalizeLoudness() {
+	source := getUnnormalizedAudioPath()
+
+	defer os.Remove(source)
+
+	cfg := settings.Recording.Loudness
+
+	target := fmt.Sprintf("I=%.1f:TP=%.1f:LRA=%.1f", cfg.TargetLUFS, cfg.TruePeak, cfg.LoudnessRange)
+
+	log.Println("Measuring audio loudness...")
+
+	stats, err := measureLoudness(source, target)
+	if err != nil {
+		panic(status.Failf(status.ExitFFmpegError, "Failed to measure audio loudness! Error: %s", err))
+	}
+
+	options := []string{
+		"-y",
+		"-i", source,
+	}
+
+	if inputI, err := strconv.ParseFloat(stats.InputI, 64); err != nil || math.IsInf(inputI, 0) {
+		log.Println("Audio is silent, skipping loudness normalization")
+	} else {
+		log.Println(fmt.Sprintf("Measured loudness: %s LUFS, true peak: %s dBTP, range: %s LU", stats.InputI, stats.InputTP, stats.InputLRA))
+
+		filter := fmt.Sprintf("loudnorm=%s:measured_I=%s:measured_TP=%s:measured_LRA=%s:measured_thresh=%s:offset=%s:linear=true", target, stats.InputI, stats.InputTP, stats.InputLRA, stats.InputThresh, stats.TargetOffset)
+
+		options = append(options, "-af", filter)
+	}
+
+	// loudnorm upsamples to 192kHz
+	options = append(options, "-ar", "48000", "-c:a", getAudioCodec(), "-strict", "-2")
+	options = append(options, getAudioEncoderOptions(getAudioCodec())...)
+	options = append(options, getAudioPath())
+
+	log.Println("Normalizing audio loudness...")
+	log.Println("Running ffmpeg with options:", options)
+
+	cmd := exec.Command(ffmpegExec, options...)
+
+	if settings.Recording.ShowFFmpegLogs {
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+	}
+
+	if err = cmd.Run(); err != nil {
+		panic(status.Failf(status.ExitFFmpegError, "Failed to normalize audio loudness! Error: %s", err))
+	}
+}
+
+func measureLoudness(path, target string) (*loudnessStats, error) {
+	options := []string{
+		"-i", path,
+		"-af", "loudnorm=" + target + ":print_format=json",
+		"-f", "null",
+		"-",
+	}
+
+	log.Println("Running ffmpeg with options:", options)
+
+	var stderr bytes.Buffer
+
+	cmd := exec.Command(ffmpegExec, options...)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+
+	// Stats are printed as the last JSON object in ffmpeg's log
+	out := stderr.Bytes()
+
+	start, end := bytes.LastIndexByte(out, '{'), bytes.LastIndexByte(out, '}')
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("loudnorm didn't print measured values")
+	}
+
+	var stats loudnessStats
+	if err := json.Unmarshal(out[start:end+1], &stats); err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
@@ -0,0 +1,150 @@
+package ffmpeg
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/app/status"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+const manifestName = "manifest.json"
+
+// manifest lists parts of the video that are already encoded, it's saved in the temp directory after each part
+type manifest struct {
+	Key        string         `json:"key"`
+	PartFrames int64          `json:"partFrames"`
+	Parts      []manifestPart `json:"parts"`
+}
+
+type manifestPart struct {
+	File   string `json:"file"`
+	Frames int64  `json:"frames"`
+}
+
+var resumeKey string
+
+var currentManifest *manifest
+
+// partFrames is the length of video parts in frames, 0 if the video is encoded as a single file
+var partFrames int64
+var partWritten int64
+
+// SetResumeKey identifies the next recording, parts of an interrupted recording are reused only if they were made with the same key
+func SetResumeKey(key string) {
+	resumeKey = key
+}
+
+// GetResumeFrame returns the first video frame that wasn't encoded by an interrupted recording
+func GetResumeFrame() int64 {
+	if partFrames == 0 {
+		return 0
+	}
+
+	frames := int64(0)
+
+	for _, part := range currentManifest.Parts {
+		frames += part.Frames
+	}
+
+	return frames
+}
+
+func usesParts() bool {
+	return segment < 0 && settings.Recording.PartLength > 0 && getOutputMode() != modePNG
+}
+
+// loadManifest prepares the temp directory for a recording encoded in parts, keeping the parts of an interrupted one
+func loadManifest() {
+	partFrames = int64(settings.Recording.PartLength) * int64(settings.Recording.FPS)
+	partWritten = 0
+
+	currentManifest = &manifest{
+		Key:        resumeKey,
+		PartFrames: partFrames,
+	}
+
+	tempDir := getTempDir()
+
+	if data, err := os.ReadFile(filepath.Join(tempDir, manifestName)); err == nil {
+		var old manifest
+
+		if err = json.Unmarshal(data, &old); err != nil {
+			log.Println("Failed to read manifest of an interrupted recording:", err)
+		} else if old.Key != resumeKey || old.PartFrames != partFrames {
+			log.Println("Found an interrupted recording with different settings, starting from the beginning")
+		} else {
+			for _, part := range old.Parts {
+				if _, err = os.Stat(filepath.Join(tempDir, part.File)); err != nil {
+					break
+				}
+
+				currentManifest.Parts = append(currentManifest.Parts, part)
+			}
+		}
+	}
+
+	if len(currentManifest.Parts) > 0 {
+		log.Println(fmt.Sprintf("Reusing %d parts of an interrupted recording", len(currentManifest.Parts)))
+		return
+	}
+
+	_ = os.RemoveAll(tempDir)
+
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		panic(err)
+	}
+}
+
+// finishPart waits for ffmpeg to encode the current part and adds it to the manifest
+func finishPart() {
+	_ = videoPipe.Close()
+
+	if err := cmdVideo.Wait(); err != nil {
+		panic(status.Failf(status.ExitFFmpegError, "ffmpeg's video process failed to encode part %d! Error: %s", len(currentManifest.Parts)+1, err))
+	}
+
+	cmdVideo = nil
+
+	currentManifest.Parts = append(currentManifest.Parts, manifestPart{
+		File:   filepath.Base(getPartPath(len(currentManifest.Parts))),
+		Frames: partWritten,
+	})
+
+	partWritten = 0
+
+	data, err := json.MarshalIndent(currentManifest, "", "\t")
+	if err != nil {
+		panic(err)
+	}
+
+	// Replace the old manifest in one step, so it's never left half-written
+	manifestPath := filepath.Join(getTempDir(), manifestName)
+
+	if err = os.WriteFile(manifestPath+".tmp", data, 0644); err == nil {
+		err = os.Rename(manifestPath+".tmp", manifestPath)
+	}
+
+	if err != nil {
+		log.Println("Failed to save recording manifest:", err)
+	}
+
+	log.Println(fmt.Sprintf("Part %d finished.", len(currentManifest.Parts)))
+}
+
+func getPartPath(index int) string {
+	return filepath.Join(getTempDir(), fmt.Sprintf("part_%04d%s", index, filepath.Ext(getVideoPath())))
+}
+
+// concatParts joins parts into a single video
+func concatParts() {
+	paths := make([]string, 0, len(currentManifest.Parts))
+
+	for _, part := range currentManifest.Parts {
+		paths = append(paths, filepath.Join(getTempDir(), part.File))
+	}
+
+	concatFiles(paths)
+}
@@ -0,0 +1,114 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/app/status"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// segment is the index of the part of a recording rendered by this process, -1 if the whole recording is rendered here
+var segment = -1
+var segmentAudio bool
+var segmentFirstFrame int64
+
+// SetSegment makes the next recording encode only a part of the video starting at firstFrame.
+// Audio is encoded only if withAudio is set, exactly one segment should do it.
+func SetSegment(index int, firstFrame int64, withAudio bool) {
+	segment = index
+	segmentFirstFrame = firstFrame
+	segmentAudio = withAudio
+}
+
+// PrepareSegments creates output directories for a recording rendered in parts by separate processes
+func PrepareSegments(_output string) {
+	preCheck()
+
+	output = _output
+
+	prepareOutput()
+
+	_ = os.RemoveAll(getTempDir())
+
+	if err := os.MkdirAll(getTempDir(), 0755); err != nil {
+		panic(err)
+	}
+}
+
+// ConcatSegments joins parts of the video without re-encoding them and finishes the recording like StopFFmpeg would
+func ConcatSegments(count int) {
+	if getOutputMode() == modePNG { // Frames were already written to one directory
+		cleanup()
+		finish()
+
+		return
+	}
+
+	paths := make([]string, 0, count)
+
+	for i := 0; i < count; i++ {
+		paths = append(paths, getSegmentPath(i))
+	}
+
+	concatFiles(paths)
+
+	if getOutputMode() == modeVideo {
+		combine()
+		return
+	}
+
+	cleanup()
+	finish()
+}
+
+// concatFiles joins videos from the temp directory into getVideoPath without re-encoding them
+func concatFiles(paths []string) {
+	listPath := filepath.Join(getTempDir(), "concat.txt")
+
+	var list strings.Builder
+
+	for _, path := range paths {
+		list.WriteString(fmt.Sprintf("file '%s'\n", filepath.Base(path)))
+	}
+
+	if err := os.WriteFile(listPath, []byte(list.String()), 0644); err != nil {
+		panic(err)
+	}
+
+	options := []string{
+		"-y",
+		"-f", "concat",
+		"-safe", "0",
+		"-i", listPath,
+		"-c", "copy",
+		getVideoPath(),
+	}
+
+	status.SetStage(status.StageMuxing)
+
+	log.Println("Joining video parts...")
+	log.Println("Running ffmpeg with options:", options)
+
+	cmd := exec.Command(ffmpegExec, options...)
+
+	if settings.Recording.ShowFFmpegLogs {
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+	}
+
+	if err := cmd.Run(); err != nil {
+		panic(status.Failf(status.ExitFFmpegError, "Failed to join video parts! Error: %s", err))
+	}
+}
+
+func getTempDir() string {
+	return filepath.Join(settings.Recording.GetOutputDir(), output+"_temp")
+}
+
+func getSegmentPath(index int) string {
+	return filepath.Join(getTempDir(), fmt.Sprintf("segment_%03d%s", index, filepath.Ext(getVideoPath())))
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/go-gl/gl/v3.3-core/gl"
 	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/app/status"
 	"github.com/wieku/danser-go/framework/files"
 	"github.com/wieku/danser-go/framework/frame"
 	"github.com/wieku/danser-go/framework/goroutines"
@@ -15,8 +16,8 @@ import (
 	"log"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -29,6 +30,10 @@ var cmdVideo *exec.Cmd
 
 var videoPipe io.WriteCloser
 
+var videoInputOptions []string
+var videoOutputOptions []string
+var videoEncoder string
+
 var videoWriteQueue chan *PBO
 var endSyncVideo *sync.WaitGroup
 
@@ -47,6 +52,9 @@ var limiter *frame.Limiter
 
 var parsedFormat pixconv.PixFmt
 
+// readAlpha is set when frames are read as RGBA instead of RGB
+var readAlpha bool
+
 type PBO struct {
 	handle     uint32
 	memPointer unsafe.Pointer
@@ -66,6 +74,10 @@ func createPBO(format pixconv.PixFmt) *PBO {
 
 	glSize := w * h * 3
 
+	if readAlpha {
+		glSize = w * h * 4
+	}
+
 	if pbo.convFormat == pixconv.I420 || pbo.convFormat == pixconv.NV12 || pbo.convFormat == pixconv.NV21 {
 		glSize = w * h * 3 / 2
 
@@ -94,10 +106,16 @@ var rgbToYuvConverter *effects.RGBYUV
 func startVideo(fps, _w, _h int) {
 	w, h = _w, _h
 
+	frameNumber = -1
+
+	cmdVideo = nil
+
 	if settings.Recording.MotionBlur.Enabled {
 		fps /= settings.Recording.MotionBlur.OversampleMultiplier
 	}
 
+	mode := getOutputMode()
+
 	encoder := strings.ToLower(settings.Recording.Encoder)
 	outputFormat := strings.ToLower(settings.Recording.PixelFormat)
 
@@ -105,6 +123,12 @@ func startVideo(fps, _w, _h int) {
 		outputFormat = "nv12"
 	}
 
+	if mode != modeVideo { // Editing outputs get raw frames, ffmpeg converts them to the target format
+		outputFormat = ""
+	}
+
+	readAlpha = settings.Recording.HasAlpha()
+
 	parsedFormat = pixconv.ARGB
 
 	switch outputFormat {
@@ -121,7 +145,9 @@ func startVideo(fps, _w, _h int) {
 	}
 
 	inputPixFmt := "rgb24"
-	if parsedFormat != pixconv.ARGB {
+	if readAlpha {
+		inputPixFmt = "rgba"
+	} else if parsedFormat != pixconv.ARGB {
 		inputPixFmt = outputFormat
 	}
 
@@ -130,19 +156,11 @@ func startVideo(fps, _w, _h int) {
 		videoFilters = "," + videoFilters
 	}
 
-	inputName := "-"
-
-	if runtime.GOOS != "windows" {
-		pipe, err := files.NewNamedPipe("")
-		if err != nil {
-			panic(err)
-		}
-
-		inputName = pipe.Name()
-		videoPipe = pipe
+	if readAlpha { // danser renders with premultiplied alpha
+		videoFilters = ",unpremultiply=inplace=1" + videoFilters
 	}
 
-	options := []string{
+	videoInputOptions = []string{
 		"-y", //(optional) overwrite output file if it exists
 
 		"-f", "rawvideo",
@@ -150,36 +168,137 @@ func startVideo(fps, _w, _h int) {
 		"-s", fmt.Sprintf("%dx%d", w, h), //size of one frame
 		"-pix_fmt", inputPixFmt,
 		"-r", strconv.Itoa(fps), //frames per second
-		"-i", inputName, //The input comes from a videoPipe
+	}
 
-		"-an",
+	videoOutputOptions = []string{"-an"}
+
+	if mode == modeVideo {
+		videoOutputOptions = append(videoOutputOptions,
+			"-vf", "vflip"+videoFilters,
+			"-c:v", encoder,
+			"-color_range", "1",
+			"-colorspace", "1",
+			"-color_trc", "1",
+			"-color_primaries", "1",
+			"-movflags", "+write_colr",
+		)
+
+		if parsedFormat == pixconv.ARGB {
+			videoOutputOptions = append(videoOutputOptions, "-pix_fmt", outputFormat)
+		}
 
-		"-vf", "vflip" + videoFilters,
-		"-c:v", encoder,
-		"-color_range", "1",
-		"-colorspace", "1",
-		"-color_trc", "1",
-		"-color_primaries", "1",
-		"-movflags", "+write_colr",
+		encOptions, err := settings.Recording.GetEncoderOptions().GenerateFFmpegArgs()
+		if err != nil {
+			panic(status.Failf(status.ExitFFmpegError, "encoder \"%s\": %s", encoder, err))
+		} else if encOptions != nil {
+			videoOutputOptions = append(videoOutputOptions, encOptions...)
+		}
+	} else {
+		videoOutputOptions = append(videoOutputOptions, getEditingOptions(mode, videoFilters)...)
 	}
 
-	if parsedFormat == pixconv.ARGB {
-		options = append(options, "-pix_fmt", outputFormat)
+	videoEncoder = encoder
+
+	videoPath := getVideoPath()
+
+	if segment >= 0 {
+		if mode == modePNG { // All segments write to the same directory
+			videoOutputOptions = append(videoOutputOptions, "-start_number", strconv.FormatInt(segmentFirstFrame+1, 10))
+		} else {
+			videoPath = getSegmentPath(segment)
+		}
 	}
 
-	encOptions, err := settings.Recording.GetEncoderOptions().GenerateFFmpegArgs()
-	if err != nil {
-		panic(fmt.Sprintf("encoder \"%s\": %s", encoder, err))
-	} else if encOptions != nil {
-		options = append(options, encOptions...)
+	if partFrames == 0 { // Parts are started when their first frame arrives
+		startVideoProcess(videoPath)
 	}
 
-	options = append(options, filepath.Join(settings.Recording.GetOutputDir(), output+"_temp", "video."+settings.Recording.Container))
+	freePBOPool = make(chan *PBO, MaxVideoBuffers)
+
+	goroutines.CallMain(func() {
+		rgbToYuvConverter = nil
+		blend = nil
+
+		if parsedFormat != pixconv.ARGB {
+			rgbToYuvConverter = effects.NewRGBYUV(w, h, parsedFormat != pixconv.I444 && parsedFormat != pixconv.I422)
+		}
+
+		for i := 0; i < MaxVideoBuffers; i++ {
+			freePBOPool <- createPBO(parsedFormat)
+		}
+
+		if settings.Recording.MotionBlur.Enabled {
+			bFrames := settings.Recording.MotionBlur.BlendFrames
+			blend = effects.NewBlend(w, h, bFrames, calculateWeights(bFrames))
+		}
+	})
+
+	videoWriteQueue = make(chan *PBO, MaxVideoBuffers)
+
+	limiter = frame.NewLimiter(settings.Recording.EncodingFPSCap)
+
+	endSyncVideo = &sync.WaitGroup{}
+	endSyncVideo.Add(1)
+
+	goroutines.RunOS(func() {
+		for pbo := range videoWriteQueue {
+			pbo.convertSync.Wait() // Wait for conversion to end
+
+			if cmdVideo == nil { // Previous part is finished
+				startVideoProcess(getPartPath(len(currentManifest.Parts)))
+			}
+
+			if _, err := videoPipe.Write(pbo.convData); err != nil {
+				errorMsg := err.Error()
+
+				videoErrorWait.Wait()
+
+				if videoError != "" {
+					errorMsg = videoError
+				}
+
+				panic(status.Failf(status.ExitFFmpegError, "ffmpeg's video process finished abruptly! Please check if you have enough storage or video parameters are entered correctly. Error: %s", errorMsg))
+			}
+
+			freePBOPool <- pbo
+
+			if partFrames > 0 {
+				partWritten++
+
+				if partWritten == partFrames {
+					finishPart()
+				}
+			}
+		}
+
+		endSyncVideo.Done()
+	})
+}
+
+// startVideoProcess starts ffmpeg that encodes frames written to videoPipe into the given file
+func startVideoProcess(path string) {
+	inputName := "-"
+
+	if runtime.GOOS != "windows" {
+		pipe, err := files.NewNamedPipe("")
+		if err != nil {
+			panic(err)
+		}
+
+		inputName = pipe.Name()
+		videoPipe = pipe
+	}
+
+	options := append(slices.Clone(videoInputOptions), "-i", inputName) //The input comes from a videoPipe
+	options = append(options, videoOutputOptions...)
+	options = append(options, path)
 
 	log.Println("Running ffmpeg with options:", options)
 
 	cmdVideo = exec.Command(ffmpegExec, options...)
 
+	var err error
+
 	if runtime.GOOS == "windows" {
 		videoPipe, err = cmdVideo.StdinPipe()
 		if err != nil {
@@ -205,29 +324,10 @@ func startVideo(fps, _w, _h int) {
 
 	err = cmdVideo.Start()
 	if err != nil {
-		panic(fmt.Sprintf("ffmpeg's video process failed to start! Please check if video parameters are entered correctly or video codec is supported by provided container. Error: %s", err))
+		panic(status.Failf(status.ExitFFmpegError, "ffmpeg's video process failed to start! Please check if video parameters are entered correctly or video codec is supported by provided container. Error: %s", err))
 	}
 
-	freePBOPool = make(chan *PBO, MaxVideoBuffers)
-
-	goroutines.CallMain(func() {
-		if parsedFormat != pixconv.ARGB {
-			rgbToYuvConverter = effects.NewRGBYUV(w, h, parsedFormat != pixconv.I444 && parsedFormat != pixconv.I422)
-		}
-
-		for i := 0; i < MaxVideoBuffers; i++ {
-			freePBOPool <- createPBO(parsedFormat)
-		}
-
-		if settings.Recording.MotionBlur.Enabled {
-			bFrames := settings.Recording.MotionBlur.BlendFrames
-			blend = effects.NewBlend(w, h, bFrames, calculateWeights(bFrames))
-		}
-	})
-
-	videoWriteQueue = make(chan *PBO, MaxVideoBuffers)
-
-	limiter = frame.NewLimiter(settings.Recording.EncodingFPSCap)
+	videoError = ""
 
 	videoErrorWait = &sync.WaitGroup{}
 	videoErrorWait.Add(1)
@@ -255,7 +355,7 @@ func startVideo(fps, _w, _h int) {
 					strings.Contains(lineLower, "no capable devices found") ||
 					strings.Contains(lineLower, "does not support") {
 
-					videoError = encoder + ": " + cutLine
+					videoError = videoEncoder + ": " + cutLine
 
 					oFile.Close()
 				}
@@ -264,31 +364,80 @@ func startVideo(fps, _w, _h int) {
 
 		videoErrorWait.Done()
 	})
+}
 
-	endSyncVideo = &sync.WaitGroup{}
-	endSyncVideo.Add(1)
-
-	goroutines.RunOS(func() {
-		for pbo := range videoWriteQueue {
-			pbo.convertSync.Wait() // Wait for conversion to end
+// getEditingOptions returns encoder options for output modes meant for editing
+func getEditingOptions(mode, videoFilters string) []string {
+	switch mode {
+	case modeFFV1:
+		pixFmt := "bgr0"
+		if readAlpha {
+			pixFmt = "bgra"
+		}
 
-			if _, err := videoPipe.Write(pbo.convData); err != nil {
-				errorMsg := err.Error()
+		return []string{
+			"-vf", "vflip" + videoFilters,
+			"-c:v", "ffv1",
+			"-level", "3",
+			"-g", "1",
+			"-slices", "16",
+			"-slicecrc", "1",
+			"-pix_fmt", pixFmt,
+		}
+	case modeProRes:
+		pixFmt := "yuv444p10le"
+		if readAlpha {
+			pixFmt = "yuva444p10le"
+		}
 
-				videoErrorWait.Wait()
+		return []string{
+			"-vf", "vflip" + videoFilters + ",scale=out_color_matrix=bt709:out_range=tv",
+			"-c:v", "prores_ks",
+			"-profile:v", "4444",
+			"-vendor", "apl0",
+			"-pix_fmt", pixFmt,
+			"-color_range", "1",
+			"-colorspace", "1",
+			"-color_trc", "1",
+			"-color_primaries", "1",
+		}
+	case modeVP9:
+		pixFmt := "yuv420p"
+		if readAlpha {
+			pixFmt = "yuva420p"
+		}
 
-				if videoError != "" {
-					errorMsg = videoError
-				}
+		options := []string{
+			"-vf", "vflip" + videoFilters + ",scale=out_color_matrix=bt709:out_range=tv",
+			"-c:v", "libvpx-vp9",
+			"-crf", "15",
+			"-b:v", "0",
+			"-row-mt", "1",
+			"-pix_fmt", pixFmt,
+			"-color_range", "1",
+			"-colorspace", "1",
+			"-color_trc", "1",
+			"-color_primaries", "1",
+		}
 
-				panic(fmt.Sprintf("ffmpeg's video process finished abruptly! Please check if you have enough storage or video parameters are entered correctly. Error: %s", errorMsg))
-			}
+		if readAlpha { // libvpx can't encode alpha with alternate reference frames
+			options = append(options, "-auto-alt-ref", "0")
+		}
 
-			freePBOPool <- pbo
+		return options
+	default:
+		pixFmt := "rgb24"
+		if readAlpha {
+			pixFmt = "rgba"
 		}
 
-		endSyncVideo.Done()
-	})
+		return []string{
+			"-vf", "vflip" + videoFilters,
+			"-c:v", "png",
+			"-pix_fmt", pixFmt,
+			"-f", "image2",
+		}
+	}
 }
 
 func stopVideo() {
@@ -300,6 +449,23 @@ func stopVideo() {
 
 	endSyncVideo.Wait()
 
+	// All buffers are back in the pool, release them so consecutive recordings don't pile them up
+	for len(freePBOPool) > 0 {
+		pbo := <-freePBOPool
+
+		gl.UnmapNamedBuffer(pbo.handle)
+		gl.DeleteBuffers(1, &pbo.handle)
+	}
+
+	if cmdVideo == nil { // Last part was already finished or all of them were encoded by an interrupted recording
+		return
+	}
+
+	if partFrames > 0 {
+		finishPart()
+		return
+	}
+
 	log.Println("Finished! Stopping video pipe...")
 
 	_ = videoPipe.Close()
@@ -321,6 +487,22 @@ func PreFrame() {
 
 var frameNumber = int64(-1)
 
+// SkipFrame advances the frame counter without rendering a frame, segmented recordings use it for frames rendered by other processes
+func SkipFrame() {
+	frameNumber++
+}
+
+// DiscardFrame finishes a frame started with PreFrame without encoding it, segmented recordings use it to warm up before their first frame
+func DiscardFrame() {
+	frameNumber++
+
+	if settings.Recording.MotionBlur.Enabled {
+		blend.End()
+	} else if rgbToYuvConverter != nil {
+		rgbToYuvConverter.End()
+	}
+}
+
 func MakeFrame() {
 	frameNumber++
 
@@ -365,6 +547,8 @@ func MakeFrame() {
 		gl.GetTextureSubImage(yuvFull.GetID(), 0, 0, 0, 0, int32(w), int32(h), 1, gl.RED, gl.UNSIGNED_BYTE, int32(w*h), gl.Ptr(nil))
 		gl.GetTextureSubImage(yuvFull.GetID(), 0, 0, 0, 0, int32(w), int32(h), 1, gl.GREEN, gl.UNSIGNED_BYTE, int32(w*h), gl.PtrOffset(w*h))
 		gl.GetTextureSubImage(yuvFull.GetID(), 0, 0, 0, 0, int32(w), int32(h), 1, gl.BLUE, gl.UNSIGNED_BYTE, int32(w*h), gl.PtrOffset(w*h*2))
+	} else if readAlpha {
+		gl.ReadPixels(0, 0, int32(w), int32(h), uint32(gl.RGBA), gl.UNSIGNED_BYTE, gl.Ptr(nil))
 	} else {
 		gl.ReadPixels(0, 0, int32(w), int32(h), uint32(gl.RGB), gl.UNSIGNED_BYTE, gl.Ptr(nil))
 	}
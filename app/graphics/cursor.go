@@ -105,6 +105,10 @@ type Cursor struct {
 }
 
 func NewCursor() *Cursor {
+	if settings.HEADLESS { // Cursor is used only for input processing, it can't be updated or drawn
+		return &Cursor{Position: vector.NewVec2f(256, -500)}
+	}
+
 	if cursorFbo == nil {
 		initCursor()
 	}
@@ -172,7 +176,10 @@ func (cursor *Cursor) SetPos(pt vector.Vector2f) {
 	}
 
 	cursor.Position = tmp
-	cursor.renderer.SetPosition(cursor.Position)
+
+	if cursor.renderer != nil {
+		cursor.renderer.SetPosition(cursor.Position)
+	}
 }
 
 func (cursor *Cursor) SetScreenPos(pt vector.Vector2f) {
@@ -361,7 +368,7 @@ func (cursor *Cursor) DrawM(scale float64, batch *batch.QuadBatch, color color2.
 		fboBatch.Begin()
 
 		blend.Push()
-		blend.SetFunction(blend.SrcAlpha, blend.One)
+		blend.SetFunctionSeparate(blend.SrcAlpha, blend.One, blend.One, blend.OneMinusSrcAlpha)
 
 		cursorFBOSprite.Draw(0, fboBatch)
 		fboBatch.Flush()
@@ -287,7 +287,7 @@ func (cursor *osuRenderer) DrawM(scale, expand float64, batch *batch.QuadBatch,
 
 		blend.Push()
 		blend.Enable()
-		blend.SetFunction(blend.SrcAlpha, blend.One)
+		blend.SetFunctionSeparate(blend.SrcAlpha, blend.One, blend.One, blend.OneMinusSrcAlpha)
 
 		cursor.vao.DrawInstanced(0, cursor.instances)
 
@@ -99,7 +99,7 @@ func BeginRenderer() {
 
 	blend.Push()
 	blend.Enable()
-	blend.SetFunction(blend.SrcAlpha, blend.OneMinusSrcAlpha)
+	blend.SetFunctionSeparate(blend.SrcAlpha, blend.OneMinusSrcAlpha, blend.One, blend.OneMinusSrcAlpha)
 }
 
 func EndRenderer() {
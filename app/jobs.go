@@ -0,0 +1,347 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/wieku/danser-go/app/audio"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/ffmpeg"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/app/skin"
+	"github.com/wieku/danser-go/app/states"
+	"github.com/wieku/danser-go/app/status"
+	"github.com/wieku/danser-go/framework/env"
+	"github.com/wieku/danser-go/framework/goroutines"
+	"github.com/wieku/rplpa"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// renderJob is a single entry of -jobs file
+type renderJob struct {
+	Replay   string          `json:"replay"`
+	MD5      string          `json:"md5"`
+	ID       int64           `json:"id"`
+	Mods     string          `json:"mods"`
+	Mods2    []rplpa.ModInfo `json:"mods2"`
+	Settings string          `json:"settings"`
+	Skin     string          `json:"skin"`
+	Start    float64         `json:"start"`
+	End      *float64        `json:"end"`
+	Skip     bool            `json:"skip"`
+	Out      string          `json:"out"`
+}
+
+func (job renderJob) String() string {
+	switch {
+	case job.Replay != "":
+		return job.Replay
+	case job.MD5 != "":
+		return "md5 " + job.MD5
+	default:
+		return fmt.Sprintf("id %d", job.ID)
+	}
+}
+
+type jobResult struct {
+	job      renderJob
+	output   string
+	err      any
+	duration time.Duration
+}
+
+var jobList []renderJob
+
+// jobBeatmaps holds beatmaps loaded from the database, jobs look up their maps there
+var jobBeatmaps []*beatmap.BeatMap
+
+var jobSkin string
+
+// Values of the main settings that can't change between jobs
+var jobWidth, jobHeight int
+var jobSongsDir string
+
+// loadJobs reads the job file, settings and skin given by flags are used for jobs that don't specify them
+func loadJobs(path, settingsVersion, skinName string) []renderJob {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to read job file: %s", err))
+	}
+
+	var jobs []renderJob
+
+	if err = json.Unmarshal(data, &jobs); err != nil {
+		panic(fmt.Sprintf("Failed to parse job file: %s", err))
+	}
+
+	if len(jobs) == 0 {
+		panic("Job file doesn't contain any jobs")
+	}
+
+	for i := range jobs {
+		job := &jobs[i]
+
+		if job.Replay == "" && job.MD5 == "" && job.ID <= 0 {
+			panic(fmt.Sprintf("Job %d: replay, md5 or id has to be specified", i+1))
+		}
+
+		if job.Mods != "" && job.Mods2 != nil {
+			panic(fmt.Sprintf("Job %d: you can't specify classic and lazer mods at the same time", i+1))
+		}
+
+		if job.Settings == "" {
+			job.Settings = settingsVersion
+		}
+
+		if job.Settings == "credentials" || job.Settings == "launcher" {
+			panic(fmt.Sprintf("Job %d: settings name \"%s\" is forbidden", i+1, job.Settings))
+		}
+
+		if strings.TrimSpace(job.Skin) == "" {
+			job.Skin = skinName
+		}
+	}
+
+	return jobs
+}
+
+func runJobs(jobs []renderJob) {
+	handleAbort()
+
+	// Window and cursor buffers are already created, so all jobs are recorded in the resolution of the main settings
+	jobWidth, jobHeight = settings.Recording.FrameWidth, settings.Recording.FrameHeight
+
+	// Beatmap paths are relative to the Songs directory used by database import
+	jobSongsDir = settings.General.OsuSongsDir
+
+	jobSkin = settings.Skin.CurrentSkin + "|" + settings.Skin.FallbackSkin
+
+	results := make([]jobResult, 0, len(jobs))
+
+	for i, job := range jobs {
+		log.Println(fmt.Sprintf("Job %d/%d: Starting %s", i+1, len(jobs), job))
+
+		startTime := time.Now()
+
+		jobErr := runJob(job)
+
+		player = nil
+
+		result := jobResult{
+			job:      job,
+			output:   output,
+			err:      jobErr,
+			duration: time.Since(startTime),
+		}
+
+		results = append(results, result)
+
+		if jobErr != nil {
+			log.Println(fmt.Sprintf("Job %d/%d: Failed: %s", i+1, len(jobs), jobErr))
+
+			status.JobFinished(i+1, len(jobs), output, status.GetExitCode(jobErr), fmt.Sprint(jobErr))
+		} else {
+			log.Println(fmt.Sprintf("Job %d/%d: Finished in %s", i+1, len(jobs), result.duration.Round(time.Second)))
+
+			status.JobFinished(i+1, len(jobs), output, status.ExitOK, "")
+		}
+	}
+
+	failed := 0
+
+	log.Println("Job summary:")
+
+	for i, result := range results {
+		if result.err != nil {
+			failed++
+
+			log.Println(fmt.Sprintf("\t%d. FAILED %s: %s", i+1, result.job, result.err))
+		} else {
+			log.Println(fmt.Sprintf("\t%d. OK %s -> %s (%s)", i+1, result.job, result.output, result.duration.Round(time.Second)))
+		}
+	}
+
+	log.Println(fmt.Sprintf("%d/%d jobs finished successfully", len(results)-failed, len(results)))
+
+	if failed > 0 {
+		panic(status.Failf(status.ExitError, "%d of %d jobs failed", failed, len(results)))
+	}
+}
+
+// runJob sets up and records a single job. Panics are recovered and returned, so a failing job doesn't stop the batch.
+func runJob(job renderJob) (jobErr any) {
+	defer func() {
+		if jobErr = recover(); jobErr != nil {
+			abortJob()
+		}
+	}()
+
+	callMain(func() {
+		setupJob(job)
+	})
+
+	mainLoopRecord()
+
+	return nil
+}
+
+// abortJob stops encoding and playback of a failed job, so the next job starts from a clean state
+func abortJob() {
+	goroutines.CallMain(func() {
+		defer func() {
+			if err := recover(); err != nil {
+				log.Println("Failed to clean up after the job:", err)
+			}
+		}()
+
+		ffmpeg.AbortFFmpeg()
+
+		if p, ok := player.(*states.Player); ok {
+			p.Dispose()
+		}
+	})
+}
+
+// callMain runs fn on the main thread. In -jobs mode panics are passed back to the calling goroutine, so they can be recovered by runJob.
+func callMain(fn func()) {
+	if jobList == nil {
+		goroutines.CallMain(fn)
+		return
+	}
+
+	var err any
+
+	goroutines.CallMain(func() {
+		defer func() {
+			err = recover()
+		}()
+
+		fn()
+	})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// setupJob loads job's settings, skin and beatmap and creates the player. It has to be called on the main thread.
+func setupJob(job renderJob) {
+	output = job.Out
+	if strings.TrimSpace(output) == "" {
+		output = "danser_" + time.Now().Format("2006-01-02_15-04-05")
+	}
+
+	settings.REPLAY = ""
+	settings.KNOCKOUT = false
+	settings.KNOCKOUTREPLAYS = nil
+	settings.PLAY = false
+	settings.SPEED = 1
+	settings.PITCH = 1
+	settings.SKIP = job.Skip
+	settings.START = job.Start
+	settings.END = math.Inf(1)
+
+	if job.End != nil {
+		settings.END = *job.End
+	}
+
+	settingsName := job.Settings
+	if settingsName == "" {
+		settingsName = "default"
+	}
+
+	// LoadSettings would create missing settings with default values
+	if _, err := os.Stat(filepath.Join(env.ConfigDir(), settingsName+".json")); err != nil {
+		panic(fmt.Sprintf("Settings \"%s\" not found", job.Settings))
+	}
+
+	settings.LoadSettings(job.Settings)
+
+	if strings.TrimSpace(job.Skin) != "" {
+		settings.Skin.CurrentSkin = job.Skin
+	}
+
+	if settings.Recording.FrameWidth != jobWidth || settings.Recording.FrameHeight != jobHeight {
+		log.Println(fmt.Sprintf("Recording resolution can't change between jobs, using %dx%d", jobWidth, jobHeight))
+
+		settings.Recording.FrameWidth = jobWidth
+		settings.Recording.FrameHeight = jobHeight
+	}
+
+	settings.General.OsuSongsDir = jobSongsDir
+
+	applyRecordSettings()
+
+	// Player of the previous job is already disposed, so textures of its skin can be freed
+	if skinKey := settings.Skin.CurrentSkin + "|" + settings.Skin.FallbackSkin; skinKey != jobSkin {
+		skin.Unload()
+		audio.LoadSamples()
+
+		jobSkin = skinKey
+	}
+
+	md5, id := job.MD5, job.ID
+
+	mods := difficulty.ParseMods(job.Mods)
+	modsNew := job.Mods2
+
+	if job.Replay != "" {
+		rp := loadReplay(job.Replay)
+
+		md5, id = rp.BeatmapMD5, -1
+		mods, modsNew = getReplayMods(rp)
+
+		settings.KNOCKOUT = true
+		settings.REPLAY = job.Replay
+	} else if modsNew != nil {
+		tempDiff := difficulty.NewDifficulty(1, 1, 1, 1)
+		tempDiff.SetMods2(modsNew)
+		mods = tempDiff.Mods
+	}
+
+	if !mods.Compatible() {
+		panic("Incompatible mods selected!")
+	}
+
+	var beatMap *beatmap.BeatMap
+
+	for _, b := range jobBeatmaps {
+		if (md5 != "" && strings.EqualFold(b.MD5, md5)) || (md5 == "" && b.ID == id) {
+			beatMap = b.Copy()
+			break
+		}
+	}
+
+	if beatMap == nil {
+		panic(status.Failf(status.ExitBeatmapNotFound, "Beatmap not found"))
+	}
+
+	checkPlayMode(beatMap)
+
+	// Same as in the main path, autoplay switches to knockout only after the play mode is checked
+	if !settings.KNOCKOUT && mods.Active(difficulty.Autoplay) {
+		settings.KNOCKOUT = true
+		settings.Knockout.MaxPlayers = 0
+	}
+
+	status.SetStage(status.StageBeatmap)
+
+	if modsNew != nil {
+		beatMap.Diff.SetMods2(modsNew)
+	} else {
+		beatMap.Diff.SetMods(mods)
+	}
+
+	beatmap.ParseTimingPointsAndPauses(beatMap)
+	beatmap.ParseObjects(beatMap, false, true, false)
+	beatMap.LoadCustomSamples()
+
+	setupJudgementLog()
+
+	player = states.NewPlayer(beatMap)
+}
@@ -0,0 +1,196 @@
+package liveserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/framework/goroutines"
+	"github.com/wieku/danser-go/framework/websocket"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const clientQueueSize = 256
+
+type client struct {
+	conn  *websocket.Conn
+	queue chan []byte
+}
+
+var server *http.Server
+var running bool
+
+var mutex sync.Mutex
+var clients = make(map[*client]struct{})
+var state = State{Status: "idle", Players: []PlayerState{}}
+
+// Start runs the server on localhost if it's enabled in settings
+func Start() {
+	if !settings.General.LiveServerOn || running {
+		return
+	}
+
+	address := fmt.Sprintf("127.0.0.1:%d", settings.General.LiveServerPort)
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		log.Println("Failed to start live state server:", err)
+		return
+	}
+
+	server = &http.Server{Handler: newHandler()}
+	running = true
+
+	log.Printf("Live state server is running at http://%s/state and ws://%s/ws", address, address)
+
+	goroutines.Run(func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Println("Live state server error:", err)
+		}
+	})
+}
+
+func Stop() {
+	if !running {
+		return
+	}
+
+	running = false
+
+	_ = server.Close()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	for c := range clients {
+		close(c.queue)
+		delete(clients, c)
+	}
+}
+
+func newHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/state", handleState)
+	mux.HandleFunc("/ws", handleWebSocket)
+
+	return mux
+}
+
+// isLocalOrigin allows clients that don't send Origin header and pages served from this machine, so websites opened in a browser can't read the state
+func isLocalOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+
+	if strings.EqualFold(u.Hostname(), "localhost") {
+		return true
+	}
+
+	ip := net.ParseIP(u.Hostname())
+
+	return ip != nil && ip.IsLoopback()
+}
+
+func handleState(w http.ResponseWriter, r *http.Request) {
+	if !isLocalOrigin(r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	mutex.Lock()
+	data, err := json.Marshal(state)
+	mutex.Unlock()
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if origin := r.Header.Get("Origin"); origin != "" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Vary", "Origin")
+	}
+
+	_, _ = w.Write(data)
+}
+
+func handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if !isLocalOrigin(r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		return
+	}
+
+	c := &client{
+		conn:  conn,
+		queue: make(chan []byte, clientQueueSize),
+	}
+
+	mutex.Lock()
+
+	if data, err := json.Marshal(message{Type: "state", Data: state}); err == nil {
+		c.queue <- data
+	}
+
+	clients[c] = struct{}{}
+
+	mutex.Unlock()
+
+	goroutines.Run(func() {
+		for data := range c.queue {
+			if err := c.conn.WriteText(data); err != nil {
+				break
+			}
+		}
+
+		c.conn.Close()
+	})
+
+	_ = conn.ReadLoop()
+
+	mutex.Lock()
+
+	if _, ok := clients[c]; ok {
+		close(c.queue)
+		delete(clients, c)
+	}
+
+	mutex.Unlock()
+}
+
+// broadcastLocked queues a message for every client, messages are dropped for clients that can't keep up
+func broadcastLocked(msg message) {
+	if len(clients) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Println("Failed to encode live state:", err)
+		return
+	}
+
+	for c := range clients {
+		select {
+		case c.queue <- data:
+		default:
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package liveserver
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/app/rulesets/osu"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testKey = "dGhlIHNhbXBsZSBub25jZQ=="
+
+// testClient is a minimal websocket client reading unmasked server frames
+type testClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// dial performs the opening handshake, response is returned as well if the server didn't switch protocols
+func dial(t *testing.T, server *httptest.Server, origin string) (*testClient, *http.Response) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	request := "GET /ws HTTP/1.1\r\nHost: " + conn.RemoteAddr().String() + "\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: " + testKey + "\r\nSec-WebSocket-Version: 13\r\n"
+	if origin != "" {
+		request += "Origin: " + origin + "\r\n"
+	}
+
+	if _, err = conn.Write([]byte(request + "\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := bufio.NewReader(conn)
+
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		return nil, response
+	}
+
+	hash := sha1.Sum([]byte(testKey + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+
+	if accept := response.Header.Get("Sec-WebSocket-Accept"); accept != base64.StdEncoding.EncodeToString(hash[:]) {
+		t.Fatalf("Wrong Sec-WebSocket-Accept: %s", accept)
+	}
+
+	return &testClient{conn: conn, reader: reader}, response
+}
+
+func (c *testClient) readFrame(t *testing.T) (byte, []byte) {
+	t.Helper()
+
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(c.reader, header); err != nil {
+		t.Fatal(err)
+	}
+
+	if header[0]&0x80 == 0 || header[1]&0x80 > 0 {
+		t.Fatalf("Expected final unmasked frame, got header %x", header)
+	}
+
+	length := uint64(header[1] & 0x7F)
+
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(c.reader, ext); err != nil {
+			t.Fatal(err)
+		}
+
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(c.reader, ext); err != nil {
+			t.Fatal(err)
+		}
+
+		length = binary.BigEndian.Uint64(ext)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		t.Fatal(err)
+	}
+
+	return header[0] & 0x0F, payload
+}
+
+// readMessage decodes a text frame, data is decoded into given value
+func (c *testClient) readMessage(t *testing.T, data any) string {
+	t.Helper()
+
+	opCode, payload := c.readFrame(t)
+	if opCode != 0x1 {
+		t.Fatalf("Expected text frame, got opcode %d", opCode)
+	}
+
+	var msg struct {
+		Type string          `json:"type"`
+		Data json.RawMessage `json:"data"`
+	}
+
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := json.Unmarshal(msg.Data, data); err != nil {
+		t.Fatal(err)
+	}
+
+	return msg.Type
+}
+
+// writeFrame sends a masked frame with raw first header byte
+func (c *testClient) writeFrame(t *testing.T, first byte, payload []byte) {
+	t.Helper()
+
+	mask := []byte{1, 2, 3, 4}
+
+	frame := append([]byte{first, 0x80 | byte(len(payload))}, mask...)
+
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+
+	if _, err := c.conn.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWebSocket(t *testing.T) {
+	server := httptest.NewServer(newHandler())
+	defer server.Close()
+
+	client, _ := dial(t, server, "http://localhost:8080")
+	if client == nil {
+		t.Fatal("Handshake from local origin failed")
+	}
+
+	var st State
+
+	if msgType := client.readMessage(t, &st); msgType != "state" || st.Status != "idle" {
+		t.Fatalf("Expected idle state, got %s %+v", msgType, st)
+	}
+
+	mutex.Lock()
+	broadcastLocked(message{Type: "judgement", Data: osu.NewJudgementEntry(&graphics.Cursor{Name: "player"}, osu.JudgementResult{HitResult: osu.Hit300, MaxResult: osu.Hit300, Time: 1234, Number: 5}, osu.Score{Score: 300, CurrentCombo: 1, Combo: 1, Accuracy: 1})})
+	mutex.Unlock()
+
+	var entry osu.JudgementEntry
+
+	if msgType := client.readMessage(t, &entry); msgType != "judgement" {
+		t.Fatalf("Expected judgement, got %s", msgType)
+	}
+
+	if entry.Player != "player" || entry.Object != 5 || entry.Time != 1234 || entry.Result != "300" || entry.Score != 300 || entry.Accuracy != 100 {
+		t.Errorf("Wrong judgement: %+v", entry)
+	}
+
+	// Ping is answered with the same payload
+	client.writeFrame(t, 0x89, []byte("ping"))
+
+	if opCode, payload := client.readFrame(t); opCode != 0xA || string(payload) != "ping" {
+		t.Errorf("Expected pong, got opcode %d with %q", opCode, payload)
+	}
+}
+
+func TestWebSocketFragmented(t *testing.T) {
+	server := httptest.NewServer(newHandler())
+	defer server.Close()
+
+	for name, first := range map[string]byte{"non-final": 0x01, "continuation": 0x80, "rsv": 0xC1} {
+		t.Run(name, func(t *testing.T) {
+			client, _ := dial(t, server, "")
+			if client == nil {
+				t.Fatal("Handshake without origin failed")
+			}
+
+			var st State
+			client.readMessage(t, &st)
+
+			client.writeFrame(t, first, []byte("{}"))
+
+			opCode, payload := client.readFrame(t)
+			if opCode != 0x8 || len(payload) < 2 {
+				t.Fatalf("Expected close frame with status, got opcode %d", opCode)
+			}
+
+			if _, err := client.reader.ReadByte(); err == nil {
+				t.Error("Expected the connection to be closed")
+			}
+		})
+	}
+}
+
+func TestOrigin(t *testing.T) {
+	server := httptest.NewServer(newHandler())
+	defer server.Close()
+
+	for _, origin := range []string{"http://localhost:3000", "http://127.0.0.1", "http://[::1]:8080"} {
+		if client, _ := dial(t, server, origin); client == nil {
+			t.Errorf("Expected %s to be allowed", origin)
+		}
+	}
+
+	for _, origin := range []string{"https://example.com", "http://localhost.example.com", "null"} {
+		if _, response := dial(t, server, origin); response.StatusCode != http.StatusForbidden {
+			t.Errorf("Expected %s to be rejected, got %s", origin, response.Status)
+		}
+
+		request, _ := http.NewRequest(http.MethodGet, server.URL+"/state", nil)
+		request.Header.Set("Origin", origin)
+
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		response.Body.Close()
+
+		if response.StatusCode != http.StatusForbidden || response.Header.Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("Expected /state to reject %s, got %s", origin, response.Status)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package liveserver
+
+import (
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/app/rulesets/osu"
+	"github.com/wieku/danser-go/app/settings"
+	"math"
+	"time"
+)
+
+const stateInterval = 100 * time.Millisecond
+
+type message struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+type State struct {
+	Status  string        `json:"status"`
+	Mode    string        `json:"mode,omitempty"`
+	Time    float64       `json:"time"`
+	Beatmap *Beatmap      `json:"beatmap,omitempty"`
+	Players []PlayerState `json:"players"`
+}
+
+type Beatmap struct {
+	Artist  string `json:"artist"`
+	Title   string `json:"title"`
+	Version string `json:"version"`
+	Creator string `json:"creator"`
+	MD5     string `json:"md5"`
+	ID      int64  `json:"id"`
+	SetID   int64  `json:"setId"`
+	Mode    int64  `json:"mode"`
+	Length  int    `json:"length"`
+}
+
+type PlayerState struct {
+	Name         string  `json:"name"`
+	Mods         string  `json:"mods,omitempty"`
+	Score        int64   `json:"score"`
+	Accuracy     float64 `json:"accuracy"`
+	Combo        uint    `json:"combo"`
+	MaxCombo     uint    `json:"maxCombo"`
+	Grade        string  `json:"grade"`
+	Count300     uint    `json:"count300"`
+	Count100     uint    `json:"count100"`
+	Count50      uint    `json:"count50"`
+	CountMiss    uint    `json:"countMiss"`
+	SliderBreaks uint    `json:"sliderBreaks"`
+	PP           float64 `json:"pp"`
+	FCPP         float64 `json:"fcPP"`
+	HP           float64 `json:"hp"`
+	Failed       bool    `json:"failed"`
+	Keys         Keys    `json:"keys"`
+}
+
+type Keys struct {
+	K1 bool `json:"k1"`
+	K2 bool `json:"k2"`
+	M1 bool `json:"m1"`
+	M2 bool `json:"m2"`
+}
+
+var lastStateTime time.Time
+
+// attached is the last attached ruleset, rulesets replaced after a rewind stop broadcasting
+var attached *osu.OsuRuleSet
+
+// SetMap publishes metadata of the beatmap that is going to be played
+func SetMap(beatMap *beatmap.BeatMap) {
+	if !running {
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	state = State{
+		Status: "playing",
+		Mode:   getMode(),
+		Beatmap: &Beatmap{
+			Artist:  beatMap.Artist,
+			Title:   beatMap.Name,
+			Version: beatMap.Difficulty,
+			Creator: beatMap.Creator,
+			MD5:     beatMap.MD5,
+			ID:      beatMap.ID,
+			SetID:   beatMap.SetID,
+			Mode:    beatMap.Mode,
+			Length:  beatMap.Length,
+		},
+		Players: []PlayerState{},
+	}
+
+	lastStateTime = time.Time{}
+
+	broadcastLocked(message{Type: "state", Data: state})
+}
+
+// ClearMap marks that nothing is being played anymore
+func ClearMap() {
+	if !running {
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	state = State{Status: "idle", Players: []PlayerState{}}
+	attached = nil
+
+	broadcastLocked(message{Type: "state", Data: state})
+}
+
+// AttachRuleset pushes judgements of the ruleset to connected clients as they happen
+func AttachRuleset(ruleset *osu.OsuRuleSet) {
+	if !running {
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if attached == ruleset {
+		return
+	}
+
+	attached = ruleset
+
+	ruleset.AddListener(func(cursor *graphics.Cursor, judgementResult osu.JudgementResult, score osu.Score) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if attached != ruleset {
+			return
+		}
+
+		broadcastLocked(message{Type: "judgement", Data: osu.NewJudgementEntry(cursor, judgementResult, score)})
+	})
+}
+
+// Update refreshes the state at most every stateInterval. Ruleset can be nil, in that case only names and keys are published.
+func Update(mapTime float64, cursors []*graphics.Cursor, ruleset *osu.OsuRuleSet) {
+	if !running || time.Since(lastStateTime) < stateInterval {
+		return
+	}
+
+	lastStateTime = time.Now()
+
+	players := make([]PlayerState, 0, len(cursors))
+
+	for _, cursor := range cursors {
+		pState := PlayerState{
+			Name: cursor.Name,
+			Keys: Keys{
+				K1: cursor.LeftKey,
+				K2: cursor.RightKey,
+				M1: cursor.LeftMouse,
+				M2: cursor.RightMouse,
+			},
+		}
+
+		if ruleset != nil {
+			score := ruleset.GetScore(cursor)
+
+			pState.Mods = ruleset.GetPlayerDifficulty(cursor).GetModString()
+			pState.Score = score.Score
+			pState.Accuracy = score.Accuracy * 100
+			pState.Combo = score.CurrentCombo
+			pState.MaxCombo = score.Combo
+			pState.Grade = score.Grade.String()
+			pState.Count300 = score.Count300
+			pState.Count100 = score.Count100
+			pState.Count50 = score.Count50
+			pState.CountMiss = score.CountMiss
+			pState.SliderBreaks = score.CountSB
+			pState.PP = finite(score.PP.Total)
+			pState.FCPP = finite(ruleset.GetFCPP(cursor).Total) // NaN before the first judgement
+			pState.HP = ruleset.GetHP(cursor)
+			pState.Failed = ruleset.IsFailed(cursor)
+		}
+
+		players = append(players, pState)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	state.Time = mapTime
+	state.Players = players
+
+	broadcastLocked(message{Type: "state", Data: state})
+}
+
+func finite(v float64) float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0
+	}
+
+	return v
+}
+
+func getMode() string {
+	switch {
+	case settings.PLAY:
+		return "play"
+	case settings.KNOCKOUT && settings.REPLAY != "":
+		return "watch"
+	case settings.KNOCKOUT:
+		return "knockout"
+	default:
+		return "dance"
+	}
+}
@@ -0,0 +1,132 @@
+package mania
+
+import (
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"math"
+)
+
+type HitResult uint16
+
+const (
+	Ignore = HitResult(0)
+	Miss   = HitResult(1 << iota)
+	Hit50
+	Hit100
+	Hit200
+	Hit300
+	Hit320
+	BaseHits  = Hit50 | Hit100 | Hit200 | Hit300 | Hit320
+	BaseHitsM = BaseHits | Miss
+)
+
+// Value returns a hit value used in ScoreV1 and accuracy calculation
+func (r HitResult) Value() int64 {
+	switch r {
+	case Hit320:
+		return 320
+	case Hit300:
+		return 300
+	case Hit200:
+		return 200
+	case Hit100:
+		return 100
+	case Hit50:
+		return 50
+	}
+
+	return 0
+}
+
+func (r HitResult) String() string {
+	switch r {
+	case Miss:
+		return "miss"
+	case Hit50:
+		return "50"
+	case Hit100:
+		return "100"
+	case Hit200:
+		return "200"
+	case Hit300:
+		return "300"
+	case Hit320:
+		return "320"
+	}
+
+	return "ignore"
+}
+
+// HitWindows are osu!stable's osu!mania windows, indexed from Hit320 to Miss
+type HitWindows struct {
+	Hit320 float64
+	Hit300 float64
+	Hit200 float64
+	Hit100 float64
+	Hit50  float64
+	Miss   float64
+}
+
+func NewHitWindows(diff *difficulty.Difficulty) HitWindows {
+	od := diff.GetOD()
+
+	windows := HitWindows{
+		Hit320: 16,
+		Hit300: 64 - 3*od,
+		Hit200: 97 - 3*od,
+		Hit100: 127 - 3*od,
+		Hit50:  151 - 3*od,
+		Miss:   188 - 3*od,
+	}
+
+	// HR and EZ don't change OD in osu!mania but scale hit windows directly
+	scale := 1.0
+
+	if diff.CheckModActive(difficulty.HardRock) {
+		scale = 1 / 1.4
+	} else if diff.CheckModActive(difficulty.Easy) {
+		scale = 1.4
+	}
+
+	windows.Hit320 = math.Floor(windows.Hit320*scale) + 0.5
+	windows.Hit300 = math.Floor(windows.Hit300*scale) + 0.5
+	windows.Hit200 = math.Floor(windows.Hit200*scale) + 0.5
+	windows.Hit100 = math.Floor(windows.Hit100*scale) + 0.5
+	windows.Hit50 = math.Floor(windows.Hit50*scale) + 0.5
+	windows.Miss = math.Floor(windows.Miss*scale) + 0.5
+
+	return windows
+}
+
+// ResultFor returns a judgement for given time offset, Ignore if it's outside any window
+func (windows HitWindows) ResultFor(delta float64) HitResult {
+	delta = math.Abs(delta)
+
+	switch {
+	case delta <= windows.Hit320:
+		return Hit320
+	case delta <= windows.Hit300:
+		return Hit300
+	case delta <= windows.Hit200:
+		return Hit200
+	case delta <= windows.Hit100:
+		return Hit100
+	case delta <= windows.Hit50:
+		return Hit50
+	case delta <= windows.Miss:
+		return Miss
+	}
+
+	return Ignore
+}
+
+// Scale returns hit windows multiplied by given value, used for hold note releases
+func (windows HitWindows) Scale(value float64) HitWindows {
+	return HitWindows{
+		Hit320: windows.Hit320 * value,
+		Hit300: windows.Hit300 * value,
+		Hit200: windows.Hit200 * value,
+		Hit100: windows.Hit100 * value,
+		Hit50:  windows.Hit50 * value,
+		Miss:   windows.Miss * value,
+	}
+}
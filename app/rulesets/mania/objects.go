@@ -0,0 +1,62 @@
+package mania
+
+import (
+	"github.com/wieku/danser-go/app/audio"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/objects"
+	"github.com/wieku/danser-go/framework/math/mutils"
+	"math"
+)
+
+const (
+	MinKeys = 1
+	MaxKeys = 10
+)
+
+type Note struct {
+	Column int
+
+	StartTime float64
+	EndTime   float64
+
+	Hold bool
+
+	HitSound audio.HitSound
+
+	Number int64
+}
+
+// GetKeyCount returns the number of columns, it's stored as CS in osu!mania maps
+func GetKeyCount(bMap *beatmap.BeatMap) int {
+	return mutils.Clamp(int(math.Round(bMap.Diff.GetBaseCS())), MinKeys, MaxKeys)
+}
+
+// ParseNotes creates notes from beatmap's objects. BeatMap needs to have its objects parsed.
+func ParseNotes(bMap *beatmap.BeatMap) []*Note {
+	keys := GetKeyCount(bMap)
+
+	notes := make([]*Note, 0, len(bMap.HitObjects))
+
+	for _, o := range bMap.HitObjects {
+		circle, ok := o.(*objects.Circle)
+		if !ok {
+			continue
+		}
+
+		notes = append(notes, &Note{
+			Column:    GetColumn(float64(circle.StartPosRaw.X), keys),
+			StartTime: circle.GetStartTime(),
+			EndTime:   circle.GetEndTime(),
+			Hold:      circle.GetEndTime() > circle.GetStartTime(),
+			HitSound:  circle.GetHitSound(),
+			Number:    int64(len(notes)),
+		})
+	}
+
+	return notes
+}
+
+// GetColumn translates x position of the hit object to the column index
+func GetColumn(x float64, keys int) int {
+	return mutils.Clamp(int(math.Floor(x*float64(keys)/512)), 0, keys-1)
+}
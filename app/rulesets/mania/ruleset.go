@@ -0,0 +1,371 @@
+package mania
+
+import (
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/graphics"
+	"log"
+)
+
+// Hold note releases are judged with more lenient windows
+const releaseLenience = 1.5
+
+type JudgementResult struct {
+	Note      *Note
+	Number    int64
+	Time      int64
+	HitResult HitResult
+	Column    int
+	Tail      bool
+}
+
+type noteState struct {
+	headResult HitResult
+	tailResult HitResult
+
+	holding bool
+
+	// broken is set when a hold note was released too early or its head was missed, tail can get at most a 50 then
+	broken bool
+}
+
+type columnState struct {
+	pressed bool
+
+	notes []int
+	next  int
+}
+
+type maniaPlayer struct {
+	cursor *graphics.Cursor
+	diff   *difficulty.Difficulty
+
+	windows        HitWindows
+	releaseWindows HitWindows
+
+	score Score
+
+	states  []noteState
+	columns []columnState
+}
+
+type hitListener func(cursor *graphics.Cursor, judgementResult JudgementResult, score Score)
+
+type pressListener func(cursor *graphics.Cursor, time int64, column int, pressed bool, note *Note)
+
+type ManiaRuleSet struct {
+	beatMap *beatmap.BeatMap
+	notes   []*Note
+	keys    int
+
+	players   []*maniaPlayer
+	playerMap map[*graphics.Cursor]*maniaPlayer
+
+	ended bool
+
+	hitListeners   []hitListener
+	pressListeners []pressListener
+}
+
+func NewManiaRuleset(beatMap *beatmap.BeatMap, cursors []*graphics.Cursor, diffs []*difficulty.Difficulty) *ManiaRuleSet {
+	log.Println("Creating osu!mania ruleset...")
+
+	ruleset := &ManiaRuleSet{
+		beatMap:   beatMap,
+		notes:     ParseNotes(beatMap),
+		keys:      GetKeyCount(beatMap),
+		playerMap: make(map[*graphics.Cursor]*maniaPlayer),
+	}
+
+	totalJudgements := len(ruleset.notes)
+
+	for _, n := range ruleset.notes {
+		if n.Hold {
+			totalJudgements++
+		}
+	}
+
+	for i, cursor := range cursors {
+		windows := NewHitWindows(diffs[i])
+
+		player := &maniaPlayer{
+			cursor:         cursor,
+			diff:           diffs[i],
+			windows:        windows,
+			releaseWindows: windows.Scale(releaseLenience),
+			score:          newScore(totalJudgements),
+			states:         make([]noteState, len(ruleset.notes)),
+			columns:        make([]columnState, ruleset.keys),
+		}
+
+		for j, n := range ruleset.notes {
+			player.columns[n.Column].notes = append(player.columns[n.Column].notes, j)
+		}
+
+		ruleset.players = append(ruleset.players, player)
+		ruleset.playerMap[cursor] = player
+	}
+
+	log.Println("Loaded", len(ruleset.notes), "notes in", ruleset.keys, "columns")
+
+	return ruleset
+}
+
+// UpdateKeys sets currently pressed columns, keys is a bitmask with the first column as the lowest bit
+func (set *ManiaRuleSet) UpdateKeys(cursor *graphics.Cursor, time int64, keys uint32) {
+	player := set.playerMap[cursor]
+
+	for c := range player.columns {
+		column := &player.columns[c]
+
+		pressed := keys&(1<<c) > 0
+		if pressed == column.pressed {
+			continue
+		}
+
+		column.pressed = pressed
+
+		for _, l := range set.pressListeners {
+			l(cursor, time, c, pressed, set.getSoundNote(player, c))
+		}
+
+		if pressed {
+			set.press(player, c, time)
+		} else {
+			set.release(player, c, time)
+		}
+	}
+}
+
+// getSoundNote returns the note which hit sound should be played on key press
+func (set *ManiaRuleSet) getSoundNote(player *maniaPlayer, c int) *Note {
+	column := &player.columns[c]
+
+	if len(column.notes) == 0 {
+		return nil
+	}
+
+	for i := column.next; i < len(column.notes); i++ {
+		if player.states[column.notes[i]].headResult == Ignore {
+			return set.notes[column.notes[i]]
+		}
+	}
+
+	return set.notes[column.notes[len(column.notes)-1]]
+}
+
+func (set *ManiaRuleSet) press(player *maniaPlayer, c int, time int64) {
+	column := &player.columns[c]
+	fTime := float64(time)
+
+	for i := column.next; i < len(column.notes); i++ {
+		index := column.notes[i]
+		note := set.notes[index]
+		state := &player.states[index]
+
+		if state.headResult != Ignore {
+			// Pressing the key again during a hold note resumes holding, but the note stays broken
+			if note.Hold && state.tailResult == Ignore {
+				state.holding = true
+				return
+			}
+
+			continue
+		}
+
+		result := player.windows.ResultFor(fTime - note.StartTime)
+		if result == Ignore {
+			return
+		}
+
+		state.headResult = result
+
+		if note.Hold {
+			state.holding = true
+			state.broken = result == Miss
+		}
+
+		set.judge(player, note, time, result, false)
+
+		return
+	}
+}
+
+func (set *ManiaRuleSet) release(player *maniaPlayer, c int, time int64) {
+	column := &player.columns[c]
+	fTime := float64(time)
+
+	for i := column.next; i < len(column.notes); i++ {
+		index := column.notes[i]
+		note := set.notes[index]
+		state := &player.states[index]
+
+		if !note.Hold || !state.holding || state.tailResult != Ignore {
+			continue
+		}
+
+		state.holding = false
+
+		if fTime < note.EndTime-player.releaseWindows.Miss {
+			state.broken = true
+			player.score.CurrentCombo = 0
+
+			return
+		}
+
+		result := player.releaseWindows.ResultFor(fTime - note.EndTime)
+		if result == Ignore {
+			result = Miss
+		}
+
+		if state.broken && result > Hit50 {
+			result = Hit50
+		}
+
+		state.tailResult = result
+
+		set.judge(player, note, time, result, true)
+
+		return
+	}
+}
+
+func (set *ManiaRuleSet) Update(time int64) {
+	fTime := float64(time)
+
+	for _, player := range set.players {
+		for c := range player.columns {
+			column := &player.columns[c]
+
+			for i := column.next; i < len(column.notes); i++ {
+				index := column.notes[i]
+				note := set.notes[index]
+				state := &player.states[index]
+
+				if state.headResult == Ignore {
+					if fTime <= note.StartTime+player.windows.Hit50 {
+						break
+					}
+
+					state.headResult = Miss
+					state.broken = true
+
+					set.judge(player, note, int64(note.StartTime+player.windows.Hit50)+1, Miss, false)
+				}
+
+				if note.Hold && state.tailResult == Ignore {
+					if fTime <= note.EndTime+player.releaseWindows.Hit50 {
+						break
+					}
+
+					// Holding for too long gives a 50
+					result := Miss
+					if state.holding {
+						result = Hit50
+					}
+
+					state.holding = false
+					state.tailResult = result
+
+					set.judge(player, note, int64(note.EndTime+player.releaseWindows.Hit50)+1, result, true)
+				}
+			}
+
+			for column.next < len(column.notes) && set.isJudged(player, column.notes[column.next]) {
+				column.next++
+			}
+		}
+	}
+
+	if !set.ended {
+		ended := true
+
+		for _, player := range set.players {
+			for _, column := range player.columns {
+				if column.next < len(column.notes) {
+					ended = false
+				}
+			}
+		}
+
+		if ended {
+			set.ended = true
+
+			for _, player := range set.players {
+				s := player.score
+				log.Printf("%s: %d, %.2f%%, %dx, %d/%d/%d/%d/%d/%d, %s", player.cursor.Name, s.Score, s.Accuracy*100, s.Combo, s.CountGeki, s.Count300, s.CountKatu, s.Count100, s.Count50, s.CountMiss, s.Grade.String())
+			}
+		}
+	}
+}
+
+func (set *ManiaRuleSet) isJudged(player *maniaPlayer, index int) bool {
+	state := player.states[index]
+
+	if state.headResult == Ignore {
+		return false
+	}
+
+	return !set.notes[index].Hold || state.tailResult != Ignore
+}
+
+func (set *ManiaRuleSet) judge(player *maniaPlayer, note *Note, time int64, result HitResult, tail bool) {
+	player.score.AddResult(result, player.diff)
+
+	for _, l := range set.hitListeners {
+		l(player.cursor, JudgementResult{
+			Note:      note,
+			Number:    note.Number,
+			Time:      time,
+			HitResult: result,
+			Column:    note.Column,
+			Tail:      tail,
+		}, player.score)
+	}
+}
+
+func (set *ManiaRuleSet) AddListener(listener func(cursor *graphics.Cursor, judgementResult JudgementResult, score Score)) {
+	set.hitListeners = append(set.hitListeners, listener)
+}
+
+func (set *ManiaRuleSet) AddPressListener(listener func(cursor *graphics.Cursor, time int64, column int, pressed bool, note *Note)) {
+	set.pressListeners = append(set.pressListeners, listener)
+}
+
+func (set *ManiaRuleSet) GetBeatMap() *beatmap.BeatMap {
+	return set.beatMap
+}
+
+func (set *ManiaRuleSet) GetNotes() []*Note {
+	return set.notes
+}
+
+func (set *ManiaRuleSet) GetKeyCount() int {
+	return set.keys
+}
+
+func (set *ManiaRuleSet) GetScore(cursor *graphics.Cursor) Score {
+	return set.playerMap[cursor].score
+}
+
+func (set *ManiaRuleSet) GetPlayerDifficulty(cursor *graphics.Cursor) *difficulty.Difficulty {
+	return set.playerMap[cursor].diff
+}
+
+func (set *ManiaRuleSet) GetHitWindows(cursor *graphics.Cursor) HitWindows {
+	return set.playerMap[cursor].windows
+}
+
+func (set *ManiaRuleSet) IsPressed(cursor *graphics.Cursor, column int) bool {
+	return set.playerMap[cursor].columns[column].pressed
+}
+
+// GetNoteState returns head and tail judgements of the note and whether it's being held, Ignore means not judged yet
+func (set *ManiaRuleSet) GetNoteState(cursor *graphics.Cursor, index int) (head, tail HitResult, holding bool) {
+	state := set.playerMap[cursor].states[index]
+	return state.headResult, state.tailResult, state.holding
+}
+
+func (set *ManiaRuleSet) IsEnded() bool {
+	return set.ended
+}
@@ -0,0 +1,170 @@
+package mania
+
+import (
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/rulesets/osu"
+	"math"
+)
+
+const maxScore = 1000000.0
+
+type Score struct {
+	Score        int64
+	Accuracy     float64
+	Grade        osu.Grade
+	CurrentCombo uint
+	Combo        uint
+	CountGeki    uint // 320s
+	Count300     uint
+	CountKatu    uint // 200s
+	Count100     uint
+	Count50      uint
+	CountMiss    uint
+
+	// totalJudgements is a number of all judgements in the map, hold notes give two of them
+	totalJudgements int
+	judgements      int
+
+	bonus      float64
+	baseScore  float64
+	bonusScore float64
+
+	v2Value float64
+}
+
+func newScore(totalJudgements int) Score {
+	return Score{
+		Accuracy:        1,
+		Grade:           osu.SS,
+		totalJudgements: max(totalJudgements, 1),
+		bonus:           100,
+	}
+}
+
+// AddResult processes a judgement. ScoreV1 follows osu!stable's formula, ScoreV2 is based only on accuracy.
+func (s *Score) AddResult(result HitResult, diff *difficulty.Difficulty) {
+	switch result {
+	case Hit320:
+		s.CountGeki++
+	case Hit300:
+		s.Count300++
+	case Hit200:
+		s.CountKatu++
+	case Hit100:
+		s.Count100++
+	case Hit50:
+		s.Count50++
+	case Miss:
+		s.CountMiss++
+	default:
+		return
+	}
+
+	s.judgements++
+
+	if result == Miss {
+		s.CurrentCombo = 0
+	} else {
+		s.CurrentCombo++
+		s.Combo = max(s.Combo, s.CurrentCombo)
+	}
+
+	var bonusValue, bonusChange float64
+
+	switch result {
+	case Hit320:
+		bonusValue, bonusChange = 32, 2
+	case Hit300:
+		bonusValue, bonusChange = 32, 1
+	case Hit200:
+		bonusValue, bonusChange = 16, -8
+	case Hit100:
+		bonusValue, bonusChange = 8, -24
+	case Hit50:
+		bonusValue, bonusChange = 4, -44
+	case Miss:
+		bonusChange = math.Inf(-1)
+	}
+
+	s.bonus = max(0, min(100, s.bonus+bonusChange))
+
+	noteScore := maxScore * getScoreMultiplier(diff) * 0.5 / float64(s.totalJudgements)
+
+	s.baseScore += noteScore * float64(result.Value()) / 320
+	s.bonusScore += noteScore * bonusValue * math.Sqrt(s.bonus) / 320
+
+	s.v2Value += float64(getV2Value(result))
+
+	if diff.CheckModActive(difficulty.ScoreV2) {
+		s.Score = int64(math.Round(maxScore * getScoreMultiplier(diff) * s.v2Value / (305 * float64(s.totalJudgements))))
+	} else {
+		s.Score = int64(math.Round(s.baseScore + s.bonusScore))
+	}
+
+	s.updateAccuracy(diff)
+}
+
+func (s *Score) updateAccuracy(diff *difficulty.Difficulty) {
+	if s.judgements == 0 {
+		return
+	}
+
+	total := float64(s.judgements)
+
+	if diff.CheckModActive(difficulty.ScoreV2) {
+		s.Accuracy = s.v2Value / (305 * total)
+	} else {
+		s.Accuracy = float64(300*(s.CountGeki+s.Count300)+200*s.CountKatu+100*s.Count100+50*s.Count50) / (300 * total)
+	}
+
+	switch {
+	case s.Accuracy == 1:
+		s.Grade = osu.SS
+	case s.Accuracy > 0.95:
+		s.Grade = osu.S
+	case s.Accuracy > 0.9:
+		s.Grade = osu.A
+	case s.Accuracy > 0.8:
+		s.Grade = osu.B
+	case s.Accuracy > 0.7:
+		s.Grade = osu.C
+	default:
+		s.Grade = osu.D
+	}
+
+	if diff.CheckModActive(difficulty.Hidden | difficulty.Flashlight | difficulty.FadeIn) {
+		switch s.Grade {
+		case osu.S:
+			s.Grade = osu.SH
+		case osu.SS:
+			s.Grade = osu.SSH
+		}
+	}
+}
+
+func getV2Value(result HitResult) int64 {
+	if result == Hit320 {
+		return 305
+	}
+
+	return result.Value()
+}
+
+// getScoreMultiplier returns osu!mania's mod multiplier, only mods making the game easier reduce the score
+func getScoreMultiplier(diff *difficulty.Difficulty) float64 {
+	multiplier := 1.0
+
+	if diff.CheckModActive(difficulty.Easy) {
+		multiplier *= 0.5
+	}
+
+	if diff.CheckModActive(difficulty.NoFail) {
+		multiplier *= 0.5
+	}
+
+	if diff.GetSpeed() < 1 {
+		multiplier *= 0.5
+	}
+
+	return multiplier
+}
@@ -0,0 +1,141 @@
+package osu
+
+import (
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/beatmap/objects"
+	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/framework/math/mutils"
+	"math"
+	"slices"
+	"sync"
+)
+
+// Values are the same as in lazer
+const (
+	asMinRate       = 0.5
+	asMaxRate       = 2.0
+	asMinRateChange = 0.9
+	asMaxRateChange = 1.11
+	asRateOnMiss    = 0.95
+	asAverageWindow = 6
+	asHalfTime      = 50.0
+)
+
+// AdaptiveSpeed tracks the rate of Adaptive Speed mod, which speeds up or slows down the map depending on how early or late objects are hit
+type AdaptiveSpeed struct {
+	mutex sync.Mutex
+
+	rate       float64
+	targetRate float64
+
+	recentRates []float64
+
+	// precedingEndTimes maps start times of circles and slider heads to end times of the objects before them
+	precedingEndTimes map[float64]float64
+
+	lastTime float64
+}
+
+func NewAdaptiveSpeed(ruleset *OsuRuleSet, settings difficulty.AdaptiveSpeedSettings) *AdaptiveSpeed {
+	adaptive := &AdaptiveSpeed{
+		rate:              settings.InitialRate,
+		targetRate:        settings.InitialRate,
+		recentRates:       make([]float64, asAverageWindow),
+		precedingEndTimes: make(map[float64]float64),
+		lastTime:          math.NaN(),
+	}
+
+	for i := range adaptive.recentRates {
+		adaptive.recentRates[i] = settings.InitialRate
+	}
+
+	var endTimes []float64
+
+	for _, o := range ruleset.GetBeatMap().HitObjects {
+		if o.GetType() != objects.SPINNER {
+			endTimes = append(endTimes, o.GetStartTime())
+		}
+	}
+
+	slices.Sort(endTimes)
+	endTimes = slices.Compact(endTimes)
+
+	for i := 1; i < len(endTimes); i++ {
+		adaptive.precedingEndTimes[endTimes[i]] = endTimes[i-1]
+	}
+
+	ruleset.AddListener(adaptive.onJudgement)
+
+	return adaptive
+}
+
+func (adaptive *AdaptiveSpeed) onJudgement(_ *graphics.Cursor, judgementResult JudgementResult, _ Score) {
+	var startTime float64
+
+	// Only circles and slider heads are judged by their timing
+	switch o := judgementResult.object.(type) {
+	case *Circle:
+		startTime = o.hitCircle.GetStartTime()
+	case *Slider:
+		if judgementResult.MaxResult != Hit300 || judgementResult.fromSliderFinish {
+			return
+		}
+
+		startTime = o.hitSlider.GetStartTime()
+	default:
+		return
+	}
+
+	if judgementResult.HitResult&BaseHitsM == 0 {
+		return
+	}
+
+	adaptive.mutex.Lock()
+	defer adaptive.mutex.Unlock()
+
+	prevEndTime, ok := adaptive.precedingEndTimes[startTime]
+	if !ok {
+		return
+	}
+
+	rateChange := asRateOnMiss
+
+	if judgementResult.HitResult&BaseHits > 0 {
+		rateChange = mutils.Clamp((startTime-prevEndTime)/max(float64(judgementResult.Time)-prevEndTime, 1), asMinRateChange, asMaxRateChange)
+	}
+
+	adaptive.recentRates = append(adaptive.recentRates[1:], mutils.Clamp(rateChange*adaptive.rate, asMinRate, asMaxRate))
+
+	// If the player hits all recent objects too early or too late the rate follows them fully, mixed timing keeps the current rate
+	consistency := 0
+	average := adaptive.recentRates[0]
+
+	for i := 1; i < len(adaptive.recentRates); i++ {
+		consistency += int(mutils.Signum(adaptive.recentRates[i] - adaptive.recentRates[i-1]))
+		average += adaptive.recentRates[i]
+	}
+
+	average /= float64(len(adaptive.recentRates))
+
+	adaptive.targetRate = mutils.Lerp(adaptive.rate, average, math.Abs(float64(consistency))/(asAverageWindow-1))
+}
+
+// Update moves the rate towards the target rate, time is in map time
+func (adaptive *AdaptiveSpeed) Update(time float64) {
+	adaptive.mutex.Lock()
+	defer adaptive.mutex.Unlock()
+
+	if !math.IsNaN(adaptive.lastTime) && time > adaptive.lastTime {
+		adaptive.rate = adaptive.targetRate + (adaptive.rate-adaptive.targetRate)*math.Pow(0.5, (time-adaptive.lastTime)/asHalfTime)
+	}
+
+	adaptive.lastTime = time
+}
+
+// GetRate returns the current rate
+func (adaptive *AdaptiveSpeed) GetRate() float64 {
+	adaptive.mutex.Lock()
+	defer adaptive.mutex.Unlock()
+
+	return adaptive.rate
+}
@@ -1,6 +1,7 @@
 package osu
 
 import (
+	"github.com/wieku/danser-go/app/audio"
 	"github.com/wieku/danser-go/app/beatmap/difficulty"
 	"github.com/wieku/danser-go/framework/math/vector"
 )
@@ -141,6 +142,31 @@ type JudgementResult struct {
 	object HitObject
 
 	fromSliderFinish bool
+	edgeNum          int
+}
+
+// GetHitSound returns the hit sound that's played along with this judgement
+func (result JudgementResult) GetHitSound() (audio.HitSound, bool) {
+	switch o := result.object.(type) {
+	case *Circle:
+		if result.HitResult&BaseHits > 0 {
+			return o.hitCircle.GetHitSound(), true
+		}
+	case *Slider:
+		if result.fromSliderFinish {
+			if result.HitResult&(BaseHits|SliderFinish) > 0 {
+				return o.hitSlider.GetEdgeHitSound(o.hitSlider.RepeatCount), true
+			}
+		} else if result.HitResult&(BaseHits|SliderStart|SliderRepeat) > 0 {
+			return o.hitSlider.GetEdgeHitSound(result.edgeNum), true
+		}
+	case *Spinner:
+		if result.HitResult&BaseHits > 0 {
+			return o.hitSpinner.GetHitSound(), true
+		}
+	}
+
+	return audio.HitSound{}, false
 }
 
 func createJudgementResult(result HitResult, maxResult HitResult, comboResult ComboResult, time int64, position vector.Vector2f, obj HitObject) JudgementResult {
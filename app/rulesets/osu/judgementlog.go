@@ -0,0 +1,212 @@
+package osu
+
+import (
+	"encoding/json"
+	"github.com/wieku/danser-go/app/graphics"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// JudgementEntry is a JSON representation of a single judgement
+type JudgementEntry struct {
+	Player      string  `json:"player"`
+	Object      int64   `json:"object"`
+	ObjectType  string  `json:"objectType,omitempty"`
+	Time        int64   `json:"time"`
+	ObjectTime  int64   `json:"objectTime"` // start time of the object the judgement belongs to
+	Delta       *int64  `json:"delta,omitempty"`
+	Result      string  `json:"result"`
+	Addition    string  `json:"addition,omitempty"`
+	MaxResult   string  `json:"maxResult"`
+	ComboResult string  `json:"comboResult"`
+	Combo       uint    `json:"combo"`
+	MaxCombo    uint    `json:"maxCombo"`
+	Score       int64   `json:"score"`
+	Accuracy    float64 `json:"accuracy"`
+	PP          float64 `json:"pp"`
+	X           float32 `json:"x"`
+	Y           float32 `json:"y"`
+}
+
+// JudgementLogger writes every judgement of attached rulesets as a JSON line
+type JudgementLogger struct {
+	file    *os.File
+	encoder *json.Encoder
+	mutex   sync.Mutex
+
+	// written is the number of judgements in the file, seen is the number of judgements received from the current ruleset.
+	// Replays are deterministic, so a ruleset recreated after a rewind repeats the first written judgements.
+	written int
+	seen    int
+}
+
+func NewJudgementLogger(path string) (*JudgementLogger, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := &JudgementLogger{
+		file:    file,
+		encoder: json.NewEncoder(file),
+	}
+
+	log.Println("Exporting judgements to:", path)
+
+	return logger, nil
+}
+
+// Attach starts logging judgements of the ruleset, judgements that were already written by previously attached rulesets are skipped
+func (logger *JudgementLogger) Attach(ruleset *OsuRuleSet) {
+	logger.mutex.Lock()
+	logger.seen = 0
+	logger.mutex.Unlock()
+
+	ruleset.AddListener(logger.onJudgement)
+}
+
+func (logger *JudgementLogger) onJudgement(cursor *graphics.Cursor, judgementResult JudgementResult, score Score) {
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+
+	logger.seen++
+
+	if logger.file == nil || logger.seen <= logger.written {
+		return
+	}
+
+	if err := logger.encoder.Encode(NewJudgementEntry(cursor, judgementResult, score)); err != nil {
+		log.Println("Failed to write judgement:", err)
+	}
+
+	logger.written++
+}
+
+func NewJudgementEntry(cursor *graphics.Cursor, judgementResult JudgementResult, score Score) JudgementEntry {
+	entry := JudgementEntry{
+		Player:      cursor.Name,
+		Object:      judgementResult.Number,
+		Time:        judgementResult.Time,
+		Result:      hitResultName(judgementResult.HitResult),
+		Addition:    additionName(judgementResult.HitResult),
+		MaxResult:   hitResultName(judgementResult.MaxResult),
+		ComboResult: comboResultName(judgementResult.ComboResult),
+		Combo:       score.CurrentCombo,
+		MaxCombo:    score.Combo,
+		Score:       score.Score,
+		Accuracy:    score.Accuracy * 100,
+		PP:          score.PP.Total,
+		X:           judgementResult.Position.X,
+		Y:           judgementResult.Position.Y,
+	}
+
+	clicked := false
+
+	switch judgementResult.object.(type) {
+	case *Circle:
+		entry.ObjectType = "circle"
+		clicked = judgementResult.HitResult&BaseHits > 0
+	case *Slider:
+		entry.ObjectType = "slider"
+
+		// slider head, in lazer it's judged like a circle
+		clicked = judgementResult.HitResult&(BaseHits|SliderStart) > 0 && judgementResult.MaxResult&(Hit300|SliderStart) > 0 && !judgementResult.fromSliderFinish
+	case *Spinner:
+		entry.ObjectType = "spinner"
+	}
+
+	if judgementResult.object != nil {
+		entry.ObjectTime = int64(judgementResult.object.GetObject().GetStartTime())
+	}
+
+	if clicked {
+		delta := judgementResult.Time - entry.ObjectTime
+		entry.Delta = &delta
+	}
+
+	return entry
+}
+
+func (logger *JudgementLogger) Close() {
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+
+	if logger.file == nil {
+		return
+	}
+
+	if err := logger.file.Close(); err != nil {
+		log.Println("Failed to close judgement log:", err)
+	}
+
+	logger.file = nil
+}
+
+func hitResultName(result HitResult) string {
+	switch result & (^Additions) {
+	case Ignore:
+		return "ignore"
+	case SliderMiss:
+		return "sliderMiss"
+	case Miss:
+		return "miss"
+	case Hit50:
+		return "50"
+	case Hit100:
+		return "100"
+	case Hit300:
+		return "300"
+	case SliderStart:
+		return "sliderStart"
+	case SliderPoint:
+		return "sliderPoint"
+	case SliderRepeat:
+		return "sliderRepeat"
+	case LegacySliderEnd:
+		return "legacySliderEnd"
+	case SliderEnd:
+		return "sliderEnd"
+	case SliderFinish:
+		return "sliderFinish"
+	case SpinnerSpin:
+		return "spinnerSpin"
+	case SpinnerPoints:
+		return "spinnerPoints"
+	case SpinnerBonus:
+		return "spinnerBonus"
+	case PositionalMiss:
+		return "positionalMiss"
+	}
+
+	return "unknown"
+}
+
+func additionName(result HitResult) string {
+	switch {
+	case result&GekiAddition > 0:
+		return "geki"
+	case result&KatuAddition > 0:
+		return "katu"
+	case result&MuAddition > 0:
+		return "mu"
+	}
+
+	return ""
+}
+
+func comboResultName(result ComboResult) string {
+	switch result {
+	case Reset:
+		return "reset"
+	case Hold:
+		return "hold"
+	}
+
+	return "increase"
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+)
+
+// CalculatorVersion is a pp version implemented by one of pp* packages
+type CalculatorVersion struct {
+	// ID is the date of the pp version in YYMMDD format, it's used in settings
+	ID          string
+	Description string
+
+	NewDifficultyCalculator func() IDifficultyCalculator
+	NewPPCalculator         func() IPerformanceCalculator
+}
+
+var versions []*CalculatorVersion
+
+// RegisterVersion adds a pp version to the registry, it should be called from init of the package implementing it
+func RegisterVersion(version CalculatorVersion) {
+	for _, v := range versions {
+		if v.ID == version.ID {
+			panic(fmt.Sprintf("pp version %s is already registered", version.ID))
+		}
+	}
+
+	versions = append(versions, &version)
+
+	// IDs are dates so the newest version is always the last one
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].ID < versions[j].ID
+	})
+}
+
+// GetVersions returns all registered pp versions, from the oldest to the newest
+func GetVersions() []*CalculatorVersion {
+	return versions
+}
@@ -1,48 +1,145 @@
 package performance
 
 import (
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/beatmap/objects"
 	"github.com/wieku/danser-go/app/rulesets/osu/performance/api"
-	"github.com/wieku/danser-go/app/rulesets/osu/performance/pp211112"
-	"github.com/wieku/danser-go/app/rulesets/osu/performance/pp220930"
-	"github.com/wieku/danser-go/app/rulesets/osu/performance/pp241007"
+	_ "github.com/wieku/danser-go/app/rulesets/osu/performance/pp211112"
+	_ "github.com/wieku/danser-go/app/rulesets/osu/performance/pp220930"
+	_ "github.com/wieku/danser-go/app/rulesets/osu/performance/pp241007"
 	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/framework/goroutines"
+	"sync"
 )
 
-var diffCalcInit func() api.IDifficultyCalculator
-var ppCalcInit func() api.IPerformanceCalculator
+// Latest is an alias of the newest pp version
+const Latest = "latest"
 
-func initConstructors() {
-	if diffCalcInit != nil {
-		return
+// Versions lists IDs of all available pp versions, "latest" is an alias of the newest one
+var Versions = initVersions()
+
+func initVersions() (ids []string) {
+	registered := api.GetVersions()
+
+	options := make([]string, 0, len(registered))
+
+	for i, v := range registered {
+		ids = append(ids, v.ID)
+
+		if i == len(registered)-1 {
+			options = append(options, Latest+"|"+v.Description+" (latest)")
+		} else {
+			options = append(options, v.ID+"|"+v.Description)
+		}
 	}
 
-	switch settings.Gameplay.PPVersion {
-	case "211112":
-		diffCalcInit = pp211112.NewDifficultyCalculator
-		ppCalcInit = pp211112.NewPPCalculator
-	case "220930":
-		diffCalcInit = pp220930.NewDifficultyCalculator
-		ppCalcInit = pp220930.NewPPCalculator
-	default:
-		diffCalcInit = pp241007.NewDifficultyCalculator
-		ppCalcInit = pp241007.NewPPCalculator
+	settings.SetPPVersions(options)
+
+	return
+}
+
+// GetVersion returns a registered pp version, "latest" and unknown versions resolve to the newest one
+func GetVersion(id string) *api.CalculatorVersion {
+	registered := api.GetVersions()
+
+	for _, v := range registered {
+		if v.ID == id {
+			return v
+		}
 	}
+
+	return registered[len(registered)-1]
 }
 
-var diffCalc api.IDifficultyCalculator
+// GetConstructors returns difficulty and performance calculator constructors of a given pp version, unknown versions fall back to the latest one
+func GetConstructors(version string) (func() api.IDifficultyCalculator, func() api.IPerformanceCalculator) {
+	v := GetVersion(version)
 
+	return v.NewDifficultyCalculator, v.NewPPCalculator
+}
+
+// GetCompareVersion returns the pp version shown next to the main one, empty if comparison is disabled
+func GetCompareVersion() string {
+	compare := settings.Gameplay.PPCounter.CompareVersion
+	if compare == "" || GetVersion(compare).ID == GetVersion(settings.Gameplay.PPVersion).ID {
+		return ""
+	}
+
+	return GetVersion(compare).ID
+}
+
+var diffCalcs = make(map[string]api.IDifficultyCalculator)
+var diffMutex sync.Mutex
+
+// GetDifficultyCalculator returns the difficulty calculator of pp version selected in settings
 func GetDifficultyCalculator() api.IDifficultyCalculator {
-	initConstructors()
+	return GetDifficultyCalculatorFor(settings.Gameplay.PPVersion)
+}
+
+// GetDifficultyCalculatorFor returns a shared difficulty calculator of a given pp version
+func GetDifficultyCalculatorFor(version string) api.IDifficultyCalculator {
+	v := GetVersion(version)
+
+	diffMutex.Lock()
+	defer diffMutex.Unlock()
 
-	if diffCalc == nil {
-		diffCalc = diffCalcInit()
+	if diffCalcs[v.ID] == nil {
+		diffCalcs[v.ID] = v.NewDifficultyCalculator()
 	}
 
-	return diffCalc
+	return diffCalcs[v.ID]
 }
 
+// CreatePPCalculator creates a performance calculator of pp version selected in settings
 func CreatePPCalculator() api.IPerformanceCalculator {
-	initConstructors()
+	return CreatePPCalculatorFor(settings.Gameplay.PPVersion)
+}
+
+// CreatePPCalculatorFor creates a performance calculator of a given pp version
+func CreatePPCalculatorFor(version string) api.IPerformanceCalculator {
+	return GetVersion(version).NewPPCalculator()
+}
+
+// CalculateStepVersions calculates successive attributes under several pp versions at once, results are in the same order as versions
+func CalculateStepVersions(versions []string, objects []objects.IHitObject, diff *difficulty.Difficulty) [][]api.Attributes {
+	results := make([][]api.Attributes, len(versions))
+
+	wg := &sync.WaitGroup{}
+
+	for i, version := range versions {
+		wg.Add(1)
+
+		goroutines.Run(func() {
+			defer wg.Done()
+
+			results[i] = GetDifficultyCalculatorFor(version).CalculateStep(objects, diff)
+		})
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// CalculateVersions calculates attributes and pp of a score under several pp versions at once, results are in the same order as versions
+func CalculateVersions(versions []string, objects []objects.IHitObject, diff *difficulty.Difficulty, score api.PerfScore) ([]api.Attributes, []api.PPv2Results) {
+	attributes := make([]api.Attributes, len(versions))
+	results := make([]api.PPv2Results, len(versions))
+
+	wg := &sync.WaitGroup{}
+
+	for i, version := range versions {
+		wg.Add(1)
+
+		goroutines.Run(func() {
+			defer wg.Done()
+
+			attributes[i] = GetDifficultyCalculatorFor(version).CalculateSingle(objects, diff)
+			results[i] = CreatePPCalculatorFor(version).Calculate(attributes[i], score, diff)
+		})
+	}
+
+	wg.Wait()
 
-	return ppCalcInit()
+	return attributes, results
 }
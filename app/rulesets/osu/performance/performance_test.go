@@ -0,0 +1,234 @@
+package performance
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/rulesets/osu/performance/api"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/framework/env"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// tolerance allows for rounding differences between platforms, e.g. fused multiply-add on arm64
+const tolerance = 1e-9
+
+// goldenCase is a single expected result. Golden files are generated with tools/ppgolden from osu-tools output stored in testdata/reference
+type goldenCase struct {
+	Map        string          `json:"map"`
+	Mods       string          `json:"mods"`
+	Score      api.PerfScore   `json:"score"`
+	Attributes api.Attributes  `json:"attributes"`
+	PP         api.PPv2Results `json:"pp"`
+}
+
+func TestMain(m *testing.M) {
+	env.Init("danser")
+
+	mapsDir, err := filepath.Abs(filepath.Join("testdata", "maps"))
+	if err != nil {
+		panic(err)
+	}
+
+	// Beatmap paths are relative to the Songs directory
+	settings.General.OsuSongsDir = mapsDir
+
+	os.Exit(m.Run())
+}
+
+func TestGolden(t *testing.T) {
+	for _, version := range Versions {
+		t.Run(version, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := os.ReadFile(filepath.Join("testdata", "golden", version+".json"))
+			if err != nil {
+				t.Fatalf("Missing golden file of pp version %s, generate it from osu-tools output with tools/ppgolden: %s", version, err)
+			}
+
+			var cases []goldenCase
+
+			if err = json.Unmarshal(data, &cases); err != nil {
+				t.Fatal(err)
+			}
+
+			diffCalcInit, ppCalcInit := GetConstructors(version)
+
+			for _, c := range cases {
+				mods := c.Mods
+				if mods == "" {
+					mods = "NM"
+				}
+
+				t.Run(c.Map+"/"+mods, func(t *testing.T) {
+					bMap := loadMap(t, c.Map, c.Mods)
+
+					attributes := diffCalcInit().CalculateSingle(bMap.HitObjects, bMap.Diff)
+					compareFields(t, "attributes", c.Attributes, attributes)
+
+					pp := ppCalcInit().Calculate(attributes, c.Score, bMap.Diff)
+					compareFields(t, "pp", c.PP, pp)
+				})
+			}
+		})
+	}
+}
+
+// TestCalculateStepVersions checks that attributes calculated for several versions at once match the last step of each version
+func TestCalculateStepVersions(t *testing.T) {
+	bMap := loadMap(t, "sliders.osu", "HD")
+
+	steps := CalculateStepVersions(Versions, bMap.HitObjects, bMap.Diff)
+
+	for i, version := range Versions {
+		if len(steps[i]) != len(bMap.HitObjects) {
+			t.Fatalf("%s: expected %d steps, got %d", version, len(bMap.HitObjects), len(steps[i]))
+		}
+
+		compareFields(t, version, GetDifficultyCalculatorFor(version).CalculateSingle(bMap.HitObjects, bMap.Diff), steps[i][len(steps[i])-1])
+	}
+}
+
+// TestTimeline checks that the timeline ends with the star rating of the whole map and hardest sections don't overlap
+func TestTimeline(t *testing.T) {
+	bMap := loadMap(t, "stacks.osu", "DT")
+
+	timeline := CalculateTimeline(Versions[len(Versions)-1], bMap.HitObjects, bMap.Diff)
+
+	if len(timeline.Points) != len(bMap.HitObjects) {
+		t.Fatalf("expected %d points, got %d", len(bMap.HitObjects), len(timeline.Points))
+	}
+
+	expected := GetDifficultyCalculatorFor(Versions[len(Versions)-1]).CalculateSingle(bMap.HitObjects, bMap.Diff)
+
+	if last := timeline.Points[len(timeline.Points)-1]; !floatEquals(expected.Total, last.Stars) {
+		t.Errorf("expected %v stars, got %v", expected.Total, last.Stars)
+	}
+
+	sections := timeline.FindHardestSections(5000, 3)
+
+	if len(sections) != 3 {
+		t.Fatalf("expected 3 sections, got %d", len(sections))
+	}
+
+	for i, s := range sections {
+		if i > 0 && s.Stars > sections[i-1].Stars {
+			t.Errorf("section %d is harder than section %d", i, i-1)
+		}
+
+		if s.FirstObject < 0 || s.LastObject < s.FirstObject || timeline.Points[s.FirstObject].Time != s.Start {
+			t.Errorf("section %d has wrong objects: %d-%d", i, s.FirstObject, s.LastObject)
+		}
+
+		for j := range i {
+			if s.Start < sections[j].End && sections[j].Start < s.End {
+				t.Errorf("sections %d and %d overlap", i, j)
+			}
+		}
+	}
+}
+
+// TestWindUp checks that star rating under Wind Up is between the initial and final rates, that it has its own masked mod string,
+// that rate adjusted time is the integral of 1/rate and that hardest sections cover the requested map time
+func TestWindUp(t *testing.T) {
+	stars := make(map[string]float64)
+	masked := make(map[string]string)
+
+	for _, mods := range []string{"", "DT", "WU"} {
+		bMap := loadMap(t, "sliders.osu", mods)
+
+		stars[mods] = GetDifficultyCalculator().CalculateSingle(bMap.HitObjects, bMap.Diff).Total
+
+		// Players with the same masked mod string share difficulty attributes in knockout
+		masked[mods] = bMap.Diff.GetModStringMasked()
+
+		if mods != "WU" {
+			continue
+		}
+
+		end := bMap.HitObjects[len(bMap.HitObjects)-1].GetEndTime()
+
+		integral := 0.0
+		for time := 0.5; time < end; time++ {
+			integral += 1 / bMap.Diff.GetSpeedAt(time)
+		}
+
+		if adjusted := bMap.Diff.GetAdjustedTime(end); math.Abs(adjusted-integral) > 0.01 {
+			t.Errorf("expected adjusted time %v, got %v", integral, adjusted)
+		}
+
+		// Sections are rounded to whole strain sections, which are up to 400ms*1.5 long in map time
+		timeline := CalculateTimeline(Versions[len(Versions)-1], bMap.HitObjects, bMap.Diff)
+
+		for i, s := range timeline.FindHardestSections(2000, 2) {
+			// Section starts at its first object, not at the start of its first strain section
+			if length := s.End - timeline.sectionStart(timeline.sectionOf(s.Start)); math.Abs(length-2000) > 300 {
+				t.Errorf("expected section %d to be 2000ms long, got %v", i, length)
+			}
+		}
+	}
+
+	if !(stars[""] < stars["WU"] && stars["WU"] < stars["DT"]) {
+		t.Errorf("expected Wind Up star rating between NM (%v) and DT (%v), got %v", stars[""], stars["DT"], stars["WU"])
+	}
+
+	if masked["WU"] != "WUS1-1.5x" {
+		t.Errorf("expected Wind Up masked mod string WUS1-1.5x, got %s", masked["WU"])
+	}
+}
+
+// loadMap parses a fixture the same way as the calc command
+func loadMap(t *testing.T, name, mods string) *beatmap.BeatMap {
+	t.Helper()
+
+	file, err := os.Open(filepath.Join(settings.General.GetSongsDir(), name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	bMap := beatmap.ParseBeatMapFile(file)
+	if bMap == nil {
+		t.Fatalf("Failed to parse %s", name)
+	}
+
+	bMap.Diff.SetMods2(difficulty.ParseMods(mods).ConvertToModInfoList())
+
+	beatmap.ParseTimingPointsAndPauses(bMap)
+	beatmap.ParseObjects(bMap, true, false, false)
+
+	return bMap
+}
+
+// compareFields compares all numeric fields of two structs of the same type
+func compareFields(t *testing.T, name string, expected, actual any) {
+	t.Helper()
+
+	eValue := reflect.ValueOf(expected)
+	aValue := reflect.ValueOf(actual)
+
+	for i := 0; i < eValue.NumField(); i++ {
+		field := fmt.Sprintf("%s.%s", name, eValue.Type().Field(i).Name)
+
+		switch e, a := eValue.Field(i), aValue.Field(i); e.Kind() {
+		case reflect.Float64:
+			if !floatEquals(e.Float(), a.Float()) {
+				t.Errorf("%s: expected %v, got %v", field, e.Float(), a.Float())
+			}
+		case reflect.Int:
+			if e.Int() != a.Int() {
+				t.Errorf("%s: expected %d, got %d", field, e.Int(), a.Int())
+			}
+		}
+	}
+}
+
+func floatEquals(expected, actual float64) bool {
+	return math.Abs(expected-actual) <= tolerance*max(1, math.Abs(expected))
+}
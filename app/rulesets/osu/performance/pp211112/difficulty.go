@@ -23,6 +23,15 @@ func NewDifficultyCalculator() api.IDifficultyCalculator {
 	return &DifficultyCalculator{}
 }
 
+func init() {
+	api.RegisterVersion(api.CalculatorVersion{
+		ID:                      "211112",
+		Description:             "2021-11-12 (First Xexxar)",
+		NewDifficultyCalculator: NewDifficultyCalculator,
+		NewPPCalculator:         NewPPCalculator,
+	})
+}
+
 // getStarsFromRawValues converts raw skill values to Attributes
 func (diffCalc *DifficultyCalculator) getStarsFromRawValues(rawAim, rawAimNoSliders, rawSpeed, rawFlashlight float64, diff *difficulty.Difficulty, attr api.Attributes) api.Attributes {
 	aimRating := math.Sqrt(rawAim) * StarScalingFactor
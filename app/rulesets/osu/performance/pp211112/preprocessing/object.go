@@ -50,9 +50,9 @@ func NewDifficultyObject(hitObject, lastLastObject, lastObject objects.IHitObjec
 		BaseObject:     hitObject,
 		lastObject:     lastObject,
 		lastLastObject: lastLastObject,
-		DeltaTime:      (hitObject.GetStartTime() - lastObject.GetStartTime()) / d.Speed,
-		StartTime:      hitObject.GetStartTime() / d.Speed,
-		EndTime:        hitObject.GetEndTime() / d.Speed,
+		DeltaTime:      d.GetAdjustedDuration(lastObject.GetStartTime(), hitObject.GetStartTime()),
+		StartTime:      d.GetAdjustedTime(hitObject.GetStartTime()),
+		EndTime:        d.GetAdjustedTime(hitObject.GetEndTime()),
 		Angle:          math.NaN(),
 	}
 
@@ -83,7 +83,7 @@ func (o *DifficultyObject) setDistances(experimental bool) {
 
 	if lastSlider, ok := o.lastObject.(*LazySlider); ok {
 		o.TravelDistance = float64(lastSlider.LazyTravelDistance)
-		o.TravelTime = max(lastSlider.LazyTravelTime/o.diff.Speed, MinDeltaTime)
+		o.TravelTime = max(o.diff.GetModifiedTimeAt(lastSlider.LazyTravelTime, lastSlider.GetStartTime()), MinDeltaTime)
 		o.MovementTime = max(o.StrainTime-o.TravelTime, MinDeltaTime)
 
 		// Jump distance from the slider tail to the next object, as opposed to the lazy position of JumpDistance.
@@ -54,7 +54,7 @@ func (s *SpeedSkill) speedStrainValue(current *preprocessing2.DifficultyObject)
 	strainTime := current.StrainTime
 
 	previous := s.GetPrevious(0)
-	greatWindowFull := s.diff.Hit300U / s.diff.Speed * 2
+	greatWindowFull := s.diff.GetModifiedTimeAt(s.diff.Hit300U, current.BaseObject.GetStartTime()) * 2
 	speedWindowRatio := strainTime / greatWindowFull
 
 	// Aim to nerf cheesy rhythms (Very fast consecutive doubles with large deltatimes between)
@@ -81,7 +81,7 @@ func (s *SpeedSkill) speedStrainBonus(current *preprocessing2.DifficultyObject)
 		return 0
 	}
 
-	greatWindow := s.diff.Hit300U / s.diff.Speed
+	greatWindow := s.diff.GetModifiedTimeAt(s.diff.Hit300U, current.BaseObject.GetStartTime())
 
 	previousIslandSize := 0
 	rhythmComplexitySum := 0.0
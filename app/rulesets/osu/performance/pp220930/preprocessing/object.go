@@ -59,11 +59,11 @@ func NewDifficultyObject(hitObject, lastLastObject, lastObject objects.IHitObjec
 		BaseObject:     hitObject,
 		lastObject:     lastObject,
 		lastLastObject: lastLastObject,
-		DeltaTime:      (hitObject.GetStartTime() - lastObject.GetStartTime()) / d.Speed,
-		StartTime:      hitObject.GetStartTime() / d.Speed,
-		EndTime:        hitObject.GetEndTime() / d.Speed,
+		DeltaTime:      d.GetAdjustedDuration(lastObject.GetStartTime(), hitObject.GetStartTime()),
+		StartTime:      d.GetAdjustedTime(hitObject.GetStartTime()),
+		EndTime:        d.GetAdjustedTime(hitObject.GetEndTime()),
 		Angle:          math.NaN(),
-		GreatWindow:    2 * d.Hit300U / d.Speed,
+		GreatWindow:    2 * d.GetModifiedTimeAt(d.Hit300U, hitObject.GetStartTime()),
 	}
 
 	obj.StrainTime = max(obj.DeltaTime, MinDeltaTime)
@@ -118,7 +118,7 @@ func (o *DifficultyObject) setDistances() {
 	if currentSlider, ok := o.BaseObject.(*LazySlider); ok {
 		// danser's RepeatCount considers first span, that's why we have to subtract 1 here
 		o.TravelDistance = float64(currentSlider.LazyTravelDistance * float32(math.Pow(1+float64(currentSlider.RepeatCount-1)/2.5, 1.0/2.5)))
-		o.TravelTime = max(currentSlider.LazyTravelTime/o.Diff.Speed, MinDeltaTime)
+		o.TravelTime = max(o.Diff.GetModifiedTimeAt(currentSlider.LazyTravelTime, currentSlider.GetStartTime()), MinDeltaTime)
 	}
 
 	_, ok1 := o.BaseObject.(*objects.Spinner)
@@ -142,7 +142,7 @@ func (o *DifficultyObject) setDistances() {
 	o.MinimumJumpDistance = o.LazyJumpDistance
 
 	if lastSlider, ok := o.lastObject.(*LazySlider); ok {
-		lastTravelTime := max(lastSlider.LazyTravelTime/o.Diff.Speed, MinDeltaTime)
+		lastTravelTime := max(o.Diff.GetModifiedTimeAt(lastSlider.LazyTravelTime, lastSlider.GetStartTime()), MinDeltaTime)
 		o.MinimumJumpTime = max(o.StrainTime-lastTravelTime, MinDeltaTime)
 
 		//
@@ -23,6 +23,15 @@ func NewDifficultyCalculator() api.IDifficultyCalculator {
 	return &DifficultyCalculator{}
 }
 
+func init() {
+	api.RegisterVersion(api.CalculatorVersion{
+		ID:                      "241007",
+		Description:             "2024 pp rework",
+		NewDifficultyCalculator: NewDifficultyCalculator,
+		NewPPCalculator:         NewPPCalculator,
+	})
+}
+
 // getStarsFromRawValues converts raw skill values to Attributes
 func (diffCalc *DifficultyCalculator) getStarsFromRawValues(rawAim, rawAimNoSliders, rawSpeed, rawFlashlight float64, diff *difficulty.Difficulty, attr api.Attributes) api.Attributes {
 	aimRating := math.Sqrt(rawAim) * StarScalingFactor
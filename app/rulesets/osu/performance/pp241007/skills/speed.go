@@ -87,5 +87,10 @@ func (s *SpeedSkill) RelevantNoteCount() float64 {
 		return s.relevantNoteCountV
 	}
 
+	// maxStrain is tracked during processing only in step calc
+	for _, strain := range s.objectStrains {
+		s.maxStrain = max(s.maxStrain, strain)
+	}
+
 	return s.relevantNoteCount()
 }
@@ -0,0 +1,250 @@
+package performance
+
+import (
+	"cmp"
+	"fmt"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/beatmap/objects"
+	"github.com/wieku/danser-go/app/rulesets/osu/performance/api"
+	"math"
+	"slices"
+)
+
+// sectionLength is the length of strain sections in rate adjusted milliseconds, the same in all pp versions
+const sectionLength = 400.0
+
+// TimelinePoint contains strains of the section an object is in and star rating of the map up to that object
+type TimelinePoint struct {
+	Index int     `json:"index"`
+	Time  float64 `json:"time"`
+
+	AimStrain        float64 `json:"aimStrain"`
+	SpeedStrain      float64 `json:"speedStrain"`
+	FlashlightStrain float64 `json:"flashlightStrain"`
+	StrainStars      float64 `json:"strainStars"`
+
+	Aim        float64 `json:"aim"`
+	Speed      float64 `json:"speed"`
+	Flashlight float64 `json:"flashlight"`
+	Stars      float64 `json:"stars"`
+}
+
+// HardSection is a part of the map with the highest average strain, times are in map time
+type HardSection struct {
+	Start       float64 `json:"start"`
+	End         float64 `json:"end"`
+	Timestamp   string  `json:"timestamp"`
+	FirstObject int     `json:"firstObject"`
+	LastObject  int     `json:"lastObject"`
+
+	// Stars is the average of section strains passed through star rating formula, Peak is the highest of them
+	Stars float64 `json:"stars"`
+	Peak  float64 `json:"peak"`
+
+	// Skill is the skill contributing the most to the section: aim, speed or flashlight
+	Skill string `json:"skill"`
+}
+
+// DifficultyTimeline contains per-object difficulty of a beatmap
+type DifficultyTimeline struct {
+	Points []TimelinePoint
+	Peaks  api.StrainPeaks
+
+	// sectionEnd is the end of the first strain section in rate adjusted time
+	sectionEnd float64
+	diff       *difficulty.Difficulty
+}
+
+// CalculateTimeline calculates strains and successive star ratings of every object under a given pp version
+func CalculateTimeline(version string, objects []objects.IHitObject, diff *difficulty.Difficulty) *DifficultyTimeline {
+	diffCalc := GetDifficultyCalculatorFor(version)
+
+	timeline := &DifficultyTimeline{
+		Peaks: diffCalc.CalculateStrainPeaks(objects, diff),
+		diff:  diff,
+	}
+
+	if len(objects) > 1 {
+		timeline.sectionEnd = math.Ceil(diff.GetAdjustedTime(objects[1].GetStartTime())/sectionLength) * sectionLength
+	}
+
+	steps := diffCalc.CalculateStep(objects, diff)
+
+	timeline.Points = make([]TimelinePoint, len(objects))
+
+	for i, o := range objects {
+		section := timeline.sectionOf(o.GetStartTime())
+
+		timeline.Points[i] = TimelinePoint{
+			Index:            i,
+			Time:             o.GetStartTime(),
+			AimStrain:        timeline.Peaks.Aim[section],
+			SpeedStrain:      timeline.Peaks.Speed[section],
+			FlashlightStrain: timeline.Peaks.Flashlight[section],
+			StrainStars:      timeline.Peaks.Total[section],
+			Aim:              steps[i].Aim,
+			Speed:            steps[i].Speed,
+			Flashlight:       steps[i].Flashlight,
+			Stars:            steps[i].Total,
+		}
+	}
+
+	return timeline
+}
+
+// sectionOf returns the index of the strain section containing given map time
+func (timeline *DifficultyTimeline) sectionOf(time float64) int {
+	index := int(math.Ceil((timeline.diff.GetAdjustedTime(time) - timeline.sectionEnd) / sectionLength))
+
+	return min(max(index, 0), len(timeline.Peaks.Total)-1)
+}
+
+// sectionStart returns the start of a strain section in map time
+func (timeline *DifficultyTimeline) sectionStart(section int) float64 {
+	adjusted := timeline.sectionEnd + float64(section-1)*sectionLength
+
+	if !timeline.diff.HasVariableRate() {
+		return adjusted * timeline.diff.GetSpeed()
+	}
+
+	// Adjusted time grows with map time, so it can be inverted with binary search
+	low, high := adjusted*0.25, adjusted*4
+	if adjusted < 0 {
+		low, high = high, low
+	}
+
+	for range 50 {
+		mid := (low + high) / 2
+
+		if timeline.diff.GetAdjustedTime(mid) < adjusted {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+
+	return (low + high) / 2
+}
+
+// FindHardestSections finds up to count non-overlapping parts of the map with the highest average strain, from the hardest one.
+// length is the length of a part in map time milliseconds.
+func (timeline *DifficultyTimeline) FindHardestSections(length float64, count int) []HardSection {
+	peaks := timeline.Peaks.Total
+
+	if len(timeline.Points) == 0 || len(peaks) == 0 || count <= 0 {
+		return nil
+	}
+
+	sums := make([]float64, len(peaks)+1)
+	for i, p := range peaks {
+		sums[i+1] = sums[i] + p
+	}
+
+	// ends holds the section after the window of each starting section. Window covers length of map time,
+	// so with variable rate windows at different places of the map have different number of sections.
+	ends := make([]int, len(peaks))
+
+	var candidates []int
+
+	for start := range peaks {
+		adjustedStart := timeline.sectionEnd + float64(start-1)*sectionLength
+		adjustedEnd := timeline.diff.GetAdjustedTime(timeline.sectionStart(start) + length)
+
+		ends[start] = start + max(int(math.Round((adjustedEnd-adjustedStart)/sectionLength)), 1)
+
+		if ends[start] <= len(peaks) {
+			candidates = append(candidates, start)
+		}
+	}
+
+	if len(candidates) == 0 { // Map is shorter than a single window
+		candidates = append(candidates, 0)
+		ends[0] = len(peaks)
+	}
+
+	average := func(start int) float64 {
+		return (sums[ends[start]] - sums[start]) / float64(ends[start]-start)
+	}
+
+	slices.SortStableFunc(candidates, func(a, b int) int {
+		return cmp.Compare(average(b), average(a))
+	})
+
+	var picked []int
+
+	for _, c := range candidates {
+		if len(picked) == count {
+			break
+		}
+
+		overlaps := slices.ContainsFunc(picked, func(p int) bool {
+			return c < ends[p] && p < ends[c]
+		})
+
+		if !overlaps {
+			picked = append(picked, c)
+		}
+	}
+
+	sections := make([]HardSection, 0, len(picked))
+
+	for _, start := range picked {
+		sections = append(sections, timeline.createSection(start, ends[start]-1, average(start)))
+	}
+
+	return sections
+}
+
+func (timeline *DifficultyTimeline) createSection(first, last int, stars float64) HardSection {
+	section := HardSection{
+		Start:       timeline.sectionStart(first),
+		End:         timeline.sectionStart(last + 1),
+		FirstObject: -1,
+		LastObject:  -1,
+		Stars:       stars,
+	}
+
+	var aim, speed, flashlight float64
+
+	for i := first; i <= last; i++ {
+		section.Peak = max(section.Peak, timeline.Peaks.Total[i])
+
+		aim += timeline.Peaks.Aim[i]
+		speed += timeline.Peaks.Speed[i]
+		flashlight += timeline.Peaks.Flashlight[i]
+	}
+
+	section.Skill = "aim"
+	if speed > aim {
+		section.Skill = "speed"
+	}
+
+	if timeline.diff.CheckModActive(difficulty.Flashlight) && flashlight > max(aim, speed) {
+		section.Skill = "flashlight"
+	}
+
+	for _, p := range timeline.Points {
+		if s := timeline.sectionOf(p.Time); s >= first && s <= last {
+			if section.FirstObject == -1 {
+				section.FirstObject = p.Index
+			}
+
+			section.LastObject = p.Index
+		}
+	}
+
+	if section.FirstObject >= 0 { // Start from the first object so the timestamp can be pasted into the editor
+		section.Start = timeline.Points[section.FirstObject].Time
+	}
+
+	section.Timestamp = FormatTimestamp(section.Start)
+
+	return section
+}
+
+// FormatTimestamp formats map time the same way as osu! editor does (mm:ss:mmm)
+func FormatTimestamp(time float64) string {
+	ms := max(int64(time), 0)
+
+	return fmt.Sprintf("%02d:%02d:%03d", ms/60000, ms/1000%60, ms%1000)
+}
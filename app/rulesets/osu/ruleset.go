@@ -84,6 +84,9 @@ type subSet struct {
 
 	ppv2 api.IPerformanceCalculator
 
+	// ppv2Compare calculates pp of the comparison version, nil if comparison is disabled
+	ppv2Compare api.IPerformanceCalculator
+
 	recoveries int
 	failed     bool
 	sdpfFail   bool
@@ -106,22 +109,40 @@ type OsuRuleSet struct {
 
 	oppDiffs map[string][]api.Attributes
 
+	compareVersion string
+	compareDiffs   map[string][]api.Attributes
+
 	queue        []HitObject
 	processed    []HitObject
-	hitListener  hitListener
+	hitListeners []hitListener
 	endListener  endListener
 	failListener failListener
 }
 
 func NewOsuRuleset(beatMap *beatmap.BeatMap, cursors []*graphics.Cursor, diffs []*difficulty.Difficulty) *OsuRuleSet {
+	return newOsuRuleset(beatMap, cursors, diffs, make(map[string][]api.Attributes), make(map[string][]api.Attributes))
+}
+
+// NewOsuRulesetFrom creates a ruleset in initial state for the same map and players as previous one, e.g. when the map is rewound.
+// Difficulty attributes of the previous ruleset are reused, so they are not calculated again.
+func NewOsuRulesetFrom(previous *OsuRuleSet, beatMap *beatmap.BeatMap, cursors []*graphics.Cursor, diffs []*difficulty.Difficulty) *OsuRuleSet {
+	return newOsuRuleset(beatMap, cursors, diffs, previous.oppDiffs, previous.compareDiffs)
+}
+
+func newOsuRuleset(beatMap *beatmap.BeatMap, cursors []*graphics.Cursor, diffs []*difficulty.Difficulty, oppDiffs, compareDiffs map[string][]api.Attributes) *OsuRuleSet {
 	log.Println("Creating osu! ruleset...")
 
 	ruleset := new(OsuRuleSet)
 	ruleset.beatMap = beatMap
-	ruleset.oppDiffs = make(map[string][]api.Attributes)
+	ruleset.oppDiffs = oppDiffs
+	ruleset.compareDiffs = compareDiffs
 
 	log.Println("Using pp calc version", performance.GetDifficultyCalculator().GetVersionMessage())
 
+	if ruleset.compareVersion = performance.GetCompareVersion(); ruleset.compareVersion != "" {
+		log.Println("Comparing with pp calc version", performance.GetDifficultyCalculatorFor(ruleset.compareVersion).GetVersionMessage())
+	}
+
 	ruleset.cursors = make(map[*graphics.Cursor]*subSet)
 
 	diffPlayers := make([]*difficultyPlayer, 0, len(cursors))
@@ -149,7 +170,14 @@ func NewOsuRuleset(beatMap *beatmap.BeatMap, cursors []*graphics.Cursor, diffs [
 		}
 
 		if ruleset.oppDiffs[player.maskedModString] == nil {
-			ruleset.oppDiffs[player.maskedModString] = performance.GetDifficultyCalculator().CalculateStep(ruleset.beatMap.HitObjects, player.diff)
+			if ruleset.compareVersion != "" {
+				attribs := performance.CalculateStepVersions([]string{settings.Gameplay.PPVersion, ruleset.compareVersion}, ruleset.beatMap.HitObjects, player.diff)
+
+				ruleset.oppDiffs[player.maskedModString] = attribs[0]
+				ruleset.compareDiffs[player.maskedModString] = attribs[1]
+			} else {
+				ruleset.oppDiffs[player.maskedModString] = performance.GetDifficultyCalculator().CalculateStep(ruleset.beatMap.HitObjects, player.diff)
+			}
 
 			star := ruleset.oppDiffs[player.maskedModString][len(ruleset.oppDiffs[player.maskedModString])-1]
 
@@ -233,6 +261,10 @@ func NewOsuRuleset(beatMap *beatmap.BeatMap, cursors []*graphics.Cursor, diffs [
 			recoveries:     recoveries,
 			scoreProcessor: sc,
 		}
+
+		if ruleset.compareVersion != "" {
+			ruleset.cursors[cursor].ppv2Compare = performance.CreatePPCalculatorFor(ruleset.compareVersion)
+		}
 	}
 
 	for _, obj := range beatMap.HitObjects {
@@ -429,8 +461,10 @@ func (set *OsuRuleSet) SendResult(cursor *graphics.Cursor, judgementResult Judge
 	subSet := set.cursors[cursor]
 
 	if judgementResult.HitResult == Ignore || judgementResult.HitResult == PositionalMiss {
-		if judgementResult.HitResult == PositionalMiss && set.hitListener != nil && !subSet.player.diff.Mods.Active(difficulty.Relax) {
-			set.hitListener(cursor, judgementResult, *subSet.score)
+		if judgementResult.HitResult == PositionalMiss && !subSet.player.diff.Mods.Active(difficulty.Relax) {
+			for _, listener := range set.hitListeners {
+				listener(cursor, judgementResult, *subSet.score)
+			}
 		}
 
 		return
@@ -482,6 +516,10 @@ func (set *OsuRuleSet) SendResult(cursor *graphics.Cursor, judgementResult Judge
 
 	subSet.score.PP = subSet.ppv2.Calculate(diff, subSet.score.ToPerfScore(), subSet.player.diff)
 
+	if subSet.ppv2Compare != nil {
+		subSet.score.PPCompare = subSet.ppv2Compare.Calculate(set.compareDiffs[subSet.player.maskedModString][index], subSet.score.ToPerfScore(), subSet.player.diff)
+	}
+
 	switch judgementResult.HitResult {
 	case Hit100:
 		subSet.currentKatu++
@@ -532,11 +570,11 @@ func (set *OsuRuleSet) SendResult(cursor *graphics.Cursor, judgementResult Judge
 		subSet.hp.AddResult(judgementResult)
 	}
 
-	if set.hitListener != nil {
-		set.hitListener(cursor, judgementResult, *subSet.score)
+	for _, listener := range set.hitListeners {
+		listener(cursor, judgementResult, *subSet.score)
 	}
 
-	if len(set.cursors) == 1 && judgementResult.HitResult != SliderFinish && !settings.RECORD {
+	if len(set.cursors) == 1 && judgementResult.HitResult != SliderFinish && !settings.RECORD && !settings.HEADLESS {
 		log.Println(fmt.Sprintf(
 			"Got: %3d, Combo: %4d, Max Combo: %4d, Score: %9d, Acc: %6.2f%%, 300: %4d, 100: %3d, 50: %2d, miss: %2d, from: %d, at: %d, pos: %.0fx%.0f, pp: %.2f",
 			judgementResult.HitResult.ScoreValueMod(subSet.player.diff.Mods),
@@ -724,8 +762,8 @@ func (set *OsuRuleSet) PlayerStopped(cursor *graphics.Cursor, time int64) {
 	}
 }
 
-func (set *OsuRuleSet) SetListener(listener hitListener) {
-	set.hitListener = listener
+func (set *OsuRuleSet) AddListener(listener hitListener) {
+	set.hitListeners = append(set.hitListeners, listener)
 }
 
 func (set *OsuRuleSet) SetEndListener(listener endListener) {
@@ -777,6 +815,18 @@ func (set *OsuRuleSet) GetSSPP(cursor *graphics.Cursor) api.PPv2Results {
 	return subSet.ppv2.Calculate(diff, api.PerfScore{CountGreat: -1, MaxCombo: -1, Accuracy: 1, SliderEnd: -1}, subSet.player.diff)
 }
 
+// GetCompareVersion returns the pp version used for PPCompare of scores, empty if comparison is disabled
+func (set *OsuRuleSet) GetCompareVersion() string {
+	return set.compareVersion
+}
+
+// GetAttributes returns difficulty attributes of the whole map with player's mods
+func (set *OsuRuleSet) GetAttributes(cursor *graphics.Cursor) api.Attributes {
+	diffs := set.oppDiffs[set.cursors[cursor].player.maskedModString]
+
+	return diffs[len(diffs)-1]
+}
+
 func (set *OsuRuleSet) GetScore(cursor *graphics.Cursor) Score {
 	return *(set.cursors[cursor].score)
 }
@@ -786,6 +836,14 @@ func (set *OsuRuleSet) GetHP(cursor *graphics.Cursor) float64 {
 	return subSet.hp.GetHealth()
 }
 
+func (set *OsuRuleSet) IsFailed(cursor *graphics.Cursor) bool {
+	return set.cursors[cursor].failed
+}
+
+func (set *OsuRuleSet) IsEnded() bool {
+	return set.ended
+}
+
 func (set *OsuRuleSet) GetPlayer(cursor *graphics.Cursor) *difficultyPlayer {
 	subSet := set.cursors[cursor]
 	return subSet.player
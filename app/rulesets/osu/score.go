@@ -34,6 +34,9 @@ type Score struct {
 	MaxSliderEnd uint
 	PP           api.PPv2Results
 
+	// PPCompare is PP under the comparison version, set only if comparison is enabled
+	PPCompare api.PPv2Results
+
 	scoredObjects uint
 }
 
@@ -337,7 +337,10 @@ func (slider *Slider) processTicksStable(player *difficultyPlayer, state *slider
 			}
 		}
 
-		slider.ruleSet.SendResult(player.cursor, createJudgementResult(scoreGiven, maxScore, combo, time, sliderPosition, slider))
+		result := createJudgementResult(scoreGiven, maxScore, combo, time, sliderPosition, slider)
+		result.edgeNum = point.edgeNum
+
+		slider.ruleSet.SendResult(player.cursor, result)
 	}
 }
 
@@ -391,7 +394,10 @@ func (slider *Slider) processTicksLazer(player *difficultyPlayer, state *sliders
 		}
 
 		if scoreGiven != Ignore {
-			slider.ruleSet.SendResult(player.cursor, createJudgementResult(scoreGiven, point.scoreGiven, combo, time, sliderPosition, slider))
+			result := createJudgementResult(scoreGiven, point.scoreGiven, combo, time, sliderPosition, slider)
+			result.edgeNum = point.edgeNum
+
+			slider.ruleSet.SendResult(player.cursor, result)
 		}
 	}
 }
@@ -0,0 +1,115 @@
+package taiko
+
+import (
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"math"
+)
+
+type HitResult uint16
+
+const (
+	Ignore = HitResult(0)
+	Miss   = HitResult(1 << iota)
+	Ok
+	Great
+	StrongBonus
+	DrumRollTick
+	SwellTick
+	SwellComplete
+	SwellFail
+	BaseHits  = Ok | Great
+	BaseHitsM = BaseHits | Miss
+	Bonuses   = StrongBonus | DrumRollTick | SwellTick | SwellComplete
+)
+
+func (r HitResult) AffectsAcc() bool {
+	return r&BaseHitsM > 0
+}
+
+func (r HitResult) ScoreValue() int64 {
+	switch r {
+	case Great, DrumRollTick, SwellTick:
+		return 300
+	case Ok:
+		return 150
+	case SwellComplete:
+		return 1000
+	}
+
+	return 0
+}
+
+func (r HitResult) String() string {
+	switch r {
+	case Miss:
+		return "miss"
+	case Ok:
+		return "ok"
+	case Great:
+		return "great"
+	case StrongBonus:
+		return "strongBonus"
+	case DrumRollTick:
+		return "drumRollTick"
+	case SwellTick:
+		return "swellTick"
+	case SwellComplete:
+		return "swellComplete"
+	case SwellFail:
+		return "swellFail"
+	}
+
+	return "ignore"
+}
+
+// Action is a drum key, replays store them as mouse and keyboard buttons
+type Action uint8
+
+const (
+	LeftCentre = Action(1 << iota)
+	LeftRim
+	RightCentre
+	RightRim
+	Centres = LeftCentre | RightCentre
+	Rims    = LeftRim | RightRim
+)
+
+func (action Action) IsCentre() bool {
+	return action&Centres > 0
+}
+
+type HitWindows struct {
+	Great float64
+	Ok    float64
+	Miss  float64
+
+	// StrongWindow is a time in which a second key has to be pressed to get strong hit bonus
+	StrongWindow float64
+}
+
+func NewHitWindows(diff *difficulty.Difficulty) HitWindows {
+	// Difficulty stores windows calculated from OD with mods applied so we can get it back from the 300 window
+	od := difficulty.DiffFromRate(diff.Hit300U, 80, 50, 20)
+
+	return HitWindows{
+		Great:        math.Floor(difficulty.DifficultyRate(od, 50, 35, 20)),
+		Ok:           math.Floor(difficulty.DifficultyRate(od, 120, 80, 50)),
+		Miss:         math.Floor(difficulty.DifficultyRate(od, 135, 95, 70)),
+		StrongWindow: 30,
+	}
+}
+
+func (windows HitWindows) ResultFor(delta float64) HitResult {
+	delta = math.Abs(delta)
+
+	switch {
+	case delta <= windows.Great:
+		return Great
+	case delta <= windows.Ok:
+		return Ok
+	case delta <= windows.Miss:
+		return Miss
+	}
+
+	return Ignore
+}
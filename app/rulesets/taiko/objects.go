@@ -0,0 +1,174 @@
+package taiko
+
+import (
+	"github.com/wieku/danser-go/app/audio"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/objects"
+	"math"
+)
+
+const (
+	// Converted maps are played with slider velocity multiplied by this value
+	velocityMultiplier = 1.4
+
+	swellHitMultiplier = 1.65
+
+	baseScoringDistance = 100.0
+)
+
+type ObjectType uint8
+
+const (
+	Centre = ObjectType(iota)
+	Rim
+	DrumRoll
+	Swell
+)
+
+type Object struct {
+	Type   ObjectType
+	Strong bool
+
+	StartTime float64
+	EndTime   float64
+
+	// Velocity is a scroll speed in osu!pixels per millisecond
+	Velocity float64
+
+	// Ticks are drum roll tick times, TickSpacing is the time between them
+	Ticks       []float64
+	TickSpacing float64
+
+	RequiredHits int
+
+	HitSound audio.HitSound
+
+	Number int64
+}
+
+func (object *Object) IsHit() bool {
+	return object.Type == Centre || object.Type == Rim
+}
+
+// ConvertBeatMap converts osu!standard objects to taiko ones the same way as osu!lazer does. BeatMap needs to have its objects parsed.
+func ConvertBeatMap(bMap *beatmap.BeatMap) []*Object {
+	native := bMap.Mode == 1
+
+	converted := make([]*Object, 0, len(bMap.HitObjects))
+
+	for _, o := range bMap.HitObjects {
+		switch obj := o.(type) {
+		case *objects.Circle:
+			converted = append(converted, newHit(obj.GetStartTime(), obj.GetHitSound(), getVelocity(bMap, obj.GetStartTime())))
+		case *objects.Slider:
+			converted = append(converted, convertSlider(bMap, obj, native)...)
+		case *objects.Spinner:
+			converted = append(converted, &Object{
+				Type:         Swell,
+				StartTime:    obj.GetStartTime(),
+				EndTime:      obj.GetEndTime(),
+				Velocity:     getVelocity(bMap, obj.GetStartTime()),
+				RequiredHits: max(1, int(obj.GetDuration()/1000*bMap.Diff.SpinnerRatio*swellHitMultiplier)),
+				HitSound:     obj.GetHitSound(),
+			})
+		}
+	}
+
+	for i, o := range converted {
+		o.Number = int64(i)
+	}
+
+	return converted
+}
+
+func convertSlider(bMap *beatmap.BeatMap, slider *objects.Slider, native bool) []*Object {
+	spans := float64(slider.RepeatCount)
+
+	distance := float64(slider.GetLength()) * spans * velocityMultiplier
+
+	point := slider.TPoint
+
+	beatLength := point.GetBeatLength()
+
+	scoringDistance := baseScoringDistance * bMap.SliderMultiplier * velocityMultiplier / bMap.Timings.TickRate
+	taikoVelocity := scoringDistance * bMap.Timings.TickRate
+
+	duration := math.Floor(distance / taikoVelocity * beatLength)
+
+	velocity := getVelocity(bMap, slider.GetStartTime())
+
+	if !native {
+		osuVelocity := taikoVelocity * (1000 / beatLength)
+
+		// osu!stable uses the speed-adjusted beat length to determine the osu! velocity, but only uses it for conversion if beatmap version < 8
+		if bMap.Version >= 8 {
+			beatLength = point.GetBaseBeatLength()
+		}
+
+		tickSpacing := min(beatLength/bMap.Timings.TickRate, duration/spans)
+
+		if tickSpacing > 0 && distance/osuVelocity*1000 < 2*beatLength {
+			hits := make([]*Object, 0)
+
+			i := 0
+
+			for t := slider.GetStartTime(); t <= slider.GetStartTime()+duration+tickSpacing/8; t += tickSpacing {
+				hits = append(hits, newHit(t, slider.GetEdgeHitSound(i), velocity))
+
+				i = (i + 1) % (slider.RepeatCount + 1)
+			}
+
+			return hits
+		}
+	}
+
+	drumRoll := &Object{
+		Type:      DrumRoll,
+		Strong:    slider.GetEdgeHitSound(0).Sample&4 > 0,
+		StartTime: slider.GetStartTime(),
+		EndTime:   slider.GetStartTime() + duration,
+		Velocity:  velocity,
+		HitSound:  slider.GetEdgeHitSound(0),
+	}
+
+	tickRate := 4.0
+	if bMap.Timings.TickRate == 3 {
+		tickRate = 3
+	}
+
+	drumRoll.TickSpacing = point.GetBaseBeatLength() / tickRate
+
+	if drumRoll.TickSpacing > 0 {
+		for t := drumRoll.StartTime; t < drumRoll.EndTime+drumRoll.TickSpacing/8; t += drumRoll.TickSpacing {
+			drumRoll.Ticks = append(drumRoll.Ticks, t)
+		}
+	}
+
+	return []*Object{drumRoll}
+}
+
+func newHit(time float64, hitSound audio.HitSound, velocity float64) *Object {
+	hit := &Object{
+		Type:      Centre,
+		Strong:    hitSound.Sample&4 > 0,
+		StartTime: time,
+		EndTime:   time,
+		Velocity:  velocity,
+		HitSound:  hitSound,
+	}
+
+	if hitSound.Sample&(2|8) > 0 { // whistle or clap
+		hit.Type = Rim
+	}
+
+	return hit
+}
+
+func getVelocity(bMap *beatmap.BeatMap, time float64) float64 {
+	beatLength := bMap.Timings.GetPointAt(time).GetBeatLength()
+	if beatLength <= 0 || math.IsNaN(beatLength) {
+		beatLength = 1000
+	}
+
+	return baseScoringDistance * bMap.SliderMultiplier * velocityMultiplier / beatLength
+}
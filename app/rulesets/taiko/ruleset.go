@@ -0,0 +1,335 @@
+package taiko
+
+import (
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/graphics"
+	"log"
+	"math"
+)
+
+type JudgementResult struct {
+	Object    *Object
+	Number    int64
+	Time      int64
+	HitResult HitResult
+	Action    Action
+}
+
+type objectState struct {
+	judged bool
+	result HitResult
+
+	nextTick int
+
+	swellHits    int
+	lastSwellRim bool
+}
+
+type taikoPlayer struct {
+	cursor  *graphics.Cursor
+	diff    *difficulty.Difficulty
+	windows HitWindows
+
+	score Score
+
+	keys Action
+
+	states     []objectState
+	nextObject int
+
+	strongObject *Object
+	strongResult HitResult
+	strongTime   int64
+	strongRim    bool
+}
+
+type hitListener func(cursor *graphics.Cursor, judgementResult JudgementResult, score Score)
+
+type pressListener func(cursor *graphics.Cursor, time int64, action Action)
+
+type TaikoRuleSet struct {
+	beatMap *beatmap.BeatMap
+	objects []*Object
+
+	players   []*taikoPlayer
+	playerMap map[*graphics.Cursor]*taikoPlayer
+
+	ended bool
+
+	hitListeners   []hitListener
+	pressListeners []pressListener
+}
+
+func NewTaikoRuleset(beatMap *beatmap.BeatMap, cursors []*graphics.Cursor, diffs []*difficulty.Difficulty) *TaikoRuleSet {
+	log.Println("Creating osu!taiko ruleset...")
+
+	ruleset := &TaikoRuleSet{
+		beatMap:   beatMap,
+		objects:   ConvertBeatMap(beatMap),
+		playerMap: make(map[*graphics.Cursor]*taikoPlayer),
+	}
+
+	for i, cursor := range cursors {
+		player := &taikoPlayer{
+			cursor:  cursor,
+			diff:    diffs[i],
+			windows: NewHitWindows(diffs[i]),
+			states:  make([]objectState, len(ruleset.objects)),
+		}
+
+		player.score.updateAccuracy(player.diff)
+
+		ruleset.players = append(ruleset.players, player)
+		ruleset.playerMap[cursor] = player
+	}
+
+	log.Println("Converted", len(beatMap.HitObjects), "objects to", len(ruleset.objects), "taiko objects")
+
+	return ruleset
+}
+
+// UpdateKeys sets currently pressed keys, newly pressed ones are processed as hits
+func (set *TaikoRuleSet) UpdateKeys(cursor *graphics.Cursor, time int64, keys Action) {
+	player := set.playerMap[cursor]
+
+	pressed := keys & (^player.keys)
+	player.keys = keys
+
+	for _, action := range []Action{LeftCentre, RightCentre, LeftRim, RightRim} {
+		if pressed&action > 0 {
+			for _, l := range set.pressListeners {
+				l(cursor, time, action)
+			}
+
+			set.press(player, time, action)
+		}
+	}
+}
+
+func (set *TaikoRuleSet) press(player *taikoPlayer, time int64, action Action) {
+	rim := !action.IsCentre()
+
+	if player.strongObject != nil {
+		if rim == player.strongRim && time-player.strongTime <= int64(player.windows.StrongWindow) {
+			player.score.AddStrongBonus(player.strongResult, player.diff)
+			set.sendResult(player, player.strongObject, time, StrongBonus, action)
+
+			player.strongObject = nil
+
+			return
+		}
+
+		player.strongObject = nil
+	}
+
+	fTime := float64(time)
+
+	for i := player.nextObject; i < len(set.objects); i++ {
+		object := set.objects[i]
+		state := &player.states[i]
+
+		if object.StartTime-player.windows.Miss > fTime {
+			break
+		}
+
+		if state.judged {
+			continue
+		}
+
+		switch object.Type {
+		case Centre, Rim:
+			result := player.windows.ResultFor(fTime - object.StartTime)
+			if result == Ignore {
+				continue
+			}
+
+			if (object.Type == Rim) != rim && !player.diff.CheckModActive(difficulty.Relax) {
+				result = Miss
+			}
+
+			state.judged = true
+			state.result = result
+
+			player.score.AddResult(result, false, player.diff)
+			set.sendResult(player, object, time, result, action)
+
+			if object.Strong && result != Miss {
+				player.strongObject = object
+				player.strongResult = result
+				player.strongTime = time
+				player.strongRim = rim
+			}
+
+			return
+		case DrumRoll:
+			if fTime > object.EndTime+object.TickSpacing/2 {
+				continue
+			}
+
+			for state.nextTick < len(object.Ticks) && object.Ticks[state.nextTick]+object.TickSpacing/2 < fTime {
+				state.nextTick++
+			}
+
+			if state.nextTick < len(object.Ticks) && math.Abs(object.Ticks[state.nextTick]-fTime) <= object.TickSpacing/2 {
+				state.nextTick++
+
+				player.score.AddResult(DrumRollTick, object.Strong, player.diff)
+				set.sendResult(player, object, time, DrumRollTick, action)
+
+				return
+			}
+		case Swell:
+			if fTime < object.StartTime || fTime > object.EndTime {
+				continue
+			}
+
+			// Swell has to be hit alternating between centres and rims
+			if state.swellHits > 0 && state.lastSwellRim == rim {
+				return
+			}
+
+			state.swellHits++
+			state.lastSwellRim = rim
+
+			player.score.AddResult(SwellTick, object.Strong, player.diff)
+			set.sendResult(player, object, time, SwellTick, action)
+
+			if state.swellHits >= object.RequiredHits {
+				state.judged = true
+				state.result = SwellComplete
+
+				player.score.AddResult(SwellComplete, object.Strong, player.diff)
+				set.sendResult(player, object, time, SwellComplete, action)
+			}
+
+			return
+		}
+	}
+}
+
+func (set *TaikoRuleSet) Update(time int64) {
+	fTime := float64(time)
+
+	for _, player := range set.players {
+		if player.strongObject != nil && time-player.strongTime > int64(player.windows.StrongWindow) {
+			player.strongObject = nil
+		}
+
+		for i := player.nextObject; i < len(set.objects); i++ {
+			object := set.objects[i]
+			state := &player.states[i]
+
+			if object.StartTime-player.windows.Miss > fTime {
+				break
+			}
+
+			if state.judged {
+				continue
+			}
+
+			switch object.Type {
+			case Centre, Rim:
+				if fTime > object.StartTime+player.windows.Ok {
+					state.judged = true
+					state.result = Miss
+
+					player.score.AddResult(Miss, false, player.diff)
+					set.sendResult(player, object, int64(object.StartTime+player.windows.Ok)+1, Miss, 0)
+				}
+			case DrumRoll:
+				if fTime > object.EndTime+object.TickSpacing/2 {
+					state.judged = true
+					state.nextTick = len(object.Ticks)
+				}
+			case Swell:
+				if fTime > object.EndTime {
+					state.judged = true
+					state.result = SwellFail
+
+					set.sendResult(player, object, int64(object.EndTime)+1, SwellFail, 0)
+				}
+			}
+		}
+
+		for player.nextObject < len(set.objects) && player.states[player.nextObject].judged {
+			player.nextObject++
+		}
+	}
+
+	if !set.ended {
+		ended := true
+
+		for _, player := range set.players {
+			if player.nextObject < len(set.objects) {
+				ended = false
+			}
+		}
+
+		if ended {
+			set.ended = true
+
+			for _, player := range set.players {
+				s := player.score
+				log.Printf("%s: %d, %.2f%%, %dx, %d/%d/%d (geki %d, katu %d), %s", player.cursor.Name, s.Score, s.Accuracy*100, s.Combo, s.Count300, s.Count100, s.CountMiss, s.CountGeki, s.CountKatu, s.Grade.String())
+			}
+		}
+	}
+}
+
+func (set *TaikoRuleSet) sendResult(player *taikoPlayer, object *Object, time int64, result HitResult, action Action) {
+	for _, l := range set.hitListeners {
+		l(player.cursor, JudgementResult{
+			Object:    object,
+			Number:    object.Number,
+			Time:      time,
+			HitResult: result,
+			Action:    action,
+		}, player.score)
+	}
+}
+
+func (set *TaikoRuleSet) AddListener(listener func(cursor *graphics.Cursor, judgementResult JudgementResult, score Score)) {
+	set.hitListeners = append(set.hitListeners, listener)
+}
+
+func (set *TaikoRuleSet) AddPressListener(listener func(cursor *graphics.Cursor, time int64, action Action)) {
+	set.pressListeners = append(set.pressListeners, listener)
+}
+
+func (set *TaikoRuleSet) GetBeatMap() *beatmap.BeatMap {
+	return set.beatMap
+}
+
+func (set *TaikoRuleSet) GetObjects() []*Object {
+	return set.objects
+}
+
+func (set *TaikoRuleSet) GetScore(cursor *graphics.Cursor) Score {
+	return set.playerMap[cursor].score
+}
+
+func (set *TaikoRuleSet) GetPlayerDifficulty(cursor *graphics.Cursor) *difficulty.Difficulty {
+	return set.playerMap[cursor].diff
+}
+
+func (set *TaikoRuleSet) GetHitWindows(cursor *graphics.Cursor) HitWindows {
+	return set.playerMap[cursor].windows
+}
+
+func (set *TaikoRuleSet) GetKeys(cursor *graphics.Cursor) Action {
+	return set.playerMap[cursor].keys
+}
+
+// GetResult returns a judgement of a hit or swell, Ignore if it's not judged yet
+func (set *TaikoRuleSet) GetResult(cursor *graphics.Cursor, index int) HitResult {
+	return set.playerMap[cursor].states[index].result
+}
+
+func (set *TaikoRuleSet) GetSwellHits(cursor *graphics.Cursor, index int) int {
+	return set.playerMap[cursor].states[index].swellHits
+}
+
+func (set *TaikoRuleSet) IsEnded() bool {
+	return set.ended
+}
@@ -0,0 +1,121 @@
+package taiko
+
+import (
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/rulesets/osu"
+	"math"
+)
+
+type Score struct {
+	Score        int64
+	Accuracy     float64
+	Grade        osu.Grade
+	CurrentCombo uint
+	Combo        uint
+	Count300     uint
+	CountGeki    uint // strong greats
+	Count100     uint
+	CountKatu    uint // strong oks
+	CountMiss    uint
+
+	DrumRollTicks  uint
+	SwellsComplete uint
+
+	scoredObjects uint
+}
+
+// AddResult processes a judgement. Score is an approximation of osu!stable's ScoreV1, hit counts and accuracy should be exact.
+func (s *Score) AddResult(result HitResult, strong bool, diff *difficulty.Difficulty) {
+	switch result {
+	case Great:
+		s.Count300++
+	case Ok:
+		s.Count100++
+	case Miss:
+		s.CountMiss++
+	case StrongBonus:
+		if strong {
+			s.CountGeki++
+		} else {
+			s.CountKatu++
+		}
+	case DrumRollTick:
+		s.DrumRollTicks++
+	case SwellComplete:
+		s.SwellsComplete++
+	}
+
+	if result&BaseHits > 0 {
+		s.CurrentCombo++
+		s.Combo = max(s.Combo, s.CurrentCombo)
+	} else if result == Miss {
+		s.CurrentCombo = 0
+	}
+
+	if result.AffectsAcc() {
+		s.scoredObjects++
+	}
+
+	value := float64(result.ScoreValue())
+
+	if result&BaseHits > 0 {
+		value += math.Floor(value * min(float64(s.CurrentCombo/10), 10) / 10)
+	}
+
+	s.Score += int64(value * diff.GetScoreMultiplier())
+
+	s.updateAccuracy(diff)
+}
+
+// AddStrongBonus adds score for hitting a strong note with both keys, it's worth the same amount as the hit itself
+func (s *Score) AddStrongBonus(result HitResult, diff *difficulty.Difficulty) {
+	s.AddResult(StrongBonus, result == Great, diff)
+
+	value := float64(result.ScoreValue())
+	value += math.Floor(value * min(float64(s.CurrentCombo/10), 10) / 10)
+
+	s.Score += int64(value * diff.GetScoreMultiplier())
+}
+
+func (s *Score) updateAccuracy(diff *difficulty.Difficulty) {
+	if s.scoredObjects == 0 {
+		s.Accuracy = 1
+		s.Grade = osu.SS
+
+		if diff.CheckModActive(difficulty.Hidden | difficulty.Flashlight) {
+			s.Grade = osu.SSH
+		}
+
+		return
+	}
+
+	total := float64(s.scoredObjects)
+
+	s.Accuracy = (float64(s.Count300) + float64(s.Count100)*0.5) / total
+
+	ratio300 := float64(s.Count300) / total
+
+	switch {
+	case ratio300 == 1:
+		s.Grade = osu.SS
+	case ratio300 > 0.9 && s.CountMiss == 0:
+		s.Grade = osu.S
+	case ratio300 > 0.8 && s.CountMiss == 0 || ratio300 > 0.9:
+		s.Grade = osu.A
+	case ratio300 > 0.7 && s.CountMiss == 0 || ratio300 > 0.8:
+		s.Grade = osu.B
+	case ratio300 > 0.6:
+		s.Grade = osu.C
+	default:
+		s.Grade = osu.D
+	}
+
+	if diff.CheckModActive(difficulty.Hidden | difficulty.Flashlight) {
+		switch s.Grade {
+		case osu.S:
+			s.Grade = osu.SH
+		case osu.SS:
+			s.Grade = osu.SSH
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/wieku/danser-go/app/ffmpeg"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/app/status"
+	"github.com/wieku/danser-go/framework/goroutines"
+	"github.com/wieku/danser-go/framework/util"
+	"log"
+	"math"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// segmentEnv tells a worker process which part of the recording it renders, e.g. "2/4"
+const segmentEnv = "DANSER_SEGMENT"
+
+type recordSegment struct {
+	index int
+	count int
+}
+
+// segment is set in worker processes of a segmented recording
+var segment *recordSegment
+
+var segmentProcesses []*exec.Cmd
+var segmentMutex sync.Mutex
+
+func parseSegment(value string) *recordSegment {
+	var index, count int
+
+	if _, err := fmt.Sscanf(value, "%d/%d", &index, &count); err != nil || index < 1 || index > count {
+		panic(fmt.Sprintf("Invalid %s value: %q", segmentEnv, value))
+	}
+
+	return &recordSegment{index: index - 1, count: count}
+}
+
+// isLast returns true for the segment that runs until the end of the recording, it also encodes audio and exports judgements
+func (s *recordSegment) isLast() bool {
+	return s.index == s.count-1
+}
+
+// getSubframes returns the range of rendered frames (including motion blur subframes) encoded by this segment
+func (s *recordSegment) getSubframes(totalFrames, oversample int64) (first, end int64) {
+	first = totalFrames * int64(s.index) / int64(s.count) * oversample
+	end = math.MaxInt64
+
+	if !s.isLast() {
+		end = totalFrames * int64(s.index+1) / int64(s.count) * oversample
+	}
+
+	if first >= min(end, totalFrames*oversample) {
+		panic(fmt.Sprintf("Recording is too short to be split into %d segments", s.count))
+	}
+
+	return
+}
+
+type segmentMessage struct {
+	Type        string  `json:"type"`
+	Frames      int64   `json:"frames"`
+	TotalFrames int64   `json:"totalFrames"`
+	FPS         float64 `json:"fps"`
+	Speed       float64 `json:"speed"`
+	ETA         float64 `json:"eta"`
+	Code        int     `json:"code"`
+	Error       string  `json:"error"`
+}
+
+type segmentFailure struct {
+	index int
+	code  int
+	cause string
+}
+
+// runSegments renders the recording in separate danser processes at the same time and joins their output
+func runSegments(count int) {
+	handleAbort()
+
+	if output == "" {
+		output = "danser_" + time.Now().Format("2006-01-02_15-04-05")
+	}
+
+	ffmpeg.PrepareSegments(output)
+
+	executable, err := os.Executable()
+	if err != nil {
+		panic(err)
+	}
+
+	args := getSegmentArgs()
+
+	log.Println(fmt.Sprintf("Rendering %d segments at once...", count))
+
+	status.SetStage(status.StageEncoding)
+
+	var mutex sync.Mutex
+	var failure *segmentFailure
+
+	progress := make([]segmentMessage, count)
+
+	wg := &sync.WaitGroup{}
+
+	segmentMutex.Lock()
+
+	for i := 0; i < count; i++ {
+		cmd := exec.Command(executable, args...)
+		cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%d/%d", segmentEnv, i+1, count))
+
+		if settings.Recording.ShowFFmpegLogs {
+			cmd.Stderr = os.Stderr
+		}
+
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			panic(err)
+		}
+
+		if err = cmd.Start(); err != nil {
+			segmentMutex.Unlock()
+			killSegments()
+
+			panic(fmt.Sprintf("Failed to start segment %d: %s", i+1, err))
+		}
+
+		segmentProcesses = append(segmentProcesses, cmd)
+
+		wg.Add(1)
+
+		goroutines.Run(func() {
+			defer wg.Done()
+
+			var lastError *segmentMessage
+
+			sc := bufio.NewScanner(stdout)
+
+			for sc.Scan() {
+				var msg segmentMessage
+				if json.Unmarshal(sc.Bytes(), &msg) != nil {
+					continue
+				}
+
+				switch msg.Type {
+				case "progress":
+					mutex.Lock()
+					progress[i] = msg
+					mutex.Unlock()
+				case "error":
+					lastError = &msg
+				}
+			}
+
+			err := cmd.Wait()
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if err == nil {
+				progress[i].Frames = progress[i].TotalFrames
+				progress[i].FPS, progress[i].Speed, progress[i].ETA = 0, 0, 0
+
+				log.Println(fmt.Sprintf("Segment %d/%d finished", i+1, count))
+
+				return
+			}
+
+			if failure != nil { // Segment was killed because another one failed
+				return
+			}
+
+			failure = &segmentFailure{index: i, code: status.ExitError, cause: err.Error()}
+
+			if lastError != nil {
+				failure.code, failure.cause = lastError.Code, lastError.Error
+			}
+
+			goroutines.Run(killSegments)
+		})
+	}
+
+	segmentMutex.Unlock()
+
+	done := make(chan struct{})
+
+	goroutines.Run(func() {
+		wg.Wait()
+		close(done)
+	})
+
+	lastProgress := -1
+
+	for finished := false; !finished; {
+		select {
+		case <-done:
+			finished = true
+		case <-time.After(time.Second):
+		}
+
+		mutex.Lock()
+
+		var frames, totalFrames int64
+		var fps, speed, eta float64
+
+		started := true
+
+		for _, p := range progress {
+			started = started && p.TotalFrames > 0
+
+			frames += p.Frames
+			totalFrames += p.TotalFrames
+			fps += p.FPS
+			speed += p.Speed
+			eta = max(eta, p.ETA)
+		}
+
+		mutex.Unlock()
+
+		if !started || finished { // Segments report their length after simulating up to their first frame
+			continue
+		}
+
+		percent := min(float64(frames)/float64(totalFrames)*100, 100)
+
+		status.Progress(percent, frames, totalFrames, fps, speed, eta)
+
+		step := 5
+		if preciseProgress {
+			step = 1
+		}
+
+		if p := int(percent) / step * step; p != lastProgress {
+			log.Println(fmt.Sprintf("Progress: %d%%, Speed: %.2fx, ETA: %s", p, speed, util.FormatSeconds(int(eta))))
+
+			lastProgress = p
+		}
+	}
+
+	if failure != nil {
+		panic(status.Failf(failure.code, "Segment %d/%d failed: %s", failure.index+1, count, failure.cause))
+	}
+
+	ffmpeg.ConcatSegments(count)
+}
+
+func killSegments() {
+	segmentMutex.Lock()
+	defer segmentMutex.Unlock()
+
+	for _, cmd := range segmentProcesses {
+		_ = cmd.Process.Kill() // Fails harmlessly for processes that already exited
+	}
+}
+
+// getSegmentArgs returns flags danser was started with, adjusted for worker processes
+func getSegmentArgs() []string {
+	var args []string
+
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "segments", "status", "out", "nodbcheck", "noupdatecheck", "preciseprogress":
+			return
+		}
+
+		args = append(args, fmt.Sprintf("-%s=%s", f.Name, f.Value.String()))
+	})
+
+	// Parent process already checked the database and updates
+	return append(args, "-out="+output, "-nodbcheck", "-noupdatecheck", "-status=stdout")
+}
@@ -98,6 +98,7 @@ func initGameplay() *gameplay {
 			Align:            "CentreLeft",
 			ShowInResults:    true,
 			ShowPPComponents: false,
+			CompareVersion:   "",
 			Static:           false,
 		},
 		HitCounter: &hitCounter{
@@ -268,6 +269,7 @@ func initGameplay() *gameplay {
 		ShowHitLighting:         false,
 		FlashlightDim:           1,
 		PlayUsername:            "Guest",
+		SaveReplays:             true,
 		IgnoreFailsInReplays:    false,
 		PPVersion:               "latest",
 		LazerClassicScore:       false,
@@ -299,8 +301,9 @@ type gameplay struct {
 	ShowHitLighting         bool
 	FlashlightDim           float64
 	PlayUsername            string `liveedit:"false"`
+	SaveReplays             bool   `label:"Save replays in play mode" tooltip:"Passed plays are saved to osu! Replays directory, or to danser's replays directory if the former doesn't exist" liveedit:"false"`
 	IgnoreFailsInReplays    bool
-	PPVersion               string `liveedit:"false" label:"PP counter version" combo:"211112|2021-11-12 (First Xexxar),220930|2022-09-30 (current web),latest|2024 pp rework (latest)"`
+	PPVersion               string `liveedit:"false" label:"PP counter version" combo:"true" comboSrc:"PPVersionOptions"`
 	LazerClassicScore       bool   `label:"Use \"Classic\" score for osu!lazer plays"`
 }
 
@@ -381,10 +384,27 @@ type ppCounter struct {
 	Decimals         int    `max:"5"`
 	Align            string `combo:"TopLeft,Top,TopRight,Left,Centre,Right,BottomLeft,Bottom,BottomRight"`
 	ShowInResults    bool
-	ShowPPComponents bool `label:"Show PP breakdown"`
+	ShowPPComponents bool   `label:"Show PP breakdown"`
+	CompareVersion   string `label:"Compare with PP version" combo:"true" comboSrc:"PPCompareOptions" tooltip:"Shows PP of another version next to the main one, e.g. to compare a pp rework with live PP" liveedit:"false"`
 	Static           bool
 }
 
+// ppVersions holds "id|description" combo entries of pp versions, they are registered by the performance package
+var ppVersions []string
+
+// SetPPVersions sets pp versions available in settings, the newest one should be listed as "latest"
+func SetPPVersions(options []string) {
+	ppVersions = options
+}
+
+func (d *defaultsFactory) PPVersionOptions() []string {
+	return ppVersions
+}
+
+func (d *defaultsFactory) PPCompareOptions() []string {
+	return append([]string{"|Off"}, ppVersions...)
+}
+
 type hitCounter struct {
 	*hudElementPosition
 	Color            []*HSV  `json:",omitempty" new:"InitHSV" label:"Color list" skip:"true"`
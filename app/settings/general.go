@@ -15,6 +15,8 @@ func initGeneral() *general {
 		OsuSkinsDir:       filepath.Join(osuBaseDir, "Skins"),
 		OsuReplaysDir:     filepath.Join(osuBaseDir, "Replays"),
 		DiscordPresenceOn: true,
+		LiveServerOn:      false,
+		LiveServerPort:    24050,
 		UnpackOszFiles:    true,
 		VerboseImportLogs: false,
 	}
@@ -33,6 +35,12 @@ type general struct {
 	// Whether discord should show that danser is on
 	DiscordPresenceOn bool `label:"Discord Rich Presence"`
 
+	// Whether danser should publish current beatmap, scores and judgements on a local HTTP/WebSocket server for stream overlays
+	LiveServerOn bool `label:"Live state server" tooltip:"Serves JSON state at http://127.0.0.1:port/state and pushes updates to ws://127.0.0.1:port/ws"`
+
+	// Port of the live state server, it's bound to localhost only
+	LiveServerPort int `string:"true" min:"1" max:"65535" label:"Live state server port" showif:"LiveServerOn=true"`
+
 	// Whether danser should unpack .osz files in Songs folder, osu! may complain about it
 	UnpackOszFiles bool
 
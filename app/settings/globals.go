@@ -15,7 +15,11 @@ var SPEED = 1.0
 var PITCH = 1.0
 var TAG = 1
 var RECORD = false
+var HEADLESS = false
 var REPLAY = ""
+var PLAYMODE = 0
+var JUDGEMENTLOG = ""
+var TRANSPARENT = false
 var LOCALOFFSET = 0
 var PerfGraph = false
 var CallGraph = false
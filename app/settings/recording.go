@@ -19,6 +19,8 @@ func initRecording() *recording {
 		FrameHeight:    1080,
 		FPS:            60,
 		EncodingFPSCap: 0,
+		OutputMode:     "video",
+		Alpha:          false,
 		Encoder:        "libx264",
 		X264Settings: &x264Settings{
 			RateControl:       "crf",
@@ -99,9 +101,16 @@ func initRecording() *recording {
 			CustomOptions: "",
 		},
 		AudioFilters:   "",
+		AudioStems:     "off",
 		OutputDir:      "videos",
 		Container:      "mp4",
 		ShowFFmpegLogs: true,
+		Loudness: &loudness{
+			Enabled:       false,
+			TargetLUFS:    -14,
+			TruePeak:      -1,
+			LoudnessRange: 11,
+		},
 		MotionBlur: &motionblur{
 			Enabled:              false,
 			OversampleMultiplier: 16,
@@ -109,6 +118,10 @@ func initRecording() *recording {
 			BlendFunctionID:      27,
 			GaussWeightsMult:     1.5,
 		},
+		Thumbnail: &thumbnail{
+			Enabled: false,
+			Layout:  "",
+		},
 	}
 }
 
@@ -118,7 +131,10 @@ type recording struct {
 	FrameHeight         int                `min:"1" max:"17280"`
 	FPS                 int                `label:"FPS (PLEASE READ TOOLTIP)" string:"true" min:"1" max:"10727" tooltip:"IMPORTANT: If you plan to have a \"high fps\" video, use Motion Blur below instead of setting FPS to absurd numbers. Setting the value too high will result in a broken video!"`
 	EncodingFPSCap      int                `string:"true" min:"0" max:"10727" label:"Max Encoding FPS (Speed)" tooltip:"Limits the speed at which danser renders the video. If FPS is set to 60 and this option to 30, then it means 2 minute map will take at least 4 minutes to render"`
-	Encoder             string             `combo:"libx264|Software x264 (AVC),libx265|Software x265 (HEVC),h264_nvenc|NVIDIA NVENC H.264 (AVC),hevc_nvenc|NVIDIA NVENC H.265 (HEVC),av1_nvenc|NVIDIA NVENC AV1,h264_qsv|Intel QuickSync H.264 (AVC),hevc_qsv|Intel QuickSync H.265 (HEVC)" comboSrc:"EncoderOptions" tooltip:"Hardware encoding with AMD GPUs is not supported because software encoding provides better performance and results"`
+	OutputMode          string             `combo:"video|Video,ffv1|Lossless video (FFV1 in MKV),prores|ProRes 4444 (MOV),vp9|VP9 (WebM),png|PNG image sequence" tooltip:"Modes other than Video write frames and a separate WAV of the mixer output, meant for editing in external tools. They ignore encoder, audio codec and container settings"`
+	Alpha               bool               `label:"Keep alpha channel" showif:"OutputMode=ffv1,prores,vp9,png" tooltip:"Exported frames keep the alpha channel so they can be composited over other footage"`
+	Transparent         bool               `label:"Transparent background" showif:"Alpha=true" tooltip:"Skips background image, video, storyboard and dim, only the playfield, cursors and HUD are rendered over a transparent background"`
+	Encoder             string             `showif:"OutputMode=video" combo:"libx264|Software x264 (AVC),libx265|Software x265 (HEVC),h264_nvenc|NVIDIA NVENC H.264 (AVC),hevc_nvenc|NVIDIA NVENC H.265 (HEVC),av1_nvenc|NVIDIA NVENC AV1,h264_qsv|Intel QuickSync H.264 (AVC),hevc_qsv|Intel QuickSync H.265 (HEVC)" comboSrc:"EncoderOptions" tooltip:"Hardware encoding with AMD GPUs is not supported because software encoding provides better performance and results"`
 	X264Settings        *x264Settings      `json:"libx264" label:"Software x264 (AVC) Settings" showif:"Encoder=libx264"`
 	X265Settings        *x265Settings      `json:"libx265" label:"Software x265 (HEVC) Settings" showif:"Encoder=libx265"`
 	H264NvencSettings   *h264NvencSettings `json:"h264_nvenc" label:"NVIDIA NVENC H.264 (AVC) Settings" showif:"Encoder=h264_nvenc"`
@@ -129,7 +145,7 @@ type recording struct {
 	CustomSettings      *custom            `json:"custom" label:"Custom Encoder Settings" showif:"Encoder=!"`
 	PixelFormat         string             `combo:"yuv420p|I420,yuv444p|I444,nv12|NV12,nv21|NV21" showif:"Encoder=!h264_qsv,!hevc_qsv"`
 	Filters             string             `label:"FFmpeg Video Filters"`
-	AudioCodec          string             `combo:"aac|AAC,libmp3lame|MP3,libopus|OPUS,flac|FLAC"`
+	AudioCodec          string             `showif:"OutputMode=video" combo:"aac|AAC,libmp3lame|MP3,libopus|OPUS,flac|FLAC"`
 	AACSettings         *aacSettings       `json:"aac" label:"AAC Settings" showif:"AudioCodec=aac"`
 	MP3Settings         *mp3Settings       `json:"libmp3lame" label:"MP3 Settings" showif:"AudioCodec=libmp3lame"`
 	OPUSSettings        *opusSettings      `json:"libopus" label:"OPUS Settings" showif:"AudioCodec=libopus"`
@@ -137,11 +153,18 @@ type recording struct {
 	CustomAudioSettings *custom            `json:"customAudio" label:"Custom Audio Settings" showif:"AudioCodec=!"`
 	//AudioOptions        string             `label:"Audio Encoder Options"`
 	AudioFilters   string `label:"FFmpeg Audio Filters"`
+	Loudness       *loudness
+	AudioStems     string `combo:"off|Off,tracks|Additional audio tracks,wav|Separate WAV files" tooltip:"Records music, hitsounds, storyboard samples and UI sounds separately as well. Additional tracks need Video output mode with MKV container, separate WAV files are written otherwise. Audio filters are not applied to stems"`
 	OutputDir      string `path:"Select video output directory"`
-	Container      string `combo:"mp4,mkv"`
+	Container      string `combo:"mp4,mkv" showif:"OutputMode=video"`
+	PartLength     int    `label:"Resumable part length (seconds)" string:"true" min:"0" max:"3600" showif:"OutputMode=!png" tooltip:"Encodes the video in parts of given length. If recording gets interrupted, running it again with the same output name and settings renders only unfinished parts. 0 encodes the video as a single file"`
 	ShowFFmpegLogs bool
 	MotionBlur     *motionblur
 
+	ExportJudgements bool `label:"Export judgements" tooltip:"Saves every judgement as a JSON line in a .jsonl file next to the video or screenshot"`
+
+	Thumbnail *thumbnail
+
 	outDir *string
 }
 
@@ -181,6 +204,13 @@ func (g *recording) GetAudioOptions() EncoderOptions {
 	}
 }
 
+// HasAlpha returns true if the output mode supports alpha and it's enabled
+func (g *recording) HasAlpha() bool {
+	mode := strings.ToLower(strings.TrimSpace(g.OutputMode))
+
+	return g.Alpha && mode != "" && mode != "video"
+}
+
 func (g *recording) GetOutputDir() string {
 	if g.outDir == nil {
 		dir := filepath.Join(env.DataDir(), g.OutputDir)
@@ -204,6 +234,18 @@ type motionblur struct {
 	BlendWeights         *blendWeights `json:",omitempty"` // Deprecated
 }
 
+type thumbnail struct {
+	Enabled bool   `label:"Save thumbnail" tooltip:"Renders a thumbnail with map background, title, difficulty, mods, stars and the final score. It's saved next to the video or screenshot as <name>_thumbnail.png"`
+	Layout  string `label:"Layout" file:"Select thumbnail layout" filter:"JSON file (*.json)|json" showif:"Enabled=true" tooltip:"JSON file with text and image slots, see README. Default layout is used if empty"`
+}
+
+type loudness struct {
+	Enabled       bool    `label:"Normalize loudness" tooltip:"Measures loudness of the whole recording after it's rendered and adjusts the volume to the target (EBU R128) with a true peak limiter. Audio stems are not normalized"`
+	TargetLUFS    float64 `label:"Target loudness (LUFS)" string:"true" min:"-70" max:"-5" showif:"Enabled=true" tooltip:"Most video platforms normalize to -14 LUFS"`
+	TruePeak      float64 `label:"True peak limit (dBTP)" string:"true" min:"-9" max:"0" showif:"Enabled=true"`
+	LoudnessRange float64 `label:"Loudness range (LU)" string:"true" min:"1" max:"20" showif:"Enabled=true" tooltip:"If the recording has a bigger dynamic range, it gets compressed instead of only changing the volume"`
+}
+
 type blendWeights struct {
 	UseManualWeights bool
 	ManualWeights    string  `showif:"UseManualWeights=true"`
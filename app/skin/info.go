@@ -68,6 +68,8 @@ type SkinInfo struct {
 	//combo font settings
 	ComboPrefix  string
 	ComboOverlap float64
+
+	Mania []*ManiaInfo
 }
 
 func newDefaultInfo() *SkinInfo {
@@ -205,15 +207,42 @@ func LoadInfo(path string, local bool) (*SkinInfo, error) {
 
 	colorsI := make([]colorI, 0)
 
+	var section string
+	var maniaInfo *ManiaInfo
+
 	for scanner.Scan() {
 		line := scanner.Text()
 
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			section = trimmed[1 : len(trimmed)-1]
+			maniaInfo = nil
+
+			continue
+		}
+
 		tokenized := tokenize(line, ":")
 
 		if tokenized == nil {
 			continue
 		}
 
+		if section == "Mania" {
+			if tokenized[0] == "Keys" {
+				keys, err := strconv.Atoi(tokenized[1])
+				if err != nil || keys < 1 || keys > 18 {
+					log.Println("SkinManager: Invalid mania key count:", tokenized[1])
+					maniaInfo = nil
+				} else {
+					maniaInfo = newDefaultManiaInfo(keys)
+					info.Mania = append(info.Mania, maniaInfo)
+				}
+			} else if maniaInfo != nil {
+				maniaInfo.parse(tokenized[0], tokenized[1])
+			}
+
+			continue
+		}
+
 		switch tokenized[0] {
 		case "Name":
 			info.Name = tokenized[1]
@@ -0,0 +1,222 @@
+package skin
+
+import (
+	"github.com/wieku/danser-go/framework/math/color"
+	"strconv"
+	"strings"
+)
+
+// ManiaInfo holds skin.ini's [Mania] section for one key count, sizes are in osu!pixels of 480px tall screen
+type ManiaInfo struct {
+	Keys int
+
+	ColumnStart     float64
+	ColumnRight     float64
+	ColumnSpacing   []float64
+	ColumnWidth     []float64
+	ColumnLineWidth []float64
+
+	HitPosition   float64
+	LightPosition float64
+	ScorePosition float64
+	ComboPosition float64
+
+	JudgementLine  bool
+	UpsideDown     bool
+	KeysUnderNotes bool
+
+	Colours             []color.Color
+	ColoursLight        []color.Color
+	ColourColumnLine    color.Color
+	ColourJudgementLine color.Color
+	ColourHold          color.Color
+
+	KeyImages      []string
+	KeyImagesDown  []string
+	NoteImages     []string
+	NoteImagesHead []string
+	NoteImagesBody []string
+	NoteImagesTail []string
+
+	StageLeft   string
+	StageRight  string
+	StageBottom string
+	StageHint   string
+	StageLight  string
+}
+
+func newDefaultManiaInfo(keys int) *ManiaInfo {
+	info := &ManiaInfo{
+		Keys:                keys,
+		ColumnStart:         136,
+		ColumnRight:         19,
+		ColumnSpacing:       make([]float64, max(0, keys-1)),
+		ColumnWidth:         make([]float64, keys),
+		ColumnLineWidth:     make([]float64, keys+1),
+		HitPosition:         402,
+		LightPosition:       413,
+		ScorePosition:       325,
+		ComboPosition:       111,
+		JudgementLine:       true,
+		Colours:             make([]color.Color, keys),
+		ColoursLight:        make([]color.Color, keys),
+		ColourColumnLine:    color.NewL(1),
+		ColourJudgementLine: color.NewL(1),
+		ColourHold:          color.NewIRGB(255, 191, 51),
+		KeyImages:           make([]string, keys),
+		KeyImagesDown:       make([]string, keys),
+		NoteImages:          make([]string, keys),
+		NoteImagesHead:      make([]string, keys),
+		NoteImagesBody:      make([]string, keys),
+		NoteImagesTail:      make([]string, keys),
+		StageLeft:           "mania-stage-left",
+		StageRight:          "mania-stage-right",
+		StageBottom:         "mania-stage-bottom",
+		StageHint:           "mania-stage-hint",
+		StageLight:          "mania-stage-light",
+	}
+
+	for i := 0; i < keys; i++ {
+		columnType := GetManiaColumnType(i, keys)
+
+		info.ColumnWidth[i] = 30
+		info.ColumnLineWidth[i] = 2
+		info.Colours[i] = color.NewL(0)
+		info.ColoursLight[i] = color.NewIRGB(55, 255, 255)
+
+		info.KeyImages[i] = "mania-key" + columnType
+		info.KeyImagesDown[i] = "mania-key" + columnType + "D"
+		info.NoteImages[i] = "mania-note" + columnType
+		info.NoteImagesHead[i] = "mania-note" + columnType + "H"
+		info.NoteImagesBody[i] = "mania-note" + columnType + "L"
+		info.NoteImagesTail[i] = "mania-note" + columnType + "T"
+	}
+
+	info.ColumnLineWidth[keys] = 2
+
+	return info
+}
+
+// GetManiaColumnType returns osu!stable's default texture variant of the column, columns are mirrored with a special one in the middle
+func GetManiaColumnType(column, keys int) string {
+	half := keys / 2
+
+	if keys%2 == 1 && column == half {
+		return "S"
+	}
+
+	if column >= half+keys%2 {
+		column = keys - 1 - column
+	}
+
+	if column%2 == 0 {
+		return "1"
+	}
+
+	return "2"
+}
+
+func (info *ManiaInfo) parse(key, value string) {
+	switch key {
+	case "ColumnStart":
+		info.ColumnStart = ParseFloat(value, key)
+	case "ColumnRight":
+		info.ColumnRight = ParseFloat(value, key)
+	case "ColumnSpacing":
+		parseFloatList(value, key, info.ColumnSpacing)
+	case "ColumnWidth":
+		parseFloatList(value, key, info.ColumnWidth)
+	case "ColumnLineWidth":
+		parseFloatList(value, key, info.ColumnLineWidth)
+	case "HitPosition":
+		info.HitPosition = ParseFloat(value, key)
+	case "LightPosition":
+		info.LightPosition = ParseFloat(value, key)
+	case "ScorePosition":
+		info.ScorePosition = ParseFloat(value, key)
+	case "ComboPosition":
+		info.ComboPosition = ParseFloat(value, key)
+	case "JudgementLine":
+		ParseBool(value, key, &info.JudgementLine)
+	case "UpsideDown":
+		ParseBool(value, key, &info.UpsideDown)
+	case "KeysUnderNotes":
+		ParseBool(value, key, &info.KeysUnderNotes)
+	case "ColourColumnLine":
+		info.ColourColumnLine = ParseColor(value, key)
+	case "ColourJudgementLine":
+		info.ColourJudgementLine = ParseColor(value, key)
+	case "ColourHold":
+		info.ColourHold = ParseColor(value, key)
+	case "StageLeft":
+		info.StageLeft = value
+	case "StageRight":
+		info.StageRight = value
+	case "StageBottom":
+		info.StageBottom = value
+	case "StageHint":
+		info.StageHint = value
+	case "StageLight":
+		info.StageLight = value
+	default:
+		info.parseColumnValue(key, value)
+	}
+}
+
+// parseColumnValue parses per-column options. Colours are indexed from 1, images from 0.
+func (info *ManiaInfo) parseColumnValue(key, value string) {
+	if index, ok := getColumnIndex(key, "ColourLight", 1); ok && index < info.Keys {
+		info.ColoursLight[index] = ParseColor(value, key)
+	} else if index, ok = getColumnIndex(key, "Colour", 1); ok && index < info.Keys {
+		info.Colours[index] = ParseColor(value, key)
+	} else if index, ok = getColumnIndex(strings.TrimSuffix(key, "D"), "KeyImage", 0); ok && strings.HasSuffix(key, "D") && index < info.Keys {
+		info.KeyImagesDown[index] = value
+	} else if index, ok = getColumnIndex(key, "KeyImage", 0); ok && index < info.Keys {
+		info.KeyImages[index] = value
+	} else if index, ok = getColumnIndex(key, "NoteImage", 0); ok && index < info.Keys {
+		info.NoteImages[index] = value
+	} else if index, ok = getColumnIndex(key[:max(0, len(key)-1)], "NoteImage", 0); ok && index < info.Keys {
+		switch key[len(key)-1] {
+		case 'H':
+			info.NoteImagesHead[index] = value
+		case 'L':
+			info.NoteImagesBody[index] = value
+		case 'T':
+			info.NoteImagesTail[index] = value
+		}
+	}
+}
+
+func getColumnIndex(key, prefix string, base int) (int, bool) {
+	if !strings.HasPrefix(key, prefix) {
+		return 0, false
+	}
+
+	index, err := strconv.Atoi(strings.TrimPrefix(key, prefix))
+	if err != nil || index-base < 0 {
+		return 0, false
+	}
+
+	return index - base, true
+}
+
+func parseFloatList(text, errType string, target []float64) {
+	for i, v := range strings.Split(text, ",") {
+		if i >= len(target) {
+			break
+		}
+
+		target[i] = ParseFloat(strings.TrimSpace(v), errType)
+	}
+}
+
+// GetManiaInfo returns skin's configuration for given key count, or osu!'s defaults if skin doesn't have one
+func (info *SkinInfo) GetManiaInfo(keys int) *ManiaInfo {
+	for _, m := range info.Mania {
+		if m.Keys == keys {
+			return m
+		}
+	}
+
+	return newDefaultManiaInfo(keys)
+}
@@ -122,6 +122,43 @@ func tryLoadSkin(name, fallbackName string) {
 	}
 }
 
+// Unload drops the current skin and its caches and frees its textures, skin selected in settings is loaded on next use.
+// It has to be called on the main thread when nothing uses textures of the skin anymore. Samples retrieved before stay valid.
+func Unload() {
+	textureLock.Lock()
+	fontLock.Lock()
+	soundLock.Lock()
+
+	defer textureLock.Unlock()
+	defer fontLock.Unlock()
+	defer soundLock.Unlock()
+
+	info = nil
+
+	skinPathCache = nil
+	fallbackPathCache = nil
+
+	for rg := range sourceCache {
+		if tx, ok := rg.Texture.(*texture.TextureSingle); ok {
+			tx.Dispose()
+		}
+	}
+
+	// Textures of the default skin share the atlas, so they are loaded again as well
+	if atlas != nil {
+		atlas.Dispose()
+		atlas = nil
+	}
+
+	clear(animationCache)
+	clear(skinCache)
+	clear(fallbackCache)
+	clear(defaultCache)
+	clear(sourceCache)
+	clear(fontCache)
+	clear(sampleCache)
+}
+
 func GetInfo() *SkinInfo {
 	checkInit()
 	return info
@@ -492,7 +529,10 @@ func AddBeatmapColor(data []string) {
 	})
 }
 
+// FinishBeatmapColors applies colors added since the last call, so maps loaded one after another don't share them
 func FinishBeatmapColors() {
+	beatmapColors = nil
+
 	if len(beatmapColorsI) > 0 {
 		sort.SliceStable(beatmapColorsI, func(i, j int) bool {
 			return beatmapColorsI[i].index <= beatmapColorsI[j].index
@@ -504,6 +544,8 @@ func FinishBeatmapColors() {
 			beatmapColors = append(beatmapColors, c.color)
 		}
 	}
+
+	beatmapColorsI = nil
 }
 
 func GetColors() []color.Color {
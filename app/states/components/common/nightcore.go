@@ -26,10 +26,10 @@ type NightcoreProcessor struct {
 func NewNightcoreProcessor() *NightcoreProcessor {
 	proc := &NightcoreProcessor{
 		BeatSynced:   NewBeatSynced(),
-		hatSample:    skin.GetSample("nightcore-hat"),
-		clapSample:   skin.GetSample("nightcore-clap"),
-		kickSample:   skin.GetSample("nightcore-kick"),
-		finishSample: skin.GetSample("nightcore-finish"),
+		hatSample:    skin.GetSample("nightcore-hat").WithStem(bass.StemMusic),
+		clapSample:   skin.GetSample("nightcore-clap").WithStem(bass.StemMusic),
+		kickSample:   skin.GetSample("nightcore-kick").WithStem(bass.StemMusic),
+		finishSample: skin.GetSample("nightcore-finish").WithStem(bass.StemMusic),
 	}
 
 	proc.Divisor = nightCoreDivisor
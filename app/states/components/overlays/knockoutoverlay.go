@@ -186,7 +186,7 @@ func NewKnockoutOverlay(replayController *dance.ReplayController) *KnockoutOverl
 		}
 	}
 
-	replayController.GetRuleset().SetListener(overlay.hitReceived)
+	replayController.GetRuleset().AddListener(overlay.hitReceived)
 
 	sortFunc := func(number int64, instantSort bool) {
 		alive := 0
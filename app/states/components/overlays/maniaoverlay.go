@@ -0,0 +1,542 @@
+package overlays
+
+import (
+	"fmt"
+	"github.com/wieku/danser-go/app/audio"
+	camera2 "github.com/wieku/danser-go/app/bmath/camera"
+	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/app/rulesets/mania"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/app/skin"
+	"github.com/wieku/danser-go/framework/bass"
+	"github.com/wieku/danser-go/framework/graphics/batch"
+	"github.com/wieku/danser-go/framework/graphics/font"
+	"github.com/wieku/danser-go/framework/graphics/texture"
+	"github.com/wieku/danser-go/framework/math/animation"
+	color2 "github.com/wieku/danser-go/framework/math/color"
+	"github.com/wieku/danser-go/framework/math/vector"
+	"math"
+)
+
+const (
+	// maniaScrollTime is a time in which a note travels to the hit position, it matches osu!'s scroll speed 20
+	maniaScrollTime = 11485.0 / 20
+
+	maniaSkinHeight = 480.0
+
+	maniaJudgementTime = 300.0
+)
+
+var (
+	maniaNoteColors = map[string]color2.Color{
+		"1": color2.NewL(0.95),
+		"2": color2.NewIRGB(80, 170, 255),
+		"S": color2.NewIRGB(255, 200, 40),
+	}
+)
+
+type maniaColumn struct {
+	x     float64
+	width float64
+
+	noteColor color2.Color
+
+	key     *texture.TextureRegion
+	keyDown *texture.TextureRegion
+
+	note     *texture.TextureRegion
+	noteHead *texture.TextureRegion
+	noteBody *texture.TextureRegion
+	noteTail *texture.TextureRegion
+
+	light *animation.Glider
+}
+
+type ManiaOverlay struct {
+	ruleset *mania.ManiaRuleSet
+	cursor  *graphics.Cursor
+
+	info *skin.ManiaInfo
+
+	music bass.ITrack
+
+	camera       *camera2.Camera
+	ScaledWidth  float64
+	ScaledHeight float64
+
+	scale float64
+
+	stageLeft  float64
+	stageRight float64
+	hitY       float64
+
+	columns []*maniaColumn
+
+	time float64
+
+	audioDisabled bool
+
+	lastJudgement     mania.HitResult
+	lastJudgementTime float64
+
+	judgementTextures map[mania.HitResult]*texture.TextureRegion
+
+	stageLeftTexture  *texture.TextureRegion
+	stageRightTexture *texture.TextureRegion
+	stageHintTexture  *texture.TextureRegion
+
+	scoreGlider    *animation.TargetGlider
+	accuracyGlider *animation.TargetGlider
+
+	hudFont   *font.Font
+	scoreFont *font.Font
+	comboFont *font.Font
+}
+
+func NewManiaOverlay(ruleset *mania.ManiaRuleSet, cursor *graphics.Cursor) *ManiaOverlay {
+	loadFonts()
+
+	overlay := &ManiaOverlay{
+		ruleset: ruleset,
+		cursor:  cursor,
+		info:    skin.GetInfo().GetManiaInfo(ruleset.GetKeyCount()),
+	}
+
+	overlay.ScaledHeight = 768
+	overlay.ScaledWidth = settings.Graphics.GetAspectRatio() * overlay.ScaledHeight
+
+	overlay.camera = camera2.NewCamera()
+	overlay.camera.SetViewportF(0, int(overlay.ScaledHeight), int(overlay.ScaledWidth), 0)
+	overlay.camera.Update()
+
+	overlay.scale = overlay.ScaledHeight / maniaSkinHeight
+
+	overlay.initColumns()
+
+	overlay.judgementTextures = map[mania.HitResult]*texture.TextureRegion{
+		mania.Hit320: skin.GetTexture("mania-hit300g"),
+		mania.Hit300: skin.GetTexture("mania-hit300"),
+		mania.Hit200: skin.GetTexture("mania-hit200"),
+		mania.Hit100: skin.GetTexture("mania-hit100"),
+		mania.Hit50:  skin.GetTexture("mania-hit50"),
+		mania.Miss:   skin.GetTexture("mania-hit0"),
+	}
+
+	overlay.stageLeftTexture = skin.GetTexture(overlay.info.StageLeft)
+	overlay.stageRightTexture = skin.GetTexture(overlay.info.StageRight)
+	overlay.stageHintTexture = skin.GetTexture(overlay.info.StageHint)
+
+	overlay.scoreGlider = animation.NewTargetGlider(0, 0)
+	overlay.accuracyGlider = animation.NewTargetGlider(100, 2)
+
+	overlay.hudFont = font.GetFont("HUDFont")
+	overlay.scoreFont = skin.GetFont("score")
+	overlay.comboFont = skin.GetFont("combo")
+
+	ruleset.AddListener(overlay.hitReceived)
+	ruleset.AddPressListener(overlay.pressReceived)
+
+	return overlay
+}
+
+// initColumns lays out the stage. It's always centered, so skin's ColumnStart and ColumnRight are not used.
+func (overlay *ManiaOverlay) initColumns() {
+	keys := overlay.ruleset.GetKeyCount()
+
+	totalWidth := 0.0
+
+	for i := 0; i < keys; i++ {
+		totalWidth += overlay.info.ColumnWidth[i]
+
+		if i < keys-1 {
+			totalWidth += overlay.info.ColumnSpacing[i]
+		}
+	}
+
+	x := (overlay.ScaledWidth - totalWidth*overlay.scale) / 2
+
+	overlay.stageLeft = x
+	overlay.stageRight = x + totalWidth*overlay.scale
+
+	overlay.hitY = overlay.info.HitPosition * overlay.scale
+
+	overlay.columns = make([]*maniaColumn, keys)
+
+	for i := 0; i < keys; i++ {
+		column := &maniaColumn{
+			x:        x,
+			width:    overlay.info.ColumnWidth[i] * overlay.scale,
+			key:      skin.GetTexture(overlay.info.KeyImages[i]),
+			keyDown:  skin.GetTexture(overlay.info.KeyImagesDown[i]),
+			note:     skin.GetTexture(overlay.info.NoteImages[i]),
+			noteHead: skin.GetTexture(overlay.info.NoteImagesHead[i]),
+			noteBody: skin.GetTexture(overlay.info.NoteImagesBody[i]),
+			noteTail: skin.GetTexture(overlay.info.NoteImagesTail[i]),
+			light:    animation.NewGlider(0),
+		}
+
+		column.noteColor = maniaNoteColors[skin.GetManiaColumnType(i, keys)]
+
+		if column.noteHead == nil {
+			column.noteHead = column.note
+		}
+
+		overlay.columns[i] = column
+
+		x += column.width
+
+		if i < keys-1 {
+			x += overlay.info.ColumnSpacing[i] * overlay.scale
+		}
+	}
+}
+
+func (overlay *ManiaOverlay) hitReceived(_ *graphics.Cursor, judgementResult mania.JudgementResult, score mania.Score) {
+	overlay.scoreGlider.SetValue(float64(score.Score), settings.Gameplay.Score.StaticScore)
+	overlay.accuracyGlider.SetValue(score.Accuracy*100, settings.Gameplay.Score.StaticAccuracy)
+
+	overlay.lastJudgement = judgementResult.HitResult
+	overlay.lastJudgementTime = float64(judgementResult.Time)
+}
+
+func (overlay *ManiaOverlay) pressReceived(_ *graphics.Cursor, time int64, column int, pressed bool, note *mania.Note) {
+	light := overlay.columns[column].light
+
+	light.Reset()
+
+	if pressed {
+		light.SetValue(1)
+	} else {
+		light.AddEventS(float64(time), float64(time)+100, 1, 0)
+	}
+
+	if !pressed || note == nil || overlay.audioDisabled {
+		return
+	}
+
+	hs := note.HitSound
+
+	audio.PlaySample(hs.Info.SampleSet, hs.Info.AdditionSet, hs.Sample, hs.Info.CustomIndex, hs.Info.CustomVolume, note.Number, 256)
+}
+
+func (overlay *ManiaOverlay) Update(time float64) {
+	overlay.time = time
+
+	for _, c := range overlay.columns {
+		c.light.Update(time)
+	}
+
+	overlay.scoreGlider.Update(time)
+	overlay.accuracyGlider.Update(time)
+}
+
+func (overlay *ManiaOverlay) SetMusic(music bass.ITrack) {
+	overlay.music = music
+}
+
+func (overlay *ManiaOverlay) DrawBackground(_ *batch.QuadBatch, _ []color2.Color, _ float64) {}
+
+func (overlay *ManiaOverlay) DrawBeforeObjects(_ *batch.QuadBatch, _ []color2.Color, _ float64) {}
+
+func (overlay *ManiaOverlay) DrawNormal(_ *batch.QuadBatch, _ []color2.Color, _ float64) {}
+
+func (overlay *ManiaOverlay) DrawHUD(batch *batch.QuadBatch, _ []color2.Color, alpha float64) {
+	prev := batch.Projection
+	batch.SetCamera(overlay.camera.GetProjectionView())
+	batch.ResetTransform()
+
+	overlay.drawStage(batch, alpha)
+
+	if overlay.info.KeysUnderNotes {
+		overlay.drawKeys(batch, alpha)
+		overlay.drawNotes(batch, alpha)
+	} else {
+		overlay.drawNotes(batch, alpha)
+		overlay.drawKeys(batch, alpha)
+	}
+
+	overlay.drawJudgement(batch, alpha)
+	overlay.drawScore(batch, alpha)
+
+	batch.ResetTransform()
+	batch.SetCamera(prev)
+}
+
+func (overlay *ManiaOverlay) drawStage(batch *batch.QuadBatch, alpha float64) {
+	for i, c := range overlay.columns {
+		bg := overlay.info.Colours[i]
+		setColorAlpha(batch, bg, alpha*float64(bg.A)*0.85)
+		overlay.drawRect(batch, c.x, 0, c.width, overlay.ScaledHeight)
+
+		if light := c.light.GetValue(); light > 0.001 {
+			lightColor := overlay.info.ColoursLight[i]
+			setColorAlpha(batch, lightColor, alpha*light*0.25)
+			overlay.drawRect(batch, c.x, overlay.hitY-overlay.ScaledHeight*0.3, c.width, overlay.ScaledHeight*0.3)
+		}
+	}
+
+	setColorAlpha(batch, overlay.info.ColourColumnLine, alpha)
+
+	for i, c := range overlay.columns {
+		if w := overlay.info.ColumnLineWidth[i] * overlay.scale / 2; w > 0 {
+			overlay.drawRect(batch, c.x-w/2, 0, w, overlay.ScaledHeight)
+		}
+	}
+
+	if w := overlay.info.ColumnLineWidth[len(overlay.columns)] * overlay.scale / 2; w > 0 {
+		overlay.drawRect(batch, overlay.stageRight-w/2, 0, w, overlay.ScaledHeight)
+	}
+
+	batch.SetColor(1, 1, 1, alpha)
+
+	overlay.drawTextureV(batch, overlay.stageLeftTexture, overlay.stageLeft, 0, 0, overlay.ScaledHeight, vector.TopRight)
+	overlay.drawTextureV(batch, overlay.stageRightTexture, overlay.stageRight, 0, 0, overlay.ScaledHeight, vector.TopLeft)
+
+	if overlay.stageHintTexture != nil {
+		overlay.drawTextureV(batch, overlay.stageHintTexture, (overlay.stageLeft+overlay.stageRight)/2, overlay.hitY, overlay.stageRight-overlay.stageLeft, 0, vector.Centre)
+	} else if overlay.info.JudgementLine {
+		setColorAlpha(batch, overlay.info.ColourJudgementLine, alpha)
+		overlay.drawRect(batch, overlay.stageLeft, overlay.hitY-overlay.scale/2, overlay.stageRight-overlay.stageLeft, overlay.scale)
+	}
+}
+
+func (overlay *ManiaOverlay) drawKeys(batch *batch.QuadBatch, alpha float64) {
+	for i, c := range overlay.columns {
+		pressed := overlay.ruleset.IsPressed(overlay.cursor, i)
+
+		tex := c.key
+		if pressed && c.keyDown != nil {
+			tex = c.keyDown
+		}
+
+		if tex != nil {
+			batch.SetColor(1, 1, 1, alpha)
+			overlay.drawTextureV(batch, tex, c.x+c.width/2, overlay.ScaledHeight, c.width, 0, vector.BottomCentre)
+
+			continue
+		}
+
+		setColorAlpha(batch, color2.NewL(0.15), alpha)
+		overlay.drawRect(batch, c.x, overlay.hitY, c.width, overlay.ScaledHeight-overlay.hitY)
+
+		if pressed {
+			setColorAlpha(batch, overlay.info.ColoursLight[i], alpha*0.8)
+			overlay.drawRect(batch, c.x+c.width*0.15, overlay.hitY+c.width*0.3, c.width*0.7, c.width*0.7)
+		}
+	}
+}
+
+func (overlay *ManiaOverlay) drawNotes(batch *batch.QuadBatch, alpha float64) {
+	notes := overlay.ruleset.GetNotes()
+
+	// Scroll speed is constant in real time
+	timeRange := maniaScrollTime * overlay.ruleset.GetPlayerDifficulty(overlay.cursor).GetSpeed()
+
+	for i, note := range notes {
+		if note.StartTime > overlay.time+timeRange {
+			break
+		}
+
+		if note.EndTime < overlay.time-timeRange*0.5 {
+			continue
+		}
+
+		head, tail, holding := overlay.ruleset.GetNoteState(overlay.cursor, i)
+
+		c := overlay.columns[note.Column]
+
+		noteAlpha := alpha
+		if head == mania.Miss || (note.Hold && head != mania.Ignore && !holding && tail == mania.Ignore) {
+			noteAlpha *= 0.4
+		}
+
+		if !note.Hold {
+			if head&mania.BaseHits > 0 {
+				continue
+			}
+
+			overlay.drawNote(batch, c, c.note, overlay.getY(note.StartTime, timeRange), noteAlpha)
+
+			continue
+		}
+
+		if tail != mania.Ignore {
+			continue
+		}
+
+		headY := overlay.getY(note.StartTime, timeRange)
+		if holding {
+			headY = overlay.hitY
+		}
+
+		tailY := overlay.getY(note.EndTime, timeRange)
+
+		if c.noteBody != nil {
+			batch.SetColor(1, 1, 1, noteAlpha)
+			overlay.drawTextureStretched(batch, c.noteBody, c.x, tailY, c.width, headY-tailY)
+		} else {
+			setColorAlpha(batch, overlay.info.ColourHold, noteAlpha*0.8)
+			overlay.drawRect(batch, c.x+c.width*0.1, tailY, c.width*0.8, headY-tailY)
+		}
+
+		if c.noteTail != nil {
+			overlay.drawNote(batch, c, c.noteTail, tailY, noteAlpha)
+		} else {
+			overlay.drawNote(batch, c, c.noteHead, tailY, noteAlpha)
+		}
+
+		overlay.drawNote(batch, c, c.noteHead, headY, noteAlpha)
+	}
+}
+
+func (overlay *ManiaOverlay) getY(time, timeRange float64) float64 {
+	y := overlay.hitY - (time-overlay.time)/timeRange*overlay.hitY
+
+	if overlay.info.UpsideDown {
+		return overlay.ScaledHeight - y
+	}
+
+	return y
+}
+
+func (overlay *ManiaOverlay) drawNote(batch *batch.QuadBatch, c *maniaColumn, tex *texture.TextureRegion, y, alpha float64) {
+	if tex != nil {
+		batch.SetColor(1, 1, 1, alpha)
+		overlay.drawTextureV(batch, tex, c.x+c.width/2, y, c.width, 0, vector.BottomCentre)
+
+		return
+	}
+
+	height := c.width * 0.4
+
+	setColorAlpha(batch, c.noteColor, alpha)
+	overlay.drawRect(batch, c.x, y-height, c.width, height)
+}
+
+func (overlay *ManiaOverlay) drawJudgement(batch *batch.QuadBatch, alpha float64) {
+	if overlay.lastJudgement == mania.Ignore {
+		return
+	}
+
+	progress := (overlay.time - overlay.lastJudgementTime) / maniaJudgementTime
+	if progress < 0 || progress > 1 {
+		return
+	}
+
+	x := (overlay.stageLeft + overlay.stageRight) / 2
+	y := overlay.info.ScorePosition * overlay.scale
+
+	a := alpha * min(1, 2-progress*2)
+	s := 1 + 0.2*(1-progress)
+
+	if tex := overlay.judgementTextures[overlay.lastJudgement]; tex != nil {
+		batch.SetColor(1, 1, 1, a)
+		batch.SetTranslation(vector.NewVec2d(x, y))
+		batch.SetScale(s*overlay.scale*0.7, s*overlay.scale*0.7)
+		batch.DrawTexture(*tex)
+		batch.ResetTransform()
+
+		return
+	}
+
+	text := overlay.lastJudgement.String()
+
+	switch overlay.lastJudgement {
+	case mania.Hit320:
+		text = "MAX"
+		batch.SetColor(0.6, 0.9, 1, a)
+	case mania.Hit300:
+		batch.SetColor(1, 0.85, 0.3, a)
+	case mania.Hit200:
+		batch.SetColor(0.6, 1, 0.6, a)
+	case mania.Miss:
+		text = "MISS"
+		batch.SetColor(1, 0.3, 0.3, a)
+	default:
+		batch.SetColor(0.8, 0.8, 0.8, a)
+	}
+
+	overlay.hudFont.DrawOrigin(batch, x, y, vector.Centre, 32*s, false, text)
+}
+
+func (overlay *ManiaOverlay) drawScore(batch *batch.QuadBatch, alpha float64) {
+	score := overlay.ruleset.GetScore(overlay.cursor)
+
+	if score.CurrentCombo > 0 {
+		batch.SetColor(1, 1, 1, alpha)
+		overlay.comboFont.DrawOrigin(batch, (overlay.stageLeft+overlay.stageRight)/2, overlay.info.ComboPosition*overlay.scale, vector.Centre, overlay.comboFont.GetSize()*overlay.scale*0.7, false, fmt.Sprintf("%d", score.CurrentCombo))
+	}
+
+	scoreAlpha := settings.Gameplay.Score.Opacity * alpha
+
+	if scoreAlpha < 0.001 || !settings.Gameplay.Score.Show {
+		return
+	}
+
+	scoreScale := settings.Gameplay.Score.Scale
+
+	scoreSize := overlay.scoreFont.GetSize() * scoreScale * 0.96
+	accSize := scoreSize * 0.6
+
+	batch.SetColor(1, 1, 1, scoreAlpha)
+
+	scoreText := fmt.Sprintf("%07d", int64(math.Round(overlay.scoreGlider.GetValue())))
+	overlay.scoreFont.DrawOrigin(batch, overlay.ScaledWidth-9.6*scoreScale, 0, vector.TopRight, scoreSize, true, scoreText)
+
+	accText := fmt.Sprintf("%5.2f%%", overlay.accuracyGlider.GetValue())
+	overlay.scoreFont.DrawOrigin(batch, overlay.ScaledWidth-9.6*scoreScale, scoreSize, vector.TopRight, accSize, true, accText)
+
+	overlay.hudFont.DrawOrigin(batch, 10, overlay.ScaledHeight-10, vector.BottomLeft, 24, false, fmt.Sprintf("%s %s", overlay.cursor.Name, overlay.ruleset.GetPlayerDifficulty(overlay.cursor).GetModString()))
+}
+
+func (overlay *ManiaOverlay) drawRect(batch *batch.QuadBatch, x, y, width, height float64) {
+	batch.SetTranslation(vector.NewVec2d(x+width/2, y+height/2))
+	batch.SetScale(width/2, height/2)
+	batch.DrawUnit(graphics.Pixel.GetRegion())
+	batch.ResetTransform()
+}
+
+// drawTextureV draws texture scaled to given width or height, the other dimension keeps the aspect ratio if it's 0
+func (overlay *ManiaOverlay) drawTextureV(batch *batch.QuadBatch, tex *texture.TextureRegion, x, y, width, height float64, origin vector.Vector2d) {
+	if tex == nil {
+		return
+	}
+
+	w, h := float64(tex.Width), float64(tex.Height)
+
+	scaleX, scaleY := width/w, height/h
+
+	if width == 0 {
+		scaleX = scaleY
+	} else if height == 0 {
+		scaleY = scaleX
+	}
+
+	batch.SetTranslation(vector.NewVec2d(x-origin.X*w*scaleX/2, y-origin.Y*h*scaleY/2))
+	batch.SetScale(scaleX, scaleY)
+	batch.DrawTexture(*tex)
+	batch.ResetTransform()
+}
+
+func (overlay *ManiaOverlay) drawTextureStretched(batch *batch.QuadBatch, tex *texture.TextureRegion, x, y, width, height float64) {
+	if height <= 0 {
+		return
+	}
+
+	batch.SetTranslation(vector.NewVec2d(x+width/2, y+height/2))
+	batch.SetScale(width/float64(tex.Width), height/float64(tex.Height))
+	batch.DrawTexture(*tex)
+	batch.ResetTransform()
+}
+
+func (overlay *ManiaOverlay) IsBroken(_ *graphics.Cursor) bool {
+	return false
+}
+
+func (overlay *ManiaOverlay) DisableAudioSubmission(b bool) {
+	overlay.audioDisabled = b
+}
+
+func (overlay *ManiaOverlay) ShouldDrawHUDBeforeCursor() bool {
+	return true
+}
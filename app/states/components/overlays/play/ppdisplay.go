@@ -31,6 +31,13 @@ type PPDisplay struct {
 	ppGlider *animation.TargetGlider
 	ppText   string
 
+	// Gliders of the comparison pp version, nil if comparison is disabled
+	aimCGlider        *animation.TargetGlider
+	tapCGlider        *animation.TargetGlider
+	accCGlider        *animation.TargetGlider
+	flashlightCGlider *animation.TargetGlider
+	ppCGlider         *animation.TargetGlider
+
 	mText string
 
 	decimals int
@@ -39,8 +46,8 @@ type PPDisplay struct {
 	mods difficulty.Modifier
 }
 
-func NewPPDisplay(mods difficulty.Modifier) *PPDisplay {
-	return &PPDisplay{
+func NewPPDisplay(mods difficulty.Modifier, compare bool) *PPDisplay {
+	ppDisplay := &PPDisplay{
 		ppFont:           font.GetFont("HUDFont"),
 		aimGlider:        animation.NewTargetGlider(0, 0),
 		tapGlider:        animation.NewTargetGlider(0, 0),
@@ -56,6 +63,16 @@ func NewPPDisplay(mods difficulty.Modifier) *PPDisplay {
 		format:           "%.0fpp",
 		mods:             mods,
 	}
+
+	if compare {
+		ppDisplay.aimCGlider = animation.NewTargetGlider(0, 0)
+		ppDisplay.tapCGlider = animation.NewTargetGlider(0, 0)
+		ppDisplay.accCGlider = animation.NewTargetGlider(0, 0)
+		ppDisplay.flashlightCGlider = animation.NewTargetGlider(0, 0)
+		ppDisplay.ppCGlider = animation.NewTargetGlider(0, 0)
+	}
+
+	return ppDisplay
 }
 
 func (ppDisplay *PPDisplay) Add(results api.PPv2Results) {
@@ -68,6 +85,21 @@ func (ppDisplay *PPDisplay) Add(results api.PPv2Results) {
 	ppDisplay.ppGlider.SetValue(results.Total, static)
 }
 
+// AddCompare sets pp of the comparison version, it's shown next to the main pp
+func (ppDisplay *PPDisplay) AddCompare(results api.PPv2Results) {
+	if ppDisplay.ppCGlider == nil {
+		return
+	}
+
+	static := settings.Gameplay.PPCounter.Static
+
+	ppDisplay.aimCGlider.SetValue(results.Aim, static)
+	ppDisplay.tapCGlider.SetValue(results.Speed, static)
+	ppDisplay.accCGlider.SetValue(results.Acc, static)
+	ppDisplay.flashlightCGlider.SetValue(results.Flashlight, static)
+	ppDisplay.ppCGlider.SetValue(results.Total, static)
+}
+
 func (ppDisplay *PPDisplay) Update(time float64) {
 	if settings.Gameplay.PPCounter.Decimals > ppDisplay.decimals {
 		ppDisplay.decimals = settings.Gameplay.PPCounter.Decimals
@@ -76,24 +108,31 @@ func (ppDisplay *PPDisplay) Update(time float64) {
 
 	var mText string
 
-	ppDisplay.updatePP(ppDisplay.ppGlider, &ppDisplay.ppText, time, &mText)
+	ppDisplay.updatePP(ppDisplay.ppGlider, ppDisplay.ppCGlider, &ppDisplay.ppText, time, &mText)
 
 	if settings.Gameplay.PPCounter.ShowPPComponents {
-		ppDisplay.updatePP(ppDisplay.aimGlider, &ppDisplay.aimText, time, &mText)
-		ppDisplay.updatePP(ppDisplay.tapGlider, &ppDisplay.tapText, time, &mText)
-		ppDisplay.updatePP(ppDisplay.accGlider, &ppDisplay.accText, time, &mText)
-		ppDisplay.updatePP(ppDisplay.flashlightGlider, &ppDisplay.flashlightText, time, &mText)
+		ppDisplay.updatePP(ppDisplay.aimGlider, ppDisplay.aimCGlider, &ppDisplay.aimText, time, &mText)
+		ppDisplay.updatePP(ppDisplay.tapGlider, ppDisplay.tapCGlider, &ppDisplay.tapText, time, &mText)
+		ppDisplay.updatePP(ppDisplay.accGlider, ppDisplay.accCGlider, &ppDisplay.accText, time, &mText)
+		ppDisplay.updatePP(ppDisplay.flashlightGlider, ppDisplay.flashlightCGlider, &ppDisplay.flashlightText, time, &mText)
 	}
 
 	ppDisplay.mText = mText
 }
 
-func (ppDisplay *PPDisplay) updatePP(glider *animation.TargetGlider, text *string, time float64, mText *string) {
+func (ppDisplay *PPDisplay) updatePP(glider, cGlider *animation.TargetGlider, text *string, time float64, mText *string) {
 	glider.SetDecimals(settings.Gameplay.PPCounter.Decimals)
 	glider.Update(time)
 
 	*text = fmt.Sprintf(ppDisplay.format, glider.GetValue())
 
+	if cGlider != nil {
+		cGlider.SetDecimals(settings.Gameplay.PPCounter.Decimals)
+		cGlider.Update(time)
+
+		*text += " / " + fmt.Sprintf(ppDisplay.format, cGlider.GetValue())
+	}
+
 	if len(*text) > len(*mText) {
 		*mText = *text
 	}
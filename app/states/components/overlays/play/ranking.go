@@ -135,7 +135,13 @@ func NewRankingPanel(cursor *graphics.Cursor, ruleset *osu.OsuRuleSet, hitError
 
 	score := panel.ruleset.GetScore(panel.cursor)
 
-	panel.pp = fmt.Sprintf("%."+strconv.Itoa(settings.Gameplay.PPCounter.Decimals)+"fpp", score.PP.Total)
+	ppFormat := "%." + strconv.Itoa(settings.Gameplay.PPCounter.Decimals) + "fpp"
+
+	panel.pp = fmt.Sprintf(ppFormat, score.PP.Total)
+
+	if panel.ruleset.GetCompareVersion() != "" {
+		panel.pp += " / " + fmt.Sprintf(ppFormat, score.PPCompare.Total)
+	}
 
 	panel.gradeS = sprite.NewSpriteSingle(skin.GetTexture("ranking-"+score.Grade.TextureName()), 5, rRPos, vector.Centre)
 
@@ -266,6 +266,14 @@ func (board *ScoreBoard) UpdatePlayer(score, combo int64) {
 	board.first = false
 }
 
+// ResetPlayer sets player's score back to 0 without animations, used when the map is rewound
+func (board *ScoreBoard) ResetPlayer() {
+	board.first = true
+	board.lastPlayerIndex = -1
+
+	board.UpdatePlayer(0, 0)
+}
+
 func (board *ScoreBoard) Update(time float64) {
 	board.time = time
 
@@ -176,6 +176,16 @@ func loadSubFont(uPath, alias string) {
 }
 
 func NewScoreOverlay(ruleset *osu.OsuRuleSet, cursor *graphics.Cursor) *ScoreOverlay {
+	return newScoreOverlay(ruleset, cursor, nil)
+}
+
+// Rewind creates an overlay for a ruleset recreated after rewinding the map.
+// Underlay, strain graph and leaderboard of this overlay are reused, so they are not loaded again.
+func (overlay *ScoreOverlay) Rewind(ruleset *osu.OsuRuleSet, cursor *graphics.Cursor) *ScoreOverlay {
+	return newScoreOverlay(ruleset, cursor, overlay)
+}
+
+func newScoreOverlay(ruleset *osu.OsuRuleSet, cursor *graphics.Cursor, previous *ScoreOverlay) *ScoreOverlay {
 	loadFonts()
 
 	overlay := new(ScoreOverlay)
@@ -185,7 +195,11 @@ func NewScoreOverlay(ruleset *osu.OsuRuleSet, cursor *graphics.Cursor) *ScoreOve
 	overlay.ScaledHeight = 768
 	overlay.ScaledWidth = settings.Graphics.GetAspectRatio() * overlay.ScaledHeight
 
-	overlay.initUnderlay()
+	if previous != nil {
+		overlay.underlay = previous.underlay
+	} else {
+		overlay.initUnderlay()
+	}
 
 	overlay.results = play.NewHitResults(ruleset.GetBeatMap().Diff)
 	overlay.ruleset = ruleset
@@ -194,9 +208,13 @@ func NewScoreOverlay(ruleset *osu.OsuRuleSet, cursor *graphics.Cursor) *ScoreOve
 	overlay.scoreGlider = animation.NewTargetGlider(0, 0)
 	overlay.accuracyGlider = animation.NewTargetGlider(100, 2)
 
-	overlay.ppDisplay = play.NewPPDisplay(ruleset.GetBeatMap().Diff.Mods)
+	overlay.ppDisplay = play.NewPPDisplay(ruleset.GetBeatMap().Diff.Mods, ruleset.GetCompareVersion() != "")
 
-	overlay.strainGraph = play.NewStrainGraph(ruleset.GetBeatMap(), performance.GetDifficultyCalculator().CalculateStrainPeaks(ruleset.GetBeatMap().HitObjects, ruleset.GetBeatMap().Diff), false, true)
+	if previous != nil {
+		overlay.strainGraph = previous.strainGraph
+	} else {
+		overlay.strainGraph = play.NewStrainGraph(ruleset.GetBeatMap(), performance.GetDifficultyCalculator().CalculateStrainPeaks(ruleset.GetBeatMap().HitObjects, ruleset.GetBeatMap().Diff), false, true)
+	}
 
 	overlay.resultsFade = animation.NewGlider(0)
 
@@ -228,7 +246,7 @@ func NewScoreOverlay(ruleset *osu.OsuRuleSet, cursor *graphics.Cursor) *ScoreOve
 	overlay.scoreFont = skin.GetFont("score")
 	overlay.circularMetre = skin.GetTextureSource("circularmetre", skin.LOCAL)
 
-	ruleset.SetListener(overlay.hitReceived)
+	ruleset.AddListener(overlay.hitReceived)
 
 	overlay.camera = camera2.NewCamera()
 	overlay.camera.SetViewportF(0, int(overlay.ScaledHeight), int(overlay.ScaledWidth), 0)
@@ -298,8 +316,13 @@ func NewScoreOverlay(ruleset *osu.OsuRuleSet, cursor *graphics.Cursor) *ScoreOve
 		overlay.flashlight = common.NewFlashlight(overlay.ruleset.GetBeatMap())
 	}
 
-	overlay.entry = play.NewScoreboard(overlay.ruleset.GetBeatMap(), ruleset.GetPlayerDifficulty(overlay.cursor).CheckModActive(difficulty.Lazer), overlay.cursor.ScoreID)
-	overlay.entry.AddPlayer(overlay.cursor.Name, overlay.cursor.IsAutoplay)
+	if previous != nil {
+		overlay.entry = previous.entry
+		overlay.entry.ResetPlayer()
+	} else {
+		overlay.entry = play.NewScoreboard(overlay.ruleset.GetBeatMap(), ruleset.GetPlayerDifficulty(overlay.cursor).CheckModActive(difficulty.Lazer), overlay.cursor.ScoreID)
+		overlay.entry.AddPlayer(overlay.cursor.Name, overlay.cursor.IsAutoplay)
+	}
 
 	overlay.initArrows()
 
@@ -399,6 +422,7 @@ func (overlay *ScoreOverlay) hitReceived(c *graphics.Cursor, judgementResult osu
 	overlay.accuracyGlider.SetValue(sc.Accuracy*100, settings.Gameplay.Score.StaticAccuracy)
 
 	overlay.ppDisplay.Add(score.PP)
+	overlay.ppDisplay.AddCompare(score.PPCompare)
 
 	overlay.hpSections = append(overlay.hpSections, vector.NewVec2d(float64(judgementResult.Time), overlay.ruleset.GetHP(overlay.cursor)))
 
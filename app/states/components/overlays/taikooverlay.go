@@ -0,0 +1,413 @@
+package overlays
+
+import (
+	"fmt"
+	"github.com/wieku/danser-go/app/audio"
+	camera2 "github.com/wieku/danser-go/app/bmath/camera"
+	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/app/rulesets/taiko"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/app/skin"
+	"github.com/wieku/danser-go/framework/bass"
+	"github.com/wieku/danser-go/framework/graphics/batch"
+	"github.com/wieku/danser-go/framework/graphics/font"
+	"github.com/wieku/danser-go/framework/graphics/texture"
+	"github.com/wieku/danser-go/framework/math/animation"
+	color2 "github.com/wieku/danser-go/framework/math/color"
+	"github.com/wieku/danser-go/framework/math/vector"
+	"math"
+)
+
+const (
+	taikoLaneY      = 230.0
+	taikoLaneHeight = 130.0
+	taikoHitX       = 210.0
+	taikoDrumX      = 90.0
+
+	taikoRadius       = 34.0
+	taikoStrongRadius = 50.0
+
+	// osu!stable's taiko playfield is 640 osu!pixels wide
+	taikoPlayfieldWidth = 640.0
+
+	taikoJudgementTime = 400.0
+)
+
+var (
+	taikoCentreColor   = color2.NewIRGB(235, 69, 44)
+	taikoRimColor      = color2.NewIRGB(68, 141, 171)
+	taikoDrumRollColor = color2.NewIRGB(252, 184, 6)
+	taikoSwellColor    = color2.NewIRGB(240, 145, 40)
+)
+
+type taikoJudgement struct {
+	time   float64
+	result taiko.HitResult
+}
+
+type TaikoOverlay struct {
+	ruleset *taiko.TaikoRuleSet
+	cursor  *graphics.Cursor
+
+	music bass.ITrack
+
+	camera       *camera2.Camera
+	ScaledWidth  float64
+	ScaledHeight float64
+
+	time float64
+
+	audioDisabled bool
+
+	firstVisible int
+
+	judgements []taikoJudgement
+
+	keyGliders map[taiko.Action]*animation.Glider
+
+	scoreGlider    *animation.TargetGlider
+	accuracyGlider *animation.TargetGlider
+
+	circle        *texture.TextureRegion
+	circleOverlay *texture.TextureRegion
+
+	hudFont   *font.Font
+	scoreFont *font.Font
+}
+
+func NewTaikoOverlay(ruleset *taiko.TaikoRuleSet, cursor *graphics.Cursor) *TaikoOverlay {
+	loadFonts()
+
+	overlay := &TaikoOverlay{
+		ruleset: ruleset,
+		cursor:  cursor,
+	}
+
+	overlay.ScaledHeight = 768
+	overlay.ScaledWidth = settings.Graphics.GetAspectRatio() * overlay.ScaledHeight
+
+	overlay.camera = camera2.NewCamera()
+	overlay.camera.SetViewportF(0, int(overlay.ScaledHeight), int(overlay.ScaledWidth), 0)
+	overlay.camera.Update()
+
+	overlay.keyGliders = make(map[taiko.Action]*animation.Glider)
+	for _, action := range []taiko.Action{taiko.LeftCentre, taiko.LeftRim, taiko.RightCentre, taiko.RightRim} {
+		overlay.keyGliders[action] = animation.NewGlider(0)
+	}
+
+	overlay.scoreGlider = animation.NewTargetGlider(0, 0)
+	overlay.accuracyGlider = animation.NewTargetGlider(100, 2)
+
+	overlay.circle = skin.GetTexture("hitcircle")
+	overlay.circleOverlay = skin.GetTexture("hitcircleoverlay")
+
+	overlay.hudFont = font.GetFont("HUDFont")
+	overlay.scoreFont = skin.GetFont("score")
+
+	ruleset.AddListener(overlay.hitReceived)
+	ruleset.AddPressListener(overlay.pressReceived)
+
+	return overlay
+}
+
+func (overlay *TaikoOverlay) hitReceived(_ *graphics.Cursor, judgementResult taiko.JudgementResult, score taiko.Score) {
+	overlay.scoreGlider.SetValue(float64(score.Score), settings.Gameplay.Score.StaticScore)
+	overlay.accuracyGlider.SetValue(score.Accuracy*100, settings.Gameplay.Score.StaticAccuracy)
+
+	switch judgementResult.HitResult {
+	case taiko.Great, taiko.Ok, taiko.Miss:
+		overlay.judgements = append(overlay.judgements, taikoJudgement{
+			time:   float64(judgementResult.Time),
+			result: judgementResult.HitResult,
+		})
+	case taiko.StrongBonus:
+		overlay.playSample(float64(judgementResult.Time), 4)
+	}
+}
+
+func (overlay *TaikoOverlay) pressReceived(_ *graphics.Cursor, time int64, action taiko.Action) {
+	glider := overlay.keyGliders[action]
+	glider.Reset()
+	glider.SetValue(1)
+	glider.AddEventS(float64(time), float64(time)+150, 1, 0)
+
+	if action.IsCentre() {
+		overlay.playSample(float64(time), 0)
+	} else {
+		overlay.playSample(float64(time), 8)
+	}
+}
+
+func (overlay *TaikoOverlay) playSample(time float64, hitSound int) {
+	if overlay.audioDisabled {
+		return
+	}
+
+	point := overlay.ruleset.GetBeatMap().Timings.GetPointAt(time)
+
+	audio.PlaySample(point.SampleSet, 0, hitSound, point.SampleIndex, point.SampleVolume, 0, 256)
+}
+
+func (overlay *TaikoOverlay) Update(time float64) {
+	overlay.time = time
+
+	for _, g := range overlay.keyGliders {
+		g.Update(time)
+	}
+
+	overlay.scoreGlider.Update(time)
+	overlay.accuracyGlider.Update(time)
+
+	for len(overlay.judgements) > 0 && overlay.judgements[0].time+taikoJudgementTime < time {
+		overlay.judgements = overlay.judgements[1:]
+	}
+}
+
+func (overlay *TaikoOverlay) SetMusic(music bass.ITrack) {
+	overlay.music = music
+}
+
+func (overlay *TaikoOverlay) DrawBackground(_ *batch.QuadBatch, _ []color2.Color, _ float64) {}
+
+func (overlay *TaikoOverlay) DrawBeforeObjects(_ *batch.QuadBatch, _ []color2.Color, _ float64) {}
+
+func (overlay *TaikoOverlay) DrawNormal(_ *batch.QuadBatch, _ []color2.Color, _ float64) {}
+
+func (overlay *TaikoOverlay) DrawHUD(batch *batch.QuadBatch, _ []color2.Color, alpha float64) {
+	prev := batch.Projection
+	batch.SetCamera(overlay.camera.GetProjectionView())
+	batch.ResetTransform()
+
+	overlay.drawLane(batch, alpha)
+	overlay.drawObjects(batch, alpha)
+	overlay.drawJudgements(batch, alpha)
+	overlay.drawDrum(batch, alpha)
+	overlay.drawScore(batch, alpha)
+
+	batch.ResetTransform()
+	batch.SetCamera(prev)
+}
+
+func (overlay *TaikoOverlay) drawLane(batch *batch.QuadBatch, alpha float64) {
+	pixel := graphics.Pixel.GetRegion()
+
+	batch.SetColor(0.05, 0.05, 0.05, 0.85*alpha)
+	batch.SetTranslation(vector.NewVec2d(overlay.ScaledWidth/2, taikoLaneY))
+	batch.SetScale(overlay.ScaledWidth/2, taikoLaneHeight/2)
+	batch.DrawUnit(pixel)
+
+	batch.SetColor(0.2, 0.2, 0.2, alpha)
+	batch.SetTranslation(vector.NewVec2d(taikoDrumX, taikoLaneY))
+	batch.SetScale(taikoDrumX, taikoLaneHeight/2)
+	batch.DrawUnit(pixel)
+
+	overlay.drawCircle(batch, taikoHitX, taikoLaneY, taikoStrongRadius, color2.NewL(1), 0.3*alpha, false)
+	overlay.drawCircle(batch, taikoHitX, taikoLaneY, taikoRadius, color2.NewL(1), 0.5*alpha, false)
+}
+
+func (overlay *TaikoOverlay) drawObjects(batch *batch.QuadBatch, alpha float64) {
+	objects := overlay.ruleset.GetObjects()
+
+	scale := overlay.ScaledWidth / taikoPlayfieldWidth
+
+	for overlay.firstVisible < len(objects) && overlay.getX(objects[overlay.firstVisible], objects[overlay.firstVisible].EndTime, scale) < -taikoStrongRadius {
+		overlay.firstVisible++
+	}
+
+	last := overlay.firstVisible
+
+	for last < len(objects) && overlay.getX(objects[last], objects[last].StartTime, scale) < overlay.ScaledWidth+taikoStrongRadius {
+		last++
+	}
+
+	// Earlier objects have to be drawn on top
+	for i := last - 1; i >= overlay.firstVisible; i-- {
+		o := objects[i]
+		result := overlay.ruleset.GetResult(overlay.cursor, i)
+
+		radius := taikoRadius
+		if o.Strong {
+			radius = taikoStrongRadius
+		}
+
+		x := overlay.getX(o, o.StartTime, scale)
+
+		switch o.Type {
+		case taiko.Centre, taiko.Rim:
+			if result&taiko.BaseHits > 0 {
+				continue
+			}
+
+			color := taikoCentreColor
+			if o.Type == taiko.Rim {
+				color = taikoRimColor
+			}
+
+			overlay.drawCircle(batch, x, taikoLaneY, radius, color, alpha, true)
+		case taiko.DrumRoll:
+			endX := overlay.getX(o, o.EndTime, scale)
+
+			setColorAlpha(batch, taikoDrumRollColor, alpha)
+			batch.SetTranslation(vector.NewVec2d((x+endX)/2, taikoLaneY))
+			batch.SetScale((endX-x)/2, radius*0.9)
+			batch.DrawUnit(graphics.Pixel.GetRegion())
+
+			overlay.drawCircle(batch, endX, taikoLaneY, radius, taikoDrumRollColor, alpha, true)
+			overlay.drawCircle(batch, x, taikoLaneY, radius, taikoDrumRollColor, alpha, true)
+
+			for _, t := range o.Ticks {
+				if t < overlay.time {
+					continue
+				}
+
+				overlay.drawCircle(batch, overlay.getX(o, t, scale), taikoLaneY, radius*0.2, color2.NewL(1), 0.8*alpha, false)
+			}
+		case taiko.Swell:
+			if result != taiko.Ignore {
+				continue
+			}
+
+			if overlay.time >= o.StartTime {
+				x = taikoHitX
+			}
+
+			overlay.drawCircle(batch, x, taikoLaneY, radius, taikoSwellColor, alpha, true)
+
+			if overlay.time >= o.StartTime {
+				remaining := o.RequiredHits - overlay.ruleset.GetSwellHits(overlay.cursor, i)
+
+				batch.ResetTransform()
+				batch.SetColor(1, 1, 1, alpha)
+				overlay.hudFont.DrawOrigin(batch, taikoHitX, taikoLaneY+taikoLaneHeight/2+25, vector.Centre, 30, false, fmt.Sprintf("%d", remaining))
+			}
+		}
+	}
+
+	batch.ResetTransform()
+}
+
+func (overlay *TaikoOverlay) getX(object *taiko.Object, time, scale float64) float64 {
+	return taikoHitX + (time-overlay.time)*object.Velocity*scale
+}
+
+func (overlay *TaikoOverlay) drawCircle(batch *batch.QuadBatch, x, y, radius float64, color color2.Color, alpha float64, withOverlay bool) {
+	if overlay.circle == nil {
+		return
+	}
+
+	s := radius / float64(overlay.circle.Width/2)
+
+	batch.SetTranslation(vector.NewVec2d(x, y))
+	batch.SetScale(s, s)
+	setColorAlpha(batch, color, alpha)
+	batch.DrawTexture(*overlay.circle)
+
+	if withOverlay && overlay.circleOverlay != nil {
+		batch.SetColor(1, 1, 1, alpha)
+		batch.DrawTexture(*overlay.circleOverlay)
+	}
+}
+
+func (overlay *TaikoOverlay) drawJudgements(batch *batch.QuadBatch, alpha float64) {
+	for _, j := range overlay.judgements {
+		progress := (overlay.time - j.time) / taikoJudgementTime
+		if progress < 0 {
+			continue
+		}
+
+		a := alpha * (1 - progress)
+
+		var text string
+
+		switch j.result {
+		case taiko.Great:
+			text = "GREAT"
+			batch.SetColor(1, 0.85, 0.3, a)
+		case taiko.Ok:
+			text = "GOOD"
+			batch.SetColor(0.6, 1, 0.6, a)
+		default:
+			text = "MISS"
+			batch.SetColor(1, 0.3, 0.3, a)
+		}
+
+		batch.ResetTransform()
+		overlay.hudFont.DrawOrigin(batch, taikoHitX, taikoLaneY-taikoLaneHeight/2-20-progress*15, vector.Centre, 28, false, text)
+	}
+
+	batch.ResetTransform()
+}
+
+func (overlay *TaikoOverlay) drawDrum(batch *batch.QuadBatch, alpha float64) {
+	pixel := graphics.Pixel.GetRegion()
+
+	halfW := taikoDrumX / 2
+	halfH := taikoLaneHeight / 2
+
+	// Rims are the outer parts of the drum, centres are the inner ones
+	parts := []struct {
+		action taiko.Action
+		x      float64
+		color  color2.Color
+	}{
+		{taiko.LeftRim, halfW / 2, taikoRimColor},
+		{taiko.LeftCentre, halfW * 1.5, taikoCentreColor},
+		{taiko.RightCentre, halfW * 2.5, taikoCentreColor},
+		{taiko.RightRim, halfW * 3.5, taikoRimColor},
+	}
+
+	for _, p := range parts {
+		v := overlay.keyGliders[p.action].GetValue()
+
+		setColorAlpha(batch, p.color, alpha*(0.25+0.75*v))
+		batch.SetTranslation(vector.NewVec2d(p.x, taikoLaneY))
+		batch.SetScale(halfW/2-2, halfH-4)
+		batch.DrawUnit(pixel)
+	}
+
+	score := overlay.ruleset.GetScore(overlay.cursor)
+
+	batch.ResetTransform()
+	batch.SetColor(1, 1, 1, alpha)
+	overlay.hudFont.DrawOrigin(batch, taikoDrumX, taikoLaneY, vector.Centre, 40, true, fmt.Sprintf("%d", score.CurrentCombo))
+}
+
+func (overlay *TaikoOverlay) drawScore(batch *batch.QuadBatch, alpha float64) {
+	scoreAlpha := settings.Gameplay.Score.Opacity * alpha
+
+	if scoreAlpha < 0.001 || !settings.Gameplay.Score.Show {
+		return
+	}
+
+	scoreScale := settings.Gameplay.Score.Scale
+
+	scoreSize := overlay.scoreFont.GetSize() * scoreScale * 0.96
+	accSize := scoreSize * 0.6
+
+	batch.ResetTransform()
+	batch.SetColor(1, 1, 1, scoreAlpha)
+
+	scoreText := fmt.Sprintf("%08d", int64(math.Round(overlay.scoreGlider.GetValue())))
+	overlay.scoreFont.DrawOrigin(batch, overlay.ScaledWidth-9.6*scoreScale, 0, vector.TopRight, scoreSize, true, scoreText)
+
+	accText := fmt.Sprintf("%5.2f%%", overlay.accuracyGlider.GetValue())
+	overlay.scoreFont.DrawOrigin(batch, overlay.ScaledWidth-9.6*scoreScale, scoreSize, vector.TopRight, accSize, true, accText)
+
+	overlay.hudFont.DrawOrigin(batch, 10, taikoLaneY+taikoLaneHeight/2+10, vector.TopLeft, 24, false, fmt.Sprintf("%s %s", overlay.cursor.Name, overlay.ruleset.GetPlayerDifficulty(overlay.cursor).GetModString()))
+}
+
+func (overlay *TaikoOverlay) IsBroken(_ *graphics.Cursor) bool {
+	return false
+}
+
+func (overlay *TaikoOverlay) DisableAudioSubmission(b bool) {
+	overlay.audioDisabled = b
+}
+
+func (overlay *TaikoOverlay) ShouldDrawHUDBeforeCursor() bool {
+	return true
+}
+
+func setColorAlpha(batch *batch.QuadBatch, color color2.Color, alpha float64) {
+	batch.SetColor(float64(color.R), float64(color.G), float64(color.B), alpha)
+}
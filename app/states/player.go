@@ -14,12 +14,14 @@ import (
 	"github.com/wieku/danser-go/app/discord"
 	"github.com/wieku/danser-go/app/graphics"
 	"github.com/wieku/danser-go/app/input"
+	"github.com/wieku/danser-go/app/liveserver"
 	"github.com/wieku/danser-go/app/osuapi"
 	"github.com/wieku/danser-go/app/rulesets/osu"
 	"github.com/wieku/danser-go/app/settings"
 	"github.com/wieku/danser-go/app/states/components/common"
 	"github.com/wieku/danser-go/app/states/components/containers"
 	"github.com/wieku/danser-go/app/states/components/overlays"
+	"github.com/wieku/danser-go/app/thumbnail"
 	"github.com/wieku/danser-go/app/utils"
 	"github.com/wieku/danser-go/framework/bass"
 	"github.com/wieku/danser-go/framework/frame"
@@ -37,12 +39,14 @@ import (
 	"github.com/wieku/danser-go/framework/math/vector"
 	"github.com/wieku/danser-go/framework/profiler"
 	"github.com/wieku/danser-go/framework/qpc"
+	"github.com/wieku/rplpa"
 	"log"
 	"math"
 	"math/rand"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -101,6 +105,9 @@ type Player struct {
 	pitchGlider     *animation.Glider
 	frequencyGlider *animation.Glider
 
+	// adaptiveSpeed is the rate of Adaptive Speed mod, nil if it's not active or can't follow player's hits
+	adaptiveSpeed *osu.AdaptiveSpeed
+
 	startPoint  float64
 	startPointE float64
 
@@ -132,6 +139,20 @@ type Player struct {
 	failAt  float64
 	failed  bool
 
+	sbBreakIndex int
+
+	judgementLog *osu.JudgementLogger
+
+	seekMutex   sync.Mutex
+	seekTarget  float64
+	seekPending bool
+
+	seekBarHovered      bool
+	seekBarPressed      bool
+	seekBarDragging     bool
+	seekBarDragPos      float64
+	seekBarVisibleUntil float64
+
 	mProfiler *frame.Counter
 	mStats1   *runtime.MemStats
 	mStats2   *runtime.MemStats
@@ -166,6 +187,7 @@ func NewPlayer(beatMap *beatmap.BeatMap) *Player {
 	player.font = font.GetFont("Quicksand Bold")
 
 	discord.SetMap(beatMap.Artist, beatMap.Name, beatMap.Difficulty)
+	liveserver.SetMap(beatMap)
 
 	player.bMap = beatMap
 	player.mapFullName = fmt.Sprintf("%s - %s [%s]", beatMap.Artist, beatMap.Name, beatMap.Difficulty)
@@ -264,9 +286,147 @@ func NewPlayer(beatMap *beatmap.BeatMap) *Player {
 
 	graphics.Camera = player.mainCamera
 
+	player.initTimeline()
+	player.trySetupJudgementLog()
+
+	// See https://github.com/Wieku/danser-go/issues/121
+	player.musicPlayer.AddSilence(max(0, player.MapEnd/1000-player.musicPlayer.GetLength()))
+
+	player.background.SetTrack(player.musicPlayer)
+
+	player.coin = common.NewDanserCoin()
+	player.coin.SetMap(beatMap, player.musicPlayer)
+
+	player.coin.SetScale(0.25 * min(settings.Graphics.GetWidthF(), settings.Graphics.GetHeightF()))
+
+	player.profiler = frame.NewCounter()
+
+	player.bloomEffect = effects.NewBloomEffect(int(settings.Graphics.GetWidth()), int(settings.Graphics.GetHeight()))
+	player.blur = effects.NewBlurEffect(int(settings.Graphics.GetWidth()), int(settings.Graphics.GetHeight()))
+
+	player.background.Update(player.progressMsF, settings.Graphics.GetWidthF()/2, settings.Graphics.GetHeightF()/2)
+
+	player.profilerU = frame.NewCounter()
+
+	player.baseLimit = 1000
+
+	player.updateLimiter = frame.NewLimiter(player.baseLimit)
+
+	if player.bMap.Diff.CheckModActive(difficulty.Nightcore) {
+		player.nightcore = common.NewNightcoreProcessor()
+		player.nightcore.SetMap(player.bMap, player.musicPlayer)
+	}
+
+	if settings.Audio.OnlineOffset { // Try to load online offset
+		onlineBeatmap, err2 := osuapi.LookupBeatmap(beatMap.MD5)
+		if err2 != nil {
+			log.Println("Failed to load online offset:", err.Error())
+		} else if onlineBeatmap != nil {
+			player.onlineOffset = onlineBeatmap.Beatmapset.Offset
+			log.Println(fmt.Sprintf("Online offset loaded: %.0fms", player.onlineOffset))
+		}
+	}
+
+	if settings.RECORD {
+		return player
+	}
+
+	if player.isSeekable() {
+		input.RegisterListener(player.seekKeyEvent)
+	}
+
+	goroutines.RunOS(func() {
+		var lastTimeNano = qpc.GetNanoTime()
+
+		for !input.Win.ShouldClose() {
+			if player.processSeek() {
+				lastTimeNano = qpc.GetNanoTime()
+			}
+
+			currentTimeNano := qpc.GetNanoTime()
+
+			delta := float64(currentTimeNano-lastTimeNano) / 1000000.0
+
+			player.profilerU.PutSample(delta)
+
+			musicState := player.musicPlayer.GetState()
+
+			speed := 1.0
+
+			if musicState == bass.MusicStopped {
+				if player.rawPositionF < player.startPointE || player.start {
+					player.rawPositionF += delta
+				} else {
+					speed = settings.SPEED * player.getMapSpeed()
+					player.rawPositionF += delta * speed
+				}
+			} else {
+				musicPos := player.musicPlayer.GetPosition() * 1000
+				speed = player.musicPlayer.GetSpeed()
+
+				if musicPos != player.lastMusicPos || musicState == bass.MusicPaused {
+					player.rawPositionF = musicPos
+					player.lastMusicPos = musicPos
+				} else if musicPos > 1 {
+					// In DirectSound mode with VistaTruePos set to FALSE music is reported at 10ms intervals so we need to *interpolate* it
+					// Wait at least 1ms because before interpolating because there's a 60ish ms delay before music in playing state starts reporting time and we don't want to jump back in time
+					player.rawPositionF += delta * speed
+				}
+			}
+
+			platformOffset := 0.0
+			if runtime.GOOS == "windows" { // For some reason WASAPI reports time with 15ms delay, so we need to correct it
+				platformOffset = windowsOffset
+			}
+
+			oldOffset := 0.0
+			if player.bMap.Version < 5 {
+				oldOffset = 24
+			}
+
+			player.progressMsF = player.rawPositionF + (platformOffset+float64(settings.Audio.Offset))*speed - oldOffset - float64(settings.LOCALOFFSET) - player.onlineOffset
+
+			player.updateMain(delta)
+
+			lastTimeNano = currentTimeNano
+
+			player.updateLimiter.Sync()
+		}
+
+		player.musicPlayer.Stop()
+		bass.StopLoops()
+	})
+
+	return player
+}
+
+// initTimeline (re)creates everything that depends on map time, so it can be also used to rewind the map
+func (player *Player) initTimeline() {
 	player.bMap.Reset()
 
-	if settings.PLAY {
+	// Replay controller exists only when the map is rewound, replays and difficulty attributes are kept then
+	if controller, ok := player.controller.(*dance.ReplayController); ok {
+		controller.Rewind(player.bMap)
+
+		switch overlay := player.overlay.(type) {
+		case *overlays.ScoreOverlay:
+			player.overlay = overlay.Rewind(controller.GetRuleset(), controller.GetCursors()[0])
+		case *overlays.KnockoutOverlay:
+			player.overlay = overlays.NewKnockoutOverlay(controller)
+		}
+	} else if settings.PLAYMODE == rplpa.TAIKO {
+		player.controller = dance.NewTaikoController()
+
+		player.controller.SetBeatMap(player.bMap)
+		player.controller.InitCursors()
+		player.overlay = overlays.NewTaikoOverlay(player.controller.(*dance.TaikoController).GetRuleset(), player.controller.GetCursors()[0])
+	} else if settings.PLAYMODE == rplpa.MANIA {
+		player.controller = dance.NewManiaController()
+
+		player.controller.SetBeatMap(player.bMap)
+		player.controller.InitCursors()
+		player.overlay = overlays.NewManiaOverlay(player.controller.(*dance.ManiaController).GetRuleset(), player.controller.GetCursors()[0])
+	} else if settings.PLAY {
 		player.controller = dance.NewPlayerController()
 
 		player.controller.SetBeatMap(player.bMap)
@@ -292,7 +452,7 @@ func NewPlayer(beatMap *beatmap.BeatMap) *Player {
 
 	player.lastTime = -1
 
-	player.objectContainer = containers.NewHitObjectContainer(beatMap)
+	player.objectContainer = containers.NewHitObjectContainer(player.bMap)
 
 	player.Scl = 1
 	player.fadeOut = 1.0
@@ -323,22 +483,32 @@ func NewPlayer(beatMap *beatmap.BeatMap) *Player {
 	player.failRotation = animation.NewGlider(0)
 
 	player.trySetupFail()
+	player.trySetupStoryboardTriggers()
+	player.trySetupAdaptiveSpeed()
+
+	if ruleset := player.getRuleset(); ruleset != nil {
+		if player.judgementLog != nil {
+			player.judgementLog.Attach(ruleset)
+		}
+
+		liveserver.AttachRuleset(ruleset)
+	}
 
-	preempt := min(1800, beatMap.Diff.Preempt)
+	preempt := min(1800, player.bMap.Diff.Preempt)
 
 	skipTime := 0.0
 	if settings.SKIP {
-		skipTime = beatMap.HitObjects[0].GetStartTime()
+		skipTime = player.bMap.HitObjects[0].GetStartTime()
 	}
 
 	skipTime = max(skipTime, settings.START*1000) - preempt
 
-	beatmapStart := max(beatMap.HitObjects[0].GetStartTime(), settings.START*1000) - preempt
-	beatmapEnd := beatMap.HitObjects[len(beatMap.HitObjects)-1].GetEndTime() + float64(beatMap.Diff.Hit50)
+	beatmapStart := max(player.bMap.HitObjects[0].GetStartTime(), settings.START*1000) - preempt
+	beatmapEnd := player.bMap.HitObjects[len(player.bMap.HitObjects)-1].GetEndTime() + float64(player.bMap.Diff.Hit50)
 
 	if !math.IsInf(settings.END, 1) {
 		end := settings.END * 1000
-		beatmapEnd = min(end, beatMap.HitObjects[len(beatMap.HitObjects)-1].GetEndTime()) + float64(beatMap.Diff.Hit50)
+		beatmapEnd = min(end, player.bMap.HitObjects[len(player.bMap.HitObjects)-1].GetEndTime()) + float64(player.bMap.Diff.Hit50)
 	}
 
 	startOffset := 0.0
@@ -347,7 +517,7 @@ func NewPlayer(beatMap *beatmap.BeatMap) *Player {
 		startOffset = skipTime
 		player.startPoint = max(0, startOffset)
 
-		for _, o := range beatMap.HitObjects {
+		for _, o := range player.bMap.HitObjects {
 			if o.GetStartTime() > player.startPoint {
 				break
 			}
@@ -356,10 +526,10 @@ func NewPlayer(beatMap *beatmap.BeatMap) *Player {
 		}
 
 		player.volumeGlider.SetValue(0.0)
-		player.volumeGlider.AddEvent(skipTime, skipTime+beatMap.Diff.TimeFadeIn, 1.0)
+		player.volumeGlider.AddEvent(skipTime, skipTime+player.bMap.Diff.TimeFadeIn, 1.0)
 
 		player.objectsAlpha.SetValue(0.0)
-		player.objectsAlpha.AddEvent(skipTime, skipTime+beatMap.Diff.TimeFadeIn, 1.0)
+		player.objectsAlpha.AddEvent(skipTime, skipTime+player.bMap.Diff.TimeFadeIn, 1.0)
 
 		if player.overlay != nil {
 			player.overlay.DisableAudioSubmission(true)
@@ -413,7 +583,7 @@ func NewPlayer(beatMap *beatmap.BeatMap) *Player {
 	}
 
 	if !math.IsInf(settings.END, 1) {
-		for _, o := range beatMap.HitObjects {
+		for _, o := range player.bMap.HitObjects {
 			if o.GetEndTime() <= beatmapEnd {
 				continue
 			}
@@ -448,9 +618,6 @@ func NewPlayer(beatMap *beatmap.BeatMap) *Player {
 
 	player.MapEnd += 100
 
-	// See https://github.com/Wieku/danser-go/issues/121
-	player.musicPlayer.AddSilence(max(0, player.MapEnd/1000-player.musicPlayer.GetLength()))
-
 	if settings.Playfield.SeizureWarning.Enabled {
 		am := max(1000, settings.Playfield.SeizureWarning.Duration*1000)
 		startOffset -= am
@@ -466,11 +633,11 @@ func NewPlayer(beatMap *beatmap.BeatMap) *Player {
 
 	player.RunningTime = player.MapEnd - startOffset
 
-	for _, p := range beatMap.Pauses {
+	for _, p := range player.bMap.Pauses {
 		startTime := p.GetStartTime()
 		endTime := p.GetEndTime()
 
-		speed := settings.SPEED * player.bMap.Diff.GetSpeed()
+		speed := settings.SPEED * player.bMap.Diff.GetSpeedAt(startTime)
 
 		if endTime-startTime < 1000*speed || endTime < player.startPoint || startTime > player.MapEnd {
 			continue
@@ -489,116 +656,31 @@ func NewPlayer(beatMap *beatmap.BeatMap) *Player {
 		player.fxGlider.AddEvent(endTime, endTime+1000*speed, bmath.Normal)
 		player.cursorGlider.AddEvent(endTime, endTime+1000*speed, 1.0)
 	}
+}
 
-	player.background.SetTrack(player.musicPlayer)
-
-	player.coin = common.NewDanserCoin()
-	player.coin.SetMap(beatMap, player.musicPlayer)
-
-	player.coin.SetScale(0.25 * min(settings.Graphics.GetWidthF(), settings.Graphics.GetHeightF()))
-
-	player.profiler = frame.NewCounter()
-
-	player.bloomEffect = effects.NewBloomEffect(int(settings.Graphics.GetWidth()), int(settings.Graphics.GetHeight()))
-	player.blur = effects.NewBlurEffect(int(settings.Graphics.GetWidth()), int(settings.Graphics.GetHeight()))
-
-	player.background.Update(player.progressMsF, settings.Graphics.GetWidthF()/2, settings.Graphics.GetHeightF()/2)
-
-	player.profilerU = frame.NewCounter()
-
-	player.baseLimit = 1000
-
-	player.updateLimiter = frame.NewLimiter(player.baseLimit)
-
-	if player.bMap.Diff.CheckModActive(difficulty.Nightcore) {
-		player.nightcore = common.NewNightcoreProcessor()
-		player.nightcore.SetMap(player.bMap, player.musicPlayer)
+func (player *Player) getRuleset() *osu.OsuRuleSet {
+	if rC, ok := player.controller.(*dance.ReplayController); ok {
+		return rC.GetRuleset()
+	} else if rP, ok := player.controller.(*dance.PlayerController); ok {
+		return rP.GetRuleset()
 	}
 
-	if settings.Audio.OnlineOffset { // Try to load online offset
-		onlineBeatmap, err2 := osuapi.LookupBeatmap(beatMap.MD5)
-		if err2 != nil {
-			log.Println("Failed to load online offset:", err.Error())
-		} else if onlineBeatmap != nil {
-			player.onlineOffset = onlineBeatmap.Beatmapset.Offset
-			log.Println(fmt.Sprintf("Online offset loaded: %.0fms", player.onlineOffset))
-		}
-	}
+	return nil
+}
 
-	if settings.RECORD {
-		return player
+// hasOwnPlayfield returns true if osu!taiko or osu!mania playfield is used instead of osu!standard objects and cursors
+func (player *Player) hasOwnPlayfield() bool {
+	switch player.controller.(type) {
+	case *dance.TaikoController, *dance.ManiaController:
+		return true
 	}
 
-	goroutines.RunOS(func() {
-		var lastTimeNano = qpc.GetNanoTime()
-
-		for !input.Win.ShouldClose() {
-			currentTimeNano := qpc.GetNanoTime()
-
-			delta := float64(currentTimeNano-lastTimeNano) / 1000000.0
-
-			player.profilerU.PutSample(delta)
-
-			musicState := player.musicPlayer.GetState()
-
-			speed := 1.0
-
-			if musicState == bass.MusicStopped {
-				if player.rawPositionF < player.startPointE || player.start {
-					player.rawPositionF += delta
-				} else {
-					speed = settings.SPEED * player.bMap.Diff.GetSpeed()
-					player.rawPositionF += delta * speed
-				}
-			} else {
-				musicPos := player.musicPlayer.GetPosition() * 1000
-				speed = player.musicPlayer.GetSpeed()
-
-				if musicPos != player.lastMusicPos || musicState == bass.MusicPaused {
-					player.rawPositionF = musicPos
-					player.lastMusicPos = musicPos
-				} else if musicPos > 1 {
-					// In DirectSound mode with VistaTruePos set to FALSE music is reported at 10ms intervals so we need to *interpolate* it
-					// Wait at least 1ms because before interpolating because there's a 60ish ms delay before music in playing state starts reporting time and we don't want to jump back in time
-					player.rawPositionF += delta * speed
-				}
-			}
-
-			platformOffset := 0.0
-			if runtime.GOOS == "windows" { // For some reason WASAPI reports time with 15ms delay, so we need to correct it
-				platformOffset = windowsOffset
-			}
-
-			oldOffset := 0.0
-			if player.bMap.Version < 5 {
-				oldOffset = 24
-			}
-
-			player.progressMsF = player.rawPositionF + (platformOffset+float64(settings.Audio.Offset))*speed - oldOffset - float64(settings.LOCALOFFSET) - player.onlineOffset
-
-			player.updateMain(delta)
-
-			lastTimeNano = currentTimeNano
-
-			player.updateLimiter.Sync()
-		}
-
-		player.musicPlayer.Stop()
-		bass.StopLoops()
-	})
-
-	return player
+	return false
 }
 
 func (player *Player) trySetupFail() {
 	if sO, ok := player.overlay.(*overlays.ScoreOverlay); ok {
-		var ruleset *osu.OsuRuleSet
-
-		if rC, ok1 := player.controller.(*dance.ReplayController); ok1 {
-			ruleset = rC.GetRuleset()
-		} else if rP, ok2 := player.controller.(*dance.PlayerController); ok2 {
-			ruleset = rP.GetRuleset()
-		}
+		ruleset := player.getRuleset()
 
 		if ruleset != nil {
 			ruleset.SetFailListener(func(cursor *graphics.Cursor) {
@@ -608,6 +690,10 @@ func (player *Player) trySetupFail() {
 
 				log.Println("Player failed!")
 
+				if storyboard := player.background.GetStoryboard(); storyboard != nil {
+					storyboard.SetPassing(player.progressMsF, false)
+				}
+
 				sO.Fail(true)
 
 				player.frequencyGlider.AddEvent(player.realTime, player.realTime+2400, 0.0)
@@ -638,13 +724,99 @@ func (player *Player) trySetupFail() {
 	}
 }
 
+func (player *Player) trySetupStoryboardTriggers() {
+	storyboard := player.background.GetStoryboard()
+
+	if _, ok := player.overlay.(*overlays.ScoreOverlay); !ok || storyboard == nil {
+		return
+	}
+
+	if ruleset := player.getRuleset(); ruleset != nil {
+		ruleset.AddListener(func(_ *graphics.Cursor, judgementResult osu.JudgementResult, _ osu.Score) {
+			if hitSound, ok := judgementResult.GetHitSound(); ok {
+				storyboard.TriggerHitSound(float64(judgementResult.Time), hitSound)
+			}
+		})
+	}
+}
+
+// trySetupAdaptiveSpeed makes the music follow hits of the player, it's possible only if there's a single player
+func (player *Player) trySetupAdaptiveSpeed() {
+	player.adaptiveSpeed = nil
+
+	asSettings, ok := difficulty.GetModConfig[difficulty.AdaptiveSpeedSettings](player.bMap.Diff)
+	if !ok {
+		return
+	}
+
+	ruleset := player.getRuleset()
+	if ruleset == nil || len(player.controller.GetCursors()) != 1 {
+		log.Println("Adaptive Speed can follow only a single player, initial rate will be used")
+		return
+	}
+
+	player.adaptiveSpeed = osu.NewAdaptiveSpeed(ruleset, asSettings)
+}
+
+// getMapSpeed returns the rate of the map at the current time
+func (player *Player) getMapSpeed() float64 {
+	if player.adaptiveSpeed != nil {
+		return player.adaptiveSpeed.GetRate()
+	}
+
+	return player.bMap.Diff.GetSpeedAt(player.progressMsF)
+}
+
+// trySetupJudgementLog creates the judgement log once, rulesets recreated by initTimeline are attached to the same log
+func (player *Player) trySetupJudgementLog() {
+	if settings.JUDGEMENTLOG == "" {
+		return
+	}
+
+	ruleset := player.getRuleset()
+	if ruleset == nil {
+		log.Println("Judgements can't be exported without a ruleset")
+		return
+	}
+
+	judgementLog, err := osu.NewJudgementLogger(settings.JUDGEMENTLOG)
+	if err != nil {
+		log.Println("Failed to create judgement log:", err)
+		return
+	}
+
+	judgementLog.Attach(ruleset)
+
+	player.judgementLog = judgementLog
+}
+
+// updateStoryboardPassing evaluates pass state at the start of each break like osu!stable does
+func (player *Player) updateStoryboardPassing() {
+	storyboard := player.background.GetStoryboard()
+	ruleset := player.getRuleset()
+
+	if _, ok := player.overlay.(*overlays.ScoreOverlay); !ok || storyboard == nil || ruleset == nil {
+		return
+	}
+
+	cursor := player.controller.GetCursors()[0]
+
+	for ; player.sbBreakIndex < len(player.bMap.Pauses); player.sbBreakIndex++ {
+		if player.progressMsF < player.bMap.Pauses[player.sbBreakIndex].GetStartTime() {
+			break
+		}
+
+		storyboard.SetPassing(player.progressMsF, !ruleset.IsFailed(cursor) && ruleset.GetHP(cursor) >= 0.5)
+	}
+}
+
 func (player *Player) Update(delta float64) bool {
 	speed := 1.0
 
 	if player.musicPlayer.GetState() == bass.MusicPlaying {
 		speed = player.musicPlayer.GetSpeed()
 	} else if !(player.progressMsF < player.startPointE || player.start) {
-		speed = settings.SPEED * player.bMap.Diff.GetSpeed()
+		speed = settings.SPEED * player.getMapSpeed()
 	}
 
 	player.rawPositionF += delta * speed
@@ -668,6 +840,63 @@ func (player *Player) Update(delta float64) bool {
 	return false
 }
 
+func (player *Player) GetBeatMap() *beatmap.BeatMap {
+	return player.bMap
+}
+
+// GetThumbnailInfo returns map details and the current result of the best player
+func (player *Player) GetThumbnailInfo() thumbnail.Info {
+	info := thumbnail.Info{
+		BeatMap: player.bMap,
+		Mods:    player.bMap.Diff.GetModString(),
+		Stars:   player.bMap.Stars,
+	}
+
+	switch controller := player.controller.(type) {
+	case *dance.TaikoController:
+		cursor := controller.GetCursors()[0]
+		score := controller.GetRuleset().GetScore(cursor)
+
+		info.HasScore, info.Player = true, cursor.Name
+		info.Score, info.Accuracy, info.Combo, info.Misses, info.Grade = score.Score, score.Accuracy, score.Combo, score.CountMiss, score.Grade
+	case *dance.ManiaController:
+		cursor := controller.GetCursors()[0]
+		score := controller.GetRuleset().GetScore(cursor)
+
+		info.HasScore, info.Player = true, cursor.Name
+		info.Score, info.Accuracy, info.Combo, info.Misses, info.Grade = score.Score, score.Accuracy, score.Combo, score.CountMiss, score.Grade
+	default:
+		ruleset := player.getRuleset()
+		if ruleset == nil {
+			break
+		}
+
+		var best *graphics.Cursor
+
+		for _, cursor := range player.controller.GetCursors() {
+			if best == nil || ruleset.GetScore(cursor).Score > ruleset.GetScore(best).Score {
+				best = cursor
+			}
+		}
+
+		if best == nil {
+			break
+		}
+
+		score := ruleset.GetScore(best)
+		attributes := ruleset.GetAttributes(best)
+
+		info.Mods = ruleset.GetPlayerDifficulty(best).GetModString()
+		info.Stars, info.MaxCombo = attributes.Total, attributes.MaxCombo
+
+		info.HasScore, info.Player = true, best.Name
+		info.Score, info.Accuracy, info.Combo, info.Misses, info.Grade = score.Score, score.Accuracy, score.Combo, score.CountMiss, score.Grade
+		info.PP = score.PP.Total
+	}
+
+	return info
+}
+
 func (player *Player) GetTime() float64 {
 	return player.progressMsF
 }
@@ -722,7 +951,11 @@ func (player *Player) updateMain(delta float64) {
 	speedAdjust := mutils.Lerp(1, settings.SPEED, player.speedGlider.GetValue())
 	freqAdjust := 1.0
 
-	speedVal := mutils.Lerp(1, player.bMap.Diff.GetSpeed(), player.speedGlider.GetValue())
+	if player.adaptiveSpeed != nil {
+		player.adaptiveSpeed.Update(player.progressMsF)
+	}
+
+	speedVal := mutils.Lerp(1, player.getMapSpeed(), player.speedGlider.GetValue())
 	if player.bMap.Diff.AdjustsPitch() {
 		freqAdjust = speedVal
 	} else {
@@ -738,7 +971,9 @@ func (player *Player) updateMain(delta float64) {
 			player.bMap.Update(player.progressMsF)
 		}
 
-		player.objectContainer.Update(player.progressMsF)
+		if !player.hasOwnPlayfield() {
+			player.objectContainer.Update(player.progressMsF)
+		}
 	}
 
 	if player.progressMsF >= player.startPointE || settings.PLAY {
@@ -748,6 +983,8 @@ func (player *Player) updateMain(delta float64) {
 			if player.nightcore != nil {
 				player.nightcore.Update(player.progressMsF)
 			}
+
+			player.updateStoryboardPassing()
 		} else if settings.Gameplay.ShowResultsScreen {
 			if player.overlay != nil {
 				player.overlay.DisableAudioSubmission(true)
@@ -766,6 +1003,8 @@ func (player *Player) updateMain(delta float64) {
 		player.overlay.Update(player.progressMsF)
 	}
 
+	liveserver.Update(player.progressMsF, player.controller.GetCursors(), player.getRuleset())
+
 	player.updateMusic(delta)
 
 	player.coin.Update(player.progressMsF)
@@ -849,7 +1088,9 @@ func (player *Player) DrawMain(float64) {
 		bgAlpha = mutils.Clamp(bgAlpha*player.Scl, 0, 1)
 	}
 
-	player.background.Draw(player.progressMsF, player.batch, player.blurGlider.GetValue(), bgAlpha, player.bgCamera.GetProjectionView())
+	if !settings.TRANSPARENT {
+		player.background.Draw(player.progressMsF, player.batch, player.blurGlider.GetValue(), bgAlpha, player.bgCamera.GetProjectionView())
+	}
 
 	if player.progressMsF > 0 {
 		timeDiff := player.progressMsF - player.lastProgressMsF
@@ -900,19 +1141,23 @@ func (player *Player) DrawMain(float64) {
 		player.drawOverlayPart(player.overlay.DrawBeforeObjects, cursorColors, objectCameras[0], player.objectsAlphaFail.GetValue())
 	}
 
-	player.objectContainer.Draw(player.batch, player.mainCamera.GetProjectionView(), objectCameras, player.progressMsF, float32(player.Scl), float32(player.objectsAlpha.GetValue()*player.objectsAlphaFail.GetValue()))
+	if !player.hasOwnPlayfield() {
+		player.objectContainer.Draw(player.batch, player.mainCamera.GetProjectionView(), objectCameras, player.progressMsF, float32(player.Scl), float32(player.objectsAlpha.GetValue()*player.objectsAlphaFail.GetValue()))
+	}
 
 	if player.overlay != nil {
 		player.drawOverlayPart(player.overlay.DrawNormal, cursorColors, objectCameras[0], 1)
 	}
 
-	player.background.DrawOverlay(player.progressMsF, player.batch, bgAlpha, player.bgCamera.GetProjectionView())
+	if !settings.TRANSPARENT {
+		player.background.DrawOverlay(player.progressMsF, player.batch, bgAlpha, player.bgCamera.GetProjectionView())
+	}
 
 	if player.overlay != nil && player.overlay.ShouldDrawHUDBeforeCursor() {
 		player.drawOverlayPart(player.overlay.DrawHUD, cursorColors, player.uiCamera.GetProjectionView(), 1)
 	}
 
-	if settings.Playfield.DrawCursors {
+	if settings.Playfield.DrawCursors && !player.hasOwnPlayfield() {
 		for _, g := range player.controller.GetCursors() {
 			g.UpdateRenderer()
 		}
@@ -963,6 +1208,7 @@ func (player *Player) Draw(d float64) {
 	profiler.StartGroup("Player.Draw", profiler.PDraw)
 
 	player.DrawMain(d)
+	player.drawSeekBar()
 	player.drawDebug()
 
 	profiler.EndGroup()
@@ -1226,4 +1472,12 @@ func (player *Player) Show() {}
 
 func (player *Player) Hide() {}
 
-func (player *Player) Dispose() {}
+func (player *Player) Dispose() {
+	player.musicPlayer.Stop() // it would be mixed into the next recording otherwise
+
+	if player.judgementLog != nil {
+		player.judgementLog.Close()
+	}
+
+	liveserver.ClearMap()
+}
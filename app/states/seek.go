@@ -0,0 +1,293 @@
+package states
+
+import (
+	"fmt"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/objects"
+	"github.com/wieku/danser-go/app/dance"
+	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/app/input"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/framework/bass"
+	"github.com/wieku/danser-go/framework/goroutines"
+	"github.com/wieku/danser-go/framework/math/mutils"
+	"github.com/wieku/danser-go/framework/math/vector"
+	"log"
+	"math"
+)
+
+const (
+	seekStep      = 5000.0
+	seekStepShift = 30000.0
+
+	seekBarHeight      = 6.0
+	seekBarHoverHeight = 12.0
+	seekBarHoverArea   = 60.0
+	seekBarShowTime    = 1500.0
+)
+
+// isSeekable returns true if the map is watched in replay/knockout mode, where all state can be simulated again
+func (player *Player) isSeekable() bool {
+	if settings.PLAY || settings.RECORD || !settings.KNOCKOUT {
+		return false
+	}
+
+	_, ok := player.controller.(*dance.ReplayController)
+
+	return ok
+}
+
+func (player *Player) seekKeyEvent(_ *glfw.Window, key glfw.Key, _ int, action glfw.Action, mods glfw.ModifierKey) {
+	if action == glfw.Release || (key != glfw.KeyLeft && key != glfw.KeyRight) {
+		return
+	}
+
+	step := seekStep
+	if mods&glfw.ModShift > 0 {
+		step = seekStepShift
+	}
+
+	if key == glfw.KeyLeft {
+		step = -step
+	}
+
+	player.seekMutex.Lock()
+
+	if !player.seekPending {
+		player.seekTarget = player.progressMsF
+	}
+
+	player.seekTarget += step * settings.SPEED * player.bMap.Diff.GetSpeed()
+	player.seekPending = true
+
+	player.seekMutex.Unlock()
+}
+
+func (player *Player) requestSeek(time float64) {
+	player.seekMutex.Lock()
+
+	player.seekTarget = time
+	player.seekPending = true
+
+	player.seekMutex.Unlock()
+}
+
+// processSeek is called by the update thread, seeking is done on the main thread so nothing is drawn while the state is rebuilt
+func (player *Player) processSeek() bool {
+	player.seekMutex.Lock()
+
+	target, pending := player.seekTarget, player.seekPending
+	player.seekPending = false
+
+	player.seekMutex.Unlock()
+
+	if !pending || !player.start {
+		return false
+	}
+
+	goroutines.CallMain(func() {
+		player.seek(target)
+	})
+
+	return true
+}
+
+func (player *Player) seek(target float64) {
+	target = mutils.Clamp(target, max(0, player.startPoint), player.mapEndL-1)
+
+	if math.Abs(target-player.progressMsF) < 1 {
+		return
+	}
+
+	log.Println(fmt.Sprintf("Seeking to %s", formatSeekTime(target)))
+
+	// Offsets applied to music position
+	offset := player.progressMsF - player.rawPositionF
+
+	from := player.progressMsF
+
+	if target < player.progressMsF {
+		player.rewind()
+
+		from = player.startPointE
+	}
+
+	player.fastForward(from, target)
+
+	player.progressMsF = target
+	player.rawPositionF = target - offset
+	player.lastMusicPos = player.rawPositionF
+
+	if player.musicPlayer.GetState() == bass.MusicPaused {
+		player.musicPlayer.Resume()
+	} else if player.musicPlayer.GetState() == bass.MusicStopped {
+		player.musicPlayer.Play()
+	}
+
+	player.musicPlayer.SetPosition(max(0, player.rawPositionF) / 1000)
+
+	if player.overlay != nil {
+		player.overlay.SetMusic(player.musicPlayer)
+	}
+
+	player.seekBarVisibleUntil = player.realTime + seekBarShowTime
+}
+
+// rewind brings back the map to its initial state, hit objects are parsed again because they can't be reverted.
+// Loaded replays and difficulty attributes are kept, see initTimeline.
+func (player *Player) rewind() {
+	for _, o := range player.bMap.HitObjects {
+		o.Finalize()
+	}
+
+	player.bMap.Clear()
+	player.bMap.Pauses = nil
+
+	beatmap.ParseTimingPointsAndPauses(player.bMap)
+	beatmap.ParseObjects(player.bMap, false, false, false)
+
+	if storyboard := player.background.GetStoryboard(); storyboard != nil {
+		storyboard.Rewind()
+	}
+
+	player.failing = false
+	player.failed = false
+	player.sbBreakIndex = 0
+
+	player.initTimeline()
+}
+
+// fastForward simulates everything up to the given time without submitting any sounds
+func (player *Player) fastForward(from, target float64) {
+	var active []objects.IHitObject
+
+	for _, o := range player.bMap.HitObjects {
+		if o.GetStartTime() > target {
+			break
+		}
+
+		o.DisableAudioSubmission(true)
+
+		if o.GetEndTime() > target {
+			active = append(active, o)
+		}
+	}
+
+	if player.overlay != nil {
+		player.overlay.DisableAudioSubmission(true)
+	}
+
+	for t := math.Floor(max(from, player.startPointE)) + 1; t < target; t++ {
+		player.controller.Update(t, 1)
+
+		if player.overlay != nil && int64(t)%16 == 0 {
+			player.overlay.Update(t)
+		}
+	}
+
+	player.controller.Update(target, 1)
+
+	if player.overlay != nil {
+		player.overlay.Update(target)
+		player.overlay.DisableAudioSubmission(false)
+	}
+
+	// Sliders and spinners that are still in progress should be heard
+	for _, o := range active {
+		o.DisableAudioSubmission(false)
+	}
+}
+
+// updateSeekBar handles mouse input, it has to be called from the main thread
+func (player *Player) updateSeekBar() {
+	wW, wH := input.Win.GetSize()
+	if wW == 0 || wH == 0 {
+		return
+	}
+
+	mX, mY := input.Win.GetCursorPos()
+
+	x := mutils.Clamp(mX/float64(wW), 0, 1)
+	y := mY / float64(wH) * player.ScaledHeight
+
+	player.seekBarHovered = input.Focused && y >= player.ScaledHeight-seekBarHoverArea && y <= player.ScaledHeight
+
+	pressed := input.Win.GetMouseButton(glfw.MouseButtonLeft) == glfw.Press
+
+	if pressed && !player.seekBarPressed && player.seekBarHovered {
+		player.seekBarDragging = true
+	}
+
+	if player.seekBarDragging {
+		player.seekBarDragPos = x
+
+		if !pressed {
+			player.seekBarDragging = false
+
+			player.requestSeek(player.startOffset + x*(player.MapEnd-player.startOffset))
+		}
+	}
+
+	player.seekBarPressed = pressed
+}
+
+func (player *Player) drawSeekBar() {
+	if !player.isSeekable() {
+		return
+	}
+
+	player.updateSeekBar()
+
+	visible := player.seekBarHovered || player.seekBarDragging || player.realTime < player.seekBarVisibleUntil
+	if !visible {
+		return
+	}
+
+	progress := mutils.Clamp(player.GetTimeOffset()/player.RunningTime, 0, 1)
+	if player.seekBarDragging {
+		progress = player.seekBarDragPos
+	}
+
+	height := seekBarHeight
+	if player.seekBarHovered || player.seekBarDragging {
+		height = seekBarHoverHeight
+	}
+
+	pixel := graphics.Pixel.GetRegion()
+
+	player.batch.Begin()
+	player.batch.ResetTransform()
+	player.batch.SetCamera(player.uiCamera.GetProjectionView())
+
+	player.batch.SetColor(0, 0, 0, 0.6)
+	player.batch.SetTranslation(vector.NewVec2d(player.ScaledWidth/2, player.ScaledHeight-height/2))
+	player.batch.SetScale(player.ScaledWidth/2, height/2)
+	player.batch.DrawUnit(pixel)
+
+	player.batch.SetColor(1, 1, 1, 0.8)
+	player.batch.SetTranslation(vector.NewVec2d(player.ScaledWidth*progress/2, player.ScaledHeight-height/2))
+	player.batch.SetScale(player.ScaledWidth*progress/2, height/2)
+	player.batch.DrawUnit(pixel)
+
+	player.batch.ResetTransform()
+	player.batch.SetColor(1, 1, 1, 1)
+
+	size := 20.0
+	text := fmt.Sprintf("%s / %s", formatSeekTime(player.startOffset+progress*(player.MapEnd-player.startOffset)), formatSeekTime(player.MapEnd))
+
+	player.font.DrawOrigin(player.batch, player.ScaledWidth*progress, player.ScaledHeight-height-size*0.2, vector.BottomCentre, size, true, text)
+
+	player.batch.End()
+}
+
+func formatSeekTime(time float64) string {
+	sign := ""
+	if time < 0 {
+		sign = "-"
+	}
+
+	seconds := int(math.Abs(time) / 1000)
+
+	return fmt.Sprintf("%s%d:%02d", sign, seconds/60, seconds%60)
+}
@@ -0,0 +1,183 @@
+package status
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"sync"
+)
+
+// Exit codes of danser's main mode, commands use 2 for invalid usage
+const (
+	ExitOK              = 0
+	ExitError           = 1
+	ExitBeatmapNotFound = 3
+	ExitReplayError     = 4
+	ExitFFmpegError     = 5
+	ExitAborted         = 6
+)
+
+const (
+	StageDatabase = "database"
+	StageBeatmap  = "beatmap"
+	StageEncoding = "encoding"
+	StageMuxing   = "muxing"
+)
+
+type stageMessage struct {
+	Type  string `json:"type"`
+	Stage string `json:"stage"`
+}
+
+type progressMessage struct {
+	Type        string  `json:"type"`
+	Frames      int64   `json:"frames"`
+	TotalFrames int64   `json:"totalFrames"`
+	Progress    float64 `json:"progress"`
+	FPS         float64 `json:"fps"`
+	Speed       float64 `json:"speed"`
+	ETA         float64 `json:"eta"`
+}
+
+type doneMessage struct {
+	Type   string `json:"type"`
+	Output string `json:"output"`
+}
+
+type jobMessage struct {
+	Type   string `json:"type"`
+	Index  int    `json:"index"`
+	Total  int    `json:"total"`
+	Output string `json:"output"`
+	Code   int    `json:"code"`
+	Error  string `json:"error,omitempty"`
+}
+
+type errorMessage struct {
+	Type  string `json:"type"`
+	Code  int    `json:"code"`
+	Error string `json:"error"`
+}
+
+var writer io.WriteCloser
+var mutex sync.Mutex
+
+// Start begins sending status messages as JSON lines to the given writer
+func Start(w io.WriteCloser) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	writer = w
+}
+
+func Stop() {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if writer == nil {
+		return
+	}
+
+	_ = writer.Close()
+
+	writer = nil
+}
+
+// send writes the message as a JSON line, status output is disabled only if writing fails
+func send(msg any) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Println("Failed to encode status:", err)
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if writer == nil {
+		return
+	}
+
+	if _, err = writer.Write(append(data, '\n')); err != nil {
+		log.Println("Failed to send status:", err)
+
+		writer = nil
+	}
+}
+
+func SetStage(stage string) {
+	send(stageMessage{Type: "stage", Stage: stage})
+}
+
+// Progress reports rendering progress in percent and rendered video frames. fps is the encoding speed in frames per second,
+// speed is relative to map's playback and eta is in seconds. Values that can't be measured yet are sent as 0, eta as -1.
+func Progress(progress float64, frames, totalFrames int64, fps, speed, eta float64) {
+	send(progressMessage{
+		Type:        "progress",
+		Frames:      frames,
+		TotalFrames: totalFrames,
+		Progress:    finiteOr(progress, 0),
+		FPS:         finiteOr(fps, 0),
+		Speed:       finiteOr(speed, 0),
+		ETA:         finiteOr(eta, -1),
+	})
+}
+
+// finiteOr replaces infinities and NaNs, they can't be encoded as JSON
+func finiteOr(value, fallback float64) float64 {
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		return fallback
+	}
+
+	return value
+}
+
+func Finished(output string) {
+	send(doneMessage{Type: "done", Output: output})
+}
+
+// JobFinished reports the result of a single job in -jobs mode, cause is empty if the job succeeded
+func JobFinished(index, total int, output string, code int, cause string) {
+	send(jobMessage{Type: "job", Index: index, Total: total, Output: output, Code: code, Error: cause})
+}
+
+func Failed(code int, cause string) {
+	send(errorMessage{Type: "error", Code: code, Error: cause})
+}
+
+// Failure is an error that should end danser with a specific exit code
+type Failure struct {
+	Code int
+	Err  error
+}
+
+func (f *Failure) Error() string {
+	return f.Err.Error()
+}
+
+func (f *Failure) Unwrap() error {
+	return f.Err
+}
+
+func Fail(code int, err error) *Failure {
+	return &Failure{Code: code, Err: err}
+}
+
+func Failf(code int, format string, args ...any) *Failure {
+	return &Failure{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+// GetExitCode returns exit code for a recovered panic value
+func GetExitCode(err any) int {
+	if e, ok := err.(error); ok {
+		var failure *Failure
+		if errors.As(e, &failure) {
+			return failure.Code
+		}
+	}
+
+	return ExitError
+}
@@ -2,6 +2,7 @@ package storyboard
 
 import (
 	"fmt"
+	"github.com/wieku/danser-go/app/audio"
 	"github.com/wieku/danser-go/app/beatmap"
 	"github.com/wieku/danser-go/app/settings"
 	"github.com/wieku/danser-go/app/skin"
@@ -19,10 +20,14 @@ import (
 	"github.com/wieku/danser-go/framework/profiler"
 	"github.com/wieku/danser-go/framework/qpc"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 type Storyboard struct {
@@ -34,6 +39,7 @@ type Storyboard struct {
 	samples map[string]*bass.Sample
 
 	background  *sprite.Manager
+	fail        *sprite.Manager
 	pass        *sprite.Manager
 	foreground  *sprite.Manager
 	overlay     *sprite.Manager
@@ -49,6 +55,14 @@ type Storyboard struct {
 
 	videos     []sprite.ISprite
 	videoAlpha float64
+
+	triggered  []*triggeredSprite
+	events     []triggerEvent
+	eventMutex *sync.Mutex
+	failing    atomic.Bool
+
+	videoCache  map[string]*video2.Video
+	updateMutex *sync.Mutex
 }
 
 func getSection(line string) string {
@@ -62,18 +76,61 @@ func getSection(line string) string {
 
 func NewStoryboard(beatMap *beatmap.BeatMap) *Storyboard {
 	storyboard := &Storyboard{
-		beatMap:    beatMap,
-		textures:   make(map[string]*texture.TextureRegion),
-		samples:    make(map[string]*bass.Sample),
-		zIndex:     -1,
-		background: sprite.NewManager(),
-		pass:       sprite.NewManager(),
-		foreground: sprite.NewManager(),
-		overlay:    sprite.NewManager(),
-		atlas:      nil,
-		videos:     make([]sprite.ISprite, 0),
+		beatMap:     beatMap,
+		textures:    make(map[string]*texture.TextureRegion),
+		samples:     make(map[string]*bass.Sample),
+		atlas:       nil,
+		videoCache:  make(map[string]*video2.Video),
+		eventMutex:  &sync.Mutex{},
+		updateMutex: &sync.Mutex{},
 	}
 
+	hasVideo, hasAudio := storyboard.parse()
+
+	storyboard.hasVisuals = storyboard.numSprites > 0 || hasVideo
+
+	if storyboard.numSprites == 0 {
+		if storyboard.atlas != nil {
+			storyboard.atlas.Dispose()
+			storyboard.atlas = nil
+		}
+
+		if !hasVideo && !hasAudio {
+			return nil
+		} else if !storyboard.widescreen {
+			storyboard.widescreen = true
+		}
+	}
+
+	for k := range storyboard.textures {
+		if k == beatMap.Bg {
+			storyboard.bgFileUsed = true
+		}
+	}
+
+	log.Println("Storyboard loaded")
+
+	storyboard.currentTime = -1000000
+	storyboard.limiter = frame.NewLimiter(1000)
+	storyboard.counter = frame.NewCounter()
+
+	return storyboard
+}
+
+// parse loads all sprites from .osu and .osb files into fresh layers, already loaded textures, samples and videos are reused
+func (storyboard *Storyboard) parse() (hasVideo, hasAudio bool) {
+	beatMap := storyboard.beatMap
+
+	storyboard.zIndex = -1
+	storyboard.numSprites = 0
+	storyboard.background = sprite.NewManager()
+	storyboard.fail = sprite.NewManager()
+	storyboard.pass = sprite.NewManager()
+	storyboard.foreground = sprite.NewManager()
+	storyboard.overlay = sprite.NewManager()
+	storyboard.videos = make([]sprite.ISprite, 0)
+	storyboard.triggered = nil
+
 	files := []string{
 		filepath.Join(settings.General.GetSongsDir(), beatMap.Dir, beatMap.File),
 	}
@@ -87,8 +144,6 @@ func NewStoryboard(beatMap *beatmap.BeatMap) *Storyboard {
 	var commands []string
 
 	variables := make([][2]string, 0)
-	hasVideo := false
-	hasAudio := false
 
 	for _, fS := range files {
 		file, err := os.Open(fS)
@@ -161,12 +216,13 @@ func NewStoryboard(beatMap *beatmap.BeatMap) *Storyboard {
 						continue
 					}
 
-					video := video2.NewVideo(fPath, -1, vector.NewVec2d(320, 240), vector.Centre)
+					video := storyboard.getVideo(fPath)
 
 					if video == nil {
 						continue
 					}
 
+					video.ClearTransformations()
 					video.SetScaleV(vector.NewVec2d(1, 1).Scl(480.0 / float64(video.Texture.Height)))
 
 					offset, _ := strconv.ParseFloat(spl[1], 64)
@@ -205,33 +261,22 @@ func NewStoryboard(beatMap *beatmap.BeatMap) *Storyboard {
 		file.Close()
 	}
 
-	storyboard.hasVisuals = storyboard.numSprites > 0 || hasVideo
-
-	if storyboard.numSprites == 0 {
-		if storyboard.atlas != nil {
-			storyboard.atlas.Dispose()
-		}
+	return
+}
 
-		if !hasVideo && !hasAudio {
-			return nil
-		} else if !storyboard.widescreen {
-			storyboard.widescreen = true
-		}
-	}
+// Rewind reloads the storyboard so it can be played again from the beginning
+func (storyboard *Storyboard) Rewind() {
+	storyboard.updateMutex.Lock()
+	defer storyboard.updateMutex.Unlock()
 
-	for k := range storyboard.textures {
-		if k == beatMap.Bg {
-			storyboard.bgFileUsed = true
-		}
-	}
+	storyboard.parse()
 
-	log.Println("Storyboard loaded")
+	storyboard.eventMutex.Lock()
+	storyboard.events = nil
+	storyboard.eventMutex.Unlock()
 
+	storyboard.failing.Store(false)
 	storyboard.currentTime = -1000000
-	storyboard.limiter = frame.NewLimiter(1000)
-	storyboard.counter = frame.NewCounter()
-
-	return storyboard
 }
 
 func (storyboard *Storyboard) loadSprite(currentSprite string, commands []string) {
@@ -281,23 +326,64 @@ func (storyboard *Storyboard) loadSprite(currentSprite string, commands []string
 	if len(textures) != 0 {
 		sbSprite := sprite.NewAnimation(textures, frameDelay, loopForever, float64(storyboard.zIndex), pos, origin)
 
-		transforms := parseCommands(commands)
+		transforms, triggers := parseCommands(commands)
+
+		triggers = slices.DeleteFunc(triggers, func(t *TriggerProcessor) bool {
+			return len(t.transforms) == 0
+		})
 
 		sbSprite.ShowForever(false)
 		sbSprite.AddTransforms(transforms)
 		sbSprite.AdjustTimesToTransformations()
 		sbSprite.ResetValuesToTransforms()
 
+		if len(triggers) > 0 {
+			storyboard.addTriggers(sbSprite, transforms, triggers)
+		}
+
 		storyboard.addSpriteToLayer(spl[1], sbSprite)
 
 		storyboard.numSprites++
 	}
 }
 
+func (storyboard *Storyboard) addTriggers(sbSprite *sprite.Animation, transforms []*animation.Transformation, triggers []*TriggerProcessor) {
+	startTime := math.MaxFloat64
+	endTime := -math.MaxFloat64
+
+	if len(transforms) > 0 {
+		startTime = sbSprite.GetStartTime()
+		endTime = sbSprite.GetEndTime()
+	}
+
+	for _, t := range triggers {
+		tStart, tEnd := t.getTimeRange()
+
+		startTime = min(startTime, tStart)
+		endTime = max(endTime, tEnd)
+	}
+
+	// Sprite has to be alive for the whole trigger window
+	sbSprite.SetStartTime(startTime)
+	sbSprite.SetEndTime(endTime)
+
+	// Sprites that are faded only by triggers stay hidden until triggered
+	if !slices.ContainsFunc(transforms, func(t *animation.Transformation) bool { return t.GetType() == animation.Fade }) {
+		sbSprite.SetAlpha(0)
+	}
+
+	storyboard.triggered = append(storyboard.triggered, &triggeredSprite{
+		sprite:   sbSprite,
+		triggers: triggers,
+	})
+}
+
 func (storyboard *Storyboard) addSpriteToLayer(layer string, sbSprite sprite.ISprite) {
 	switch layer {
 	case "0", "Background":
 		storyboard.background.Add(sbSprite)
+	case "1", "Fail":
+		storyboard.fail.Add(sbSprite)
 	case "2", "Pass":
 		storyboard.pass.Add(sbSprite)
 	case "3", "Foreground":
@@ -351,6 +437,18 @@ func (storyboard *Storyboard) getTexture(image string) *texture.TextureRegion {
 	return texture1
 }
 
+func (storyboard *Storyboard) getVideo(path string) (video *video2.Video) {
+	if video = storyboard.videoCache[path]; video == nil {
+		video = video2.NewVideo(path, -1, vector.NewVec2d(320, 240), vector.Centre)
+
+		if video != nil {
+			storyboard.videoCache[path] = video
+		}
+	}
+
+	return
+}
+
 func (storyboard *Storyboard) getSample(sample string) (bassSample *bass.Sample) {
 	if bassSample = storyboard.samples[sample]; bassSample == nil {
 		path, err := storyboard.beatMap.GetRelatedFile(sample)
@@ -359,7 +457,7 @@ func (storyboard *Storyboard) getSample(sample string) (bassSample *bass.Sample)
 			return
 		}
 
-		bassSample = bass.NewSample(path)
+		bassSample = bass.NewSample(path).WithStem(bass.StemStoryboard)
 	}
 
 	return
@@ -407,8 +505,69 @@ func (storyboard *Storyboard) SetFPS(i int) {
 	storyboard.limiter.FPS = i
 }
 
+// TriggerHitSound activates HitSound triggers matching the played hit sound
+func (storyboard *Storyboard) TriggerHitSound(time float64, hitSound audio.HitSound) {
+	storyboard.queueEvent(triggerEvent{
+		time:     time,
+		tType:    triggerHitSound,
+		hitSound: hitSound,
+	})
+}
+
+// SetPassing switches between Pass and Fail layers, Passing or Failing triggers are activated on state change
+func (storyboard *Storyboard) SetPassing(time float64, passing bool) {
+	if !storyboard.failing.CompareAndSwap(passing, !passing) {
+		return
+	}
+
+	tType := triggerPassing
+	if !passing {
+		tType = triggerFailing
+	}
+
+	storyboard.queueEvent(triggerEvent{
+		time:  time,
+		tType: tType,
+	})
+}
+
+func (storyboard *Storyboard) queueEvent(event triggerEvent) {
+	if len(storyboard.triggered) == 0 {
+		return
+	}
+
+	storyboard.eventMutex.Lock()
+	storyboard.events = append(storyboard.events, event)
+	storyboard.eventMutex.Unlock()
+}
+
+func (storyboard *Storyboard) processEvents() {
+	storyboard.eventMutex.Lock()
+	events := storyboard.events
+	storyboard.events = nil
+	storyboard.eventMutex.Unlock()
+
+	for _, e := range events {
+		for _, ts := range storyboard.triggered {
+			ts.activate(e.time, func(trigger *TriggerProcessor) bool {
+				if e.tType == triggerHitSound {
+					return trigger.matchesHitSound(e.hitSound)
+				}
+
+				return trigger.tType == e.tType
+			})
+		}
+	}
+}
+
 func (storyboard *Storyboard) Update(time float64) {
+	storyboard.updateMutex.Lock()
+	defer storyboard.updateMutex.Unlock()
+
+	storyboard.processEvents()
+
 	storyboard.background.Update(time)
+	storyboard.fail.Update(time)
 	storyboard.pass.Update(time)
 	storyboard.foreground.Update(time)
 	storyboard.overlay.Update(time)
@@ -428,7 +587,13 @@ func (storyboard *Storyboard) Draw(time float64, batch *batch.QuadBatch) {
 	profiler.StartGroup("Storyboard.Draw", profiler.PDraw)
 	batch.SetTranslation(vector.NewVec2d(-64, -48))
 	storyboard.background.Draw(time, batch)
-	storyboard.pass.Draw(time, batch)
+
+	if storyboard.failing.Load() {
+		storyboard.fail.Draw(time, batch)
+	} else {
+		storyboard.pass.Draw(time, batch)
+	}
+
 	storyboard.foreground.Draw(time, batch)
 	batch.SetTranslation(vector.NewVec2d(0, 0))
 	profiler.EndGroup()
@@ -443,15 +608,15 @@ func (storyboard *Storyboard) DrawOverlay(time float64, batch *batch.QuadBatch)
 }
 
 func (storyboard *Storyboard) GetRenderedSprites() int {
-	return storyboard.background.GetNumRendered() + storyboard.pass.GetNumRendered() + storyboard.foreground.GetNumRendered() + storyboard.overlay.GetNumRendered()
+	return storyboard.background.GetNumRendered() + storyboard.fail.GetNumRendered() + storyboard.pass.GetNumRendered() + storyboard.foreground.GetNumRendered() + storyboard.overlay.GetNumRendered()
 }
 
 func (storyboard *Storyboard) GetProcessedSprites() int {
-	return storyboard.background.GetNumProcessed() + storyboard.pass.GetNumProcessed() + storyboard.foreground.GetNumProcessed() + storyboard.overlay.GetNumProcessed()
+	return storyboard.background.GetNumProcessed() + storyboard.fail.GetNumProcessed() + storyboard.pass.GetNumProcessed() + storyboard.foreground.GetNumProcessed() + storyboard.overlay.GetNumProcessed()
 }
 
 func (storyboard *Storyboard) GetQueueSprites() int {
-	return storyboard.background.GetNumInQueue() + storyboard.pass.GetNumInQueue() + storyboard.foreground.GetNumInQueue() + storyboard.overlay.GetNumInQueue()
+	return storyboard.background.GetNumInQueue() + storyboard.fail.GetNumInQueue() + storyboard.pass.GetNumInQueue() + storyboard.foreground.GetNumInQueue() + storyboard.overlay.GetNumInQueue()
 }
 
 func (storyboard *Storyboard) GetTotalSprites() int {
@@ -0,0 +1,224 @@
+package storyboard
+
+import (
+	"github.com/wieku/danser-go/app/audio"
+	"github.com/wieku/danser-go/framework/graphics/sprite"
+	"github.com/wieku/danser-go/framework/math/animation"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type triggerType int
+
+const (
+	triggerHitSound triggerType = iota
+	triggerPassing
+	triggerFailing
+)
+
+var triggerSampleSets = []string{"All", "Normal", "Soft", "Drum"}
+
+var triggerAdditions = map[string]int{
+	"Whistle": 2,
+	"Finish":  4,
+	"Clap":    8,
+}
+
+type TriggerProcessor struct {
+	tType triggerType
+
+	sampleSet   int
+	additionSet int
+	addition    int
+	customIndex int
+
+	start, end float64
+	group      int64
+
+	transforms []*animation.Transformation
+
+	active []*animation.Transformation
+}
+
+// NewTriggerProcessor parses a trigger command, nil is returned if it's malformed.
+// Start time, end time and group are optional, like in osu!lazer trigger is active during the whole map by default and belongs to group 0.
+func NewTriggerProcessor(data []string) *TriggerProcessor {
+	if len(data) < 2 {
+		log.Println("Skipping storyboard trigger without a name:", data)
+		return nil
+	}
+
+	trigger := &TriggerProcessor{
+		customIndex: -1,
+		start:       -math.MaxFloat64,
+		end:         math.MaxFloat64,
+	}
+
+	trigger.parseName(strings.TrimSpace(data[1]))
+
+	field := func(i int) string {
+		if i < len(data) {
+			return strings.TrimSpace(data[i])
+		}
+
+		return ""
+	}
+
+	var err error
+
+	if f := field(2); f != "" {
+		trigger.start, err = strconv.ParseFloat(f, 64)
+	}
+
+	if f := field(3); f != "" && err == nil {
+		trigger.end, err = strconv.ParseFloat(f, 64)
+	}
+
+	if f := field(4); f != "" && err == nil {
+		trigger.group, err = strconv.ParseInt(f, 10, 64)
+	}
+
+	if err != nil {
+		log.Println("Skipping malformed storyboard trigger:", data, err)
+		return nil
+	}
+
+	return trigger
+}
+
+// parseName parses trigger names in HitSound[SampleSet][AdditionsSampleSet][Addition][CustomSampleSet] format, Passing and Failing
+func (trigger *TriggerProcessor) parseName(name string) {
+	switch {
+	case name == "Passing":
+		trigger.tType = triggerPassing
+		return
+	case name == "Failing":
+		trigger.tType = triggerFailing
+		return
+	case !strings.HasPrefix(name, "HitSound"):
+		log.Println("Unknown storyboard trigger:", name)
+		trigger.tType = -1
+		return
+	}
+
+	trigger.tType = triggerHitSound
+
+	name = strings.TrimPrefix(name, "HitSound")
+
+	var sets []int
+
+	for len(sets) < 2 {
+		found := false
+
+		for i, s := range triggerSampleSets {
+			if strings.HasPrefix(name, s) {
+				sets = append(sets, i)
+				name = strings.TrimPrefix(name, s)
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			break
+		}
+	}
+
+	for s, v := range triggerAdditions {
+		if strings.HasPrefix(name, s) {
+			trigger.addition = v
+			name = strings.TrimPrefix(name, s)
+
+			break
+		}
+	}
+
+	switch {
+	case len(sets) == 2:
+		trigger.sampleSet, trigger.additionSet = sets[0], sets[1]
+	case len(sets) == 1 && trigger.addition > 0: // HitSoundDrumWhistle refers to the drum whistle
+		trigger.additionSet = sets[0]
+	case len(sets) == 1:
+		trigger.sampleSet = sets[0]
+	}
+
+	if name != "" {
+		if index, err := strconv.ParseInt(name, 10, 32); err == nil {
+			trigger.customIndex = int(index)
+		}
+	}
+}
+
+func (trigger *TriggerProcessor) Add(command []string) {
+	if parsed := parseCommand(command); parsed != nil {
+		trigger.transforms = append(trigger.transforms, parsed...)
+	}
+}
+
+func (trigger *TriggerProcessor) matchesHitSound(hitSound audio.HitSound) bool {
+	if trigger.tType != triggerHitSound {
+		return false
+	}
+
+	if trigger.sampleSet > 0 && trigger.sampleSet != hitSound.Info.SampleSet {
+		return false
+	}
+
+	if trigger.additionSet > 0 && trigger.additionSet != hitSound.Info.AdditionSet {
+		return false
+	}
+
+	if trigger.addition > 0 && hitSound.Sample&trigger.addition == 0 {
+		return false
+	}
+
+	return trigger.customIndex < 0 || trigger.customIndex == hitSound.Info.CustomIndex
+}
+
+func (trigger *TriggerProcessor) getTimeRange() (float64, float64) {
+	startTime := math.MaxFloat64
+	endTime := -math.MaxFloat64
+
+	for _, t := range trigger.transforms {
+		startTime = min(startTime, t.GetStartTime())
+		endTime = max(endTime, t.GetEndTime())
+	}
+
+	return trigger.start + startTime, trigger.end + endTime
+}
+
+type triggeredSprite struct {
+	sprite   *sprite.Animation
+	triggers []*TriggerProcessor
+}
+
+func (ts *triggeredSprite) activate(time float64, matches func(trigger *TriggerProcessor) bool) {
+	for _, trigger := range ts.triggers {
+		if time < trigger.start || time > trigger.end || !matches(trigger) {
+			continue
+		}
+
+		// Only one trigger from a group can be active at a time
+		for _, other := range ts.triggers {
+			if other.group == trigger.group && len(other.active) > 0 {
+				ts.sprite.RemoveTransforms(other.active)
+				other.active = nil
+			}
+		}
+
+		for _, t := range trigger.transforms {
+			trigger.active = append(trigger.active, t.Clone(time+t.GetStartTime(), time+t.GetEndTime()))
+		}
+
+		ts.sprite.AddTransforms(trigger.active)
+	}
+}
+
+type triggerEvent struct {
+	time     float64
+	tType    triggerType
+	hitSound audio.HitSound
+}
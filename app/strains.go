@@ -0,0 +1,220 @@
+package app
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/rulesets/osu/performance"
+	"github.com/wieku/danser-go/app/settings"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type strainsReport struct {
+	File            string                      `json:"file"`
+	MD5             string                      `json:"md5"`
+	Artist          string                      `json:"artist"`
+	Title           string                      `json:"title"`
+	Difficulty      string                      `json:"difficulty"`
+	Creator         string                      `json:"creator"`
+	Mods            string                      `json:"mods"`
+	PPVersion       string                      `json:"ppVersion"`
+	Stars           float64                     `json:"stars"`
+	HardestSections []performance.HardSection   `json:"hardestSections"`
+	Timeline        []performance.TimelinePoint `json:"timeline"`
+}
+
+func runStrains(args []string) int {
+	fs := flag.NewFlagSet("strains", flag.ExitOnError)
+
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: danser strains [flags] [path/to/map.osu]")
+		fmt.Fprintln(fs.Output(), "Exports per-object strains and star rating of a map together with its hardest sections. Times are in milliseconds of map time.")
+		fs.PrintDefaults()
+	}
+
+	md5 := fs.String("md5", "", "Beatmap md5 hash, beatmap is looked up in danser's database")
+	mods := fs.String("mods", "", "Mods, e.g. -mods=HDDT")
+	mods2 := fs.String("mods2", "", "Mods, lazer style. Overrides -mods")
+	ppVersion := fs.String("ppversion", "", "PP version to use. Defaults to Gameplay.PPVersion setting. Available: "+strings.Join(performance.Versions, ", "))
+
+	format := fs.String("format", "json", "Output format: json or csv")
+	spikes := fs.Bool("spikes", false, "Write only the hardest sections, without the timeline")
+	sections := fs.Int("sections", 5, "Number of hardest sections to find")
+	length := fs.Float64("length", 10, "Length of hardest sections in seconds")
+	out := fs.String("out", "", "Output file, defaults to standard output")
+
+	settingsVersion := fs.String("settings", "", "Specify settings version, used to locate the Songs directory")
+	noDbCheck := fs.Bool("nodbcheck", false, "Don't validate the database and only import new beatmap sets if there are any")
+
+	_ = fs.Parse(args)
+
+	if (*md5 == "") == (fs.NArg() == 0) || fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	if *format != "json" && *format != "csv" {
+		panic(fmt.Sprintf("Unknown format: %s", *format))
+	}
+
+	settings.LoadSettings(*settingsVersion)
+
+	modList, err := parseCalcMods(*mods, *mods2)
+	if err != nil {
+		panic(err)
+	}
+
+	if len(modList) > 1 {
+		panic("Only one mod combination can be used")
+	}
+
+	version := performance.GetVersion(settings.Gameplay.PPVersion).ID
+	if *ppVersion != "" && *ppVersion != performance.Latest {
+		if performance.GetVersion(*ppVersion).ID != *ppVersion {
+			panic(fmt.Sprintf("Unknown pp version: %s", *ppVersion))
+		}
+
+		version = *ppVersion
+	}
+
+	var job calcJob
+
+	if *md5 != "" {
+		job = loadCalcMD5s([]string{*md5}, *noDbCheck)[0]
+	} else {
+		job = loadCalcFile(fs.Arg(0))
+	}
+
+	if job.err != nil {
+		panic(job.err)
+	}
+
+	bMap := job.beatMap
+
+	if bMap.Mode != 0 {
+		panic("Only osu!standard maps are supported")
+	}
+
+	bMap.Diff.SetMods2(modList[0])
+
+	beatmap.ParseTimingPointsAndPauses(bMap)
+	beatmap.ParseObjects(bMap, true, false, false)
+
+	if len(bMap.HitObjects) < 2 {
+		panic("Beatmap needs at least 2 hit objects")
+	}
+
+	timeline := performance.CalculateTimeline(version, bMap.HitObjects, bMap.Diff)
+
+	file := job.path
+	if file == "" {
+		file = bMap.Dir + "/" + bMap.File
+	}
+
+	report := strainsReport{
+		File:            file,
+		MD5:             bMap.MD5,
+		Artist:          bMap.Artist,
+		Title:           bMap.Name,
+		Difficulty:      bMap.Difficulty,
+		Creator:         bMap.Creator,
+		Mods:            bMap.Diff.GetModString(),
+		PPVersion:       version,
+		Stars:           timeline.Points[len(timeline.Points)-1].Stars,
+		HardestSections: timeline.FindHardestSections(*length*1000, *sections),
+		Timeline:        timeline.Points,
+	}
+
+	var writer io.Writer = os.Stdout
+
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			panic(err)
+		}
+
+		defer f.Close()
+
+		writer = f
+	}
+
+	switch {
+	case *format == "json":
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "\t")
+
+		if *spikes {
+			err = encoder.Encode(report.HardestSections)
+		} else {
+			err = encoder.Encode(report)
+		}
+	case *spikes:
+		err = writeSectionsCSV(writer, report.HardestSections)
+	default:
+		err = writeTimelineCSV(writer, report.Timeline)
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	return 0
+}
+
+func writeTimelineCSV(w io.Writer, points []performance.TimelinePoint) error {
+	writer := csv.NewWriter(w)
+
+	_ = writer.Write([]string{"index", "time", "aimStrain", "speedStrain", "flashlightStrain", "strainStars", "aim", "speed", "flashlight", "stars"})
+
+	for _, p := range points {
+		_ = writer.Write([]string{
+			strconv.Itoa(p.Index),
+			formatCSVFloat(p.Time),
+			formatCSVFloat(p.AimStrain),
+			formatCSVFloat(p.SpeedStrain),
+			formatCSVFloat(p.FlashlightStrain),
+			formatCSVFloat(p.StrainStars),
+			formatCSVFloat(p.Aim),
+			formatCSVFloat(p.Speed),
+			formatCSVFloat(p.Flashlight),
+			formatCSVFloat(p.Stars),
+		})
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+func writeSectionsCSV(w io.Writer, sections []performance.HardSection) error {
+	writer := csv.NewWriter(w)
+
+	_ = writer.Write([]string{"rank", "timestamp", "start", "end", "firstObject", "lastObject", "stars", "peak", "skill"})
+
+	for i, s := range sections {
+		_ = writer.Write([]string{
+			strconv.Itoa(i + 1),
+			s.Timestamp,
+			formatCSVFloat(s.Start),
+			formatCSVFloat(s.End),
+			strconv.Itoa(s.FirstObject),
+			strconv.Itoa(s.LastObject),
+			formatCSVFloat(s.Stars),
+			formatCSVFloat(s.Peak),
+			s.Skill,
+		})
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+func formatCSVFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
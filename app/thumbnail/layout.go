@@ -0,0 +1,115 @@
+package thumbnail
+
+import (
+	"encoding/json"
+	"fmt"
+	color2 "github.com/wieku/danser-go/framework/math/color"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	slotText  = "text"
+	slotImage = "image"
+)
+
+// Layout describes what's drawn on a thumbnail, positions and sizes are in pixels of the thumbnail
+type Layout struct {
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Slots  []Slot `json:"slots"`
+}
+
+// Slot is a single text or image, slots are drawn in order
+type Slot struct {
+	Type   string  `json:"type"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Origin string  `json:"origin"`
+	Color  string  `json:"color"`
+
+	// Text can contain placeholders like {title}, slot is skipped if any of them is unknown
+	Text     string  `json:"text"`
+	Font     string  `json:"font"`
+	Size     float64 `json:"size"`
+	MaxWidth float64 `json:"maxWidth"`
+	Shadow   bool    `json:"shadow"`
+
+	// Image is "background", "grade" or a path relative to the layout file
+	Image  string  `json:"image"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+	Fit    string  `json:"fit"`
+}
+
+var defaultLayout = Layout{
+	Width:  1280,
+	Height: 720,
+	Slots: []Slot{
+		{Type: slotImage, Image: "background", Width: 1280, Height: 720, Fit: "cover", Color: "#ffffff59"},
+		{Type: slotText, Text: "{title}", X: 640, Y: 110, Origin: "Centre", Size: 76, MaxWidth: 1200, Shadow: true},
+		{Type: slotText, Text: "{artist}", X: 640, Y: 185, Origin: "Centre", Size: 40, MaxWidth: 1100, Shadow: true, Color: "#dddddd"},
+		{Type: slotText, Text: "[{version}]", X: 640, Y: 245, Origin: "Centre", Size: 44, MaxWidth: 1100, Shadow: true, Color: "#ffcc22"},
+		{Type: slotImage, Image: "grade", X: 340, Y: 460, Origin: "Centre", Height: 280},
+		{Type: slotText, Text: "{accuracy}%", X: 640, Y: 390, Origin: "CentreLeft", Size: 80, Shadow: true},
+		{Type: slotText, Text: "{pp}pp", X: 640, Y: 480, Origin: "CentreLeft", Size: 72, Shadow: true, Color: "#ff66aa"},
+		{Type: slotText, Text: "{stars}* {mods}", X: 640, Y: 565, Origin: "CentreLeft", Size: 48, MaxWidth: 600, Shadow: true},
+		{Type: slotText, Text: "{player}", X: 640, Y: 670, Origin: "Centre", Size: 40, MaxWidth: 1200, Shadow: true},
+	},
+}
+
+func loadLayout(path string) (*Layout, error) {
+	if path == "" {
+		layout := defaultLayout
+		return &layout, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	layout := new(Layout)
+
+	if err = json.Unmarshal(data, layout); err != nil {
+		return nil, err
+	}
+
+	if layout.Width < 1 || layout.Height < 1 || layout.Width > 7680 || layout.Height > 7680 {
+		return nil, fmt.Errorf("invalid size %dx%d", layout.Width, layout.Height)
+	}
+
+	for i, slot := range layout.Slots {
+		if slot.Type != slotText && slot.Type != slotImage {
+			return nil, fmt.Errorf("slot %d: unknown type %q", i+1, slot.Type)
+		}
+
+		if _, err = parseColor(slot.Color); err != nil {
+			return nil, fmt.Errorf("slot %d: %w", i+1, err)
+		}
+	}
+
+	return layout, nil
+}
+
+// parseColor reads #RRGGBB or #RRGGBBAA, empty value is white
+func parseColor(value string) (color2.Color, error) {
+	if value == "" {
+		return color2.NewL(1), nil
+	}
+
+	hex := strings.TrimPrefix(value, "#")
+
+	c, err := strconv.ParseUint(hex, 16, 32)
+	if err == nil {
+		switch len(hex) {
+		case 6:
+			return color2.NewI(uint32(c)), nil
+		case 8:
+			return color2.NewIA(uint32(c)), nil
+		}
+	}
+
+	return color2.Color{}, fmt.Errorf("invalid color %q", value)
+}
@@ -0,0 +1,291 @@
+package thumbnail
+
+import (
+	"fmt"
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/rulesets/osu"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/app/skin"
+	"github.com/wieku/danser-go/framework/assets"
+	"github.com/wieku/danser-go/framework/env"
+	"github.com/wieku/danser-go/framework/graphics/batch"
+	"github.com/wieku/danser-go/framework/graphics/blend"
+	"github.com/wieku/danser-go/framework/graphics/buffer"
+	"github.com/wieku/danser-go/framework/graphics/font"
+	"github.com/wieku/danser-go/framework/graphics/texture"
+	"github.com/wieku/danser-go/framework/graphics/viewport"
+	color2 "github.com/wieku/danser-go/framework/math/color"
+	"github.com/wieku/danser-go/framework/math/vector"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const defaultFont = "Quicksand Bold"
+
+var placeholderRegex = regexp.MustCompile(`\{(\w+)}`)
+
+// Info holds everything a thumbnail can show. Score fields are valid only if HasScore is set.
+type Info struct {
+	BeatMap  *beatmap.BeatMap
+	Mods     string
+	Stars    float64
+	MaxCombo int
+
+	HasScore bool
+	Player   string
+	Score    int64
+	Accuracy float64
+	Combo    uint
+	Misses   uint
+	Grade    osu.Grade
+	PP       float64
+}
+
+// values returns texts of placeholders, unknown values are left empty
+func (info Info) values() map[string]string {
+	values := map[string]string{
+		"artist":  info.BeatMap.Artist,
+		"title":   info.BeatMap.Name,
+		"version": info.BeatMap.Difficulty,
+		"creator": info.BeatMap.Creator,
+		"mods":    info.Mods,
+	}
+
+	if info.Stars > 0 {
+		values["stars"] = fmt.Sprintf("%.2f", info.Stars)
+	}
+
+	if info.MaxCombo > 0 {
+		values["maxcombo"] = strconv.Itoa(info.MaxCombo)
+	}
+
+	if info.HasScore {
+		values["player"] = info.Player
+		values["score"] = strconv.FormatInt(info.Score, 10)
+		values["accuracy"] = fmt.Sprintf("%.2f", info.Accuracy*100)
+		values["combo"] = strconv.FormatUint(uint64(info.Combo), 10)
+		values["misses"] = strconv.FormatUint(uint64(info.Misses), 10)
+		values["grade"] = info.Grade.String()
+
+		if info.PP > 0 {
+			values["pp"] = fmt.Sprintf("%.0f", info.PP)
+		}
+	}
+
+	return values
+}
+
+// Render draws a thumbnail using the layout from settings and saves it as PNG. It has to be called on the main thread.
+func Render(info Info, path string) {
+	layoutPath := strings.TrimSpace(settings.Recording.Thumbnail.Layout)
+	if layoutPath != "" && !filepath.IsAbs(layoutPath) {
+		layoutPath = filepath.Join(env.DataDir(), layoutPath)
+	}
+
+	layout, err := loadLayout(layoutPath)
+	if err != nil {
+		log.Println("Failed to load thumbnail layout:", err)
+		return
+	}
+
+	loadFonts()
+
+	w, h := layout.Width, layout.Height
+
+	fbo := buffer.NewFrame(w, h, true, false)
+	defer fbo.Dispose()
+
+	fbo.Bind()
+	viewport.Push(w, h)
+
+	fbo.ClearColor(0, 0, 0, 1)
+
+	blend.Push()
+	blend.Enable()
+	blend.SetFunction(blend.One, blend.OneMinusSrcAlpha)
+
+	values := info.values()
+
+	// Textures loaded only for this thumbnail
+	var loaded []*texture.TextureSingle
+
+	quadBatch := batch.NewQuadBatch()
+	quadBatch.Begin()
+	quadBatch.SetCamera(mgl32.Ortho(0, float32(w), float32(h), 0, 1, -1))
+
+	for _, slot := range layout.Slots {
+		col, _ := parseColor(slot.Color)
+
+		switch slot.Type {
+		case slotText:
+			drawText(quadBatch, slot, col, values)
+		case slotImage:
+			region, tex := getImage(slot.Image, info, filepath.Dir(layoutPath))
+			if tex != nil {
+				loaded = append(loaded, tex)
+			}
+
+			if region != nil {
+				drawImage(quadBatch, slot, col, *region)
+			}
+		}
+	}
+
+	quadBatch.End()
+
+	blend.Pop()
+
+	pixmap := texture.NewPixMapC(w, h, 3)
+	defer pixmap.Dispose()
+
+	gl.PixelStorei(gl.PACK_ALIGNMENT, int32(1))
+	gl.ReadPixels(0, 0, int32(w), int32(h), gl.RGB, gl.UNSIGNED_BYTE, pixmap.RawPointer)
+
+	viewport.Pop()
+	fbo.Unbind()
+
+	for _, tex := range loaded {
+		tex.Dispose()
+	}
+
+	if err = pixmap.WritePng(path, true); err != nil {
+		log.Println("Failed to save the thumbnail:", err)
+		return
+	}
+
+	log.Println("Thumbnail saved to:", path)
+}
+
+func loadFonts() {
+	if font.GetFont(defaultFont) == nil {
+		file, _ := assets.Open("assets/fonts/Quicksand-Bold.ttf")
+		font.LoadFont(file)
+		file.Close()
+	}
+}
+
+func drawText(quadBatch *batch.QuadBatch, slot Slot, col color2.Color, values map[string]string) {
+	missing := false
+
+	text := placeholderRegex.ReplaceAllStringFunc(slot.Text, func(s string) string {
+		value := values[strings.ToLower(s[1:len(s)-1])]
+		missing = missing || value == ""
+
+		return value
+	})
+
+	if missing || strings.TrimSpace(text) == "" {
+		return
+	}
+
+	fnt := font.GetFont(slot.Font)
+	if fnt == nil {
+		fnt = font.GetFont(defaultFont)
+	}
+
+	size := slot.Size
+	if size <= 0 {
+		size = 32
+	}
+
+	if width := fnt.GetWidth(size, text); slot.MaxWidth > 0 && width > slot.MaxWidth {
+		size *= slot.MaxWidth / width
+	}
+
+	origin := vector.ParseOrigin(slot.Origin)
+
+	if slot.Shadow {
+		offset := size * 0.04
+		fnt.DrawOriginRotationColor(quadBatch, slot.X+offset, slot.Y+offset, origin, size, 0, false, color2.NewLA(0, col.A*0.7), text)
+	}
+
+	fnt.DrawOriginRotationColor(quadBatch, slot.X, slot.Y, origin, size, 0, false, col, text)
+}
+
+// getImage returns the image of a slot, tex is set if the texture was loaded only for the thumbnail
+func getImage(name string, info Info, layoutDir string) (region *texture.TextureRegion, tex *texture.TextureSingle) {
+	switch name {
+	case "grade":
+		if !info.HasScore || info.Grade == osu.NONE {
+			return nil, nil
+		}
+
+		return skin.GetTexture("ranking-" + info.Grade.TextureName()), nil
+	case "background":
+		pixmap, err := texture.NewPixmapFileString(filepath.Join(settings.General.GetSongsDir(), info.BeatMap.Dir, info.BeatMap.Bg))
+		if err != nil {
+			pixmap, err = assets.GetPixmap("assets/textures/background-1.png")
+		}
+
+		if err != nil {
+			log.Println("Failed to load thumbnail background:", err)
+			return nil, nil
+		}
+
+		defer pixmap.Dispose()
+
+		tex = texture.LoadTextureSingle(pixmap.RGBA(), 0)
+	default:
+		path := name
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(layoutDir, path)
+		}
+
+		pixmap, err := texture.NewPixmapFileString(path)
+		if err != nil {
+			log.Println("Failed to load thumbnail image:", err)
+			return nil, nil
+		}
+
+		defer pixmap.Dispose()
+
+		tex = texture.LoadTextureSingle(pixmap.RGBA(), 0)
+	}
+
+	r := tex.GetRegion()
+
+	return &r, tex
+}
+
+// drawImage draws the image in slot's bounds, missing width or height keeps the aspect ratio
+func drawImage(quadBatch *batch.QuadBatch, slot Slot, col color2.Color, region texture.TextureRegion) {
+	tW, tH := float64(region.Width), float64(region.Height)
+
+	w, h := slot.Width, slot.Height
+
+	switch {
+	case w <= 0 && h <= 0:
+		w, h = tW, tH
+	case w <= 0:
+		w = h * tW / tH
+	case h <= 0:
+		h = w * tH / tW
+	}
+
+	scaleX, scaleY := w/tW, h/tH
+
+	switch slot.Fit {
+	case "cover": // Crop the image to fill the bounds
+		s := max(scaleX, scaleY)
+
+		cropU := float32(1-scaleX/s) / 2 * (region.U2 - region.U1)
+		cropV := float32(1-scaleY/s) / 2 * (region.V2 - region.V1)
+
+		region.U1, region.U2 = region.U1+cropU, region.U2-cropU
+		region.V1, region.V2 = region.V1+cropV, region.V2-cropV
+
+		region.Width, region.Height = float32(w/s), float32(h/s)
+
+		scaleX, scaleY = s, s
+	case "contain": // Fit the whole image inside the bounds
+		s := min(scaleX, scaleY)
+		scaleX, scaleY = s, s
+	}
+
+	quadBatch.DrawStObject(vector.NewVec2d(slot.X, slot.Y), vector.ParseOrigin(slot.Origin), vector.NewVec2d(scaleX, scaleY), false, false, 0, col, false, region)
+}
@@ -0,0 +1,339 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/dance"
+	"github.com/wieku/danser-go/app/database"
+	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/app/rulesets/osu"
+	"github.com/wieku/danser-go/app/rulesets/osu/performance/api"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/framework/files"
+	"github.com/wieku/rplpa"
+	"log"
+	"os"
+	"strings"
+)
+
+type verifyScore struct {
+	Score       int64  `json:"score"`
+	MaxCombo    int64  `json:"maxCombo"`
+	Count300    int64  `json:"count300"`
+	Count100    int64  `json:"count100"`
+	Count50     int64  `json:"count50"`
+	CountMiss   int64  `json:"countMiss"`
+	CountGeki   int64  `json:"countGeki"`
+	CountKatu   int64  `json:"countKatu"`
+	SliderTails *int64 `json:"sliderTails,omitempty"`
+}
+
+type verifyMismatch struct {
+	Field    string `json:"field"`
+	Expected int64  `json:"expected"`
+	Actual   int64  `json:"actual"`
+
+	// Object and Time point to the judgement after which danser's value went over the expected one
+	Object *int64 `json:"object,omitempty"`
+	Time   *int64 `json:"time,omitempty"`
+}
+
+type verifyResult struct {
+	File       string           `json:"file"`
+	Player     string           `json:"player,omitempty"`
+	MD5        string           `json:"md5,omitempty"`
+	Beatmap    string           `json:"beatmap,omitempty"`
+	Mods       string           `json:"mods,omitempty"`
+	Scoring    string           `json:"scoring,omitempty"`
+	Expected   *verifyScore     `json:"expected,omitempty"`
+	Actual     *verifyScore     `json:"actual,omitempty"`
+	Accuracy   float64          `json:"accuracy,omitempty"`
+	Grade      string           `json:"grade,omitempty"`
+	PP         *api.PPv2Results `json:"pp,omitempty"`
+	Failed     bool             `json:"failed,omitempty"`
+	Match      bool             `json:"match"`
+	Mismatches []verifyMismatch `json:"mismatches,omitempty"`
+	Error      string           `json:"error,omitempty"`
+}
+
+type verifyField struct {
+	name string
+	get  func(score verifyScore) int64
+
+	// judgement is set for counts of base judgements, every object adds exactly one of them
+	judgement bool
+
+	divergence *osu.JudgementResult
+}
+
+func runVerify(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: danser verify [flags] path/to/replay.osr|path/to/replays ...")
+		fmt.Fprintln(fs.Output(), "Simulates given replays without opening a window and compares the results with scores saved in them. Results are printed as JSON, one line per replay. Exits with code 1 if any replay doesn't match.")
+		fs.PrintDefaults()
+	}
+
+	settingsVersion := fs.String("settings", "", "Specify settings version, used to locate the Songs directory")
+	noDbCheck := fs.Bool("nodbcheck", false, "Don't validate the database and only import new beatmap sets if there are any")
+
+	_ = fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	var replays []string
+
+	for _, path := range fs.Args() {
+		stat, err := os.Stat(path)
+		if err == nil && stat.IsDir() {
+			found, _ := files.SearchFiles(path, "*.osr", -1)
+			replays = append(replays, found...)
+		} else {
+			replays = append(replays, path)
+		}
+	}
+
+	settings.LoadSettings(*settingsVersion)
+
+	settings.HEADLESS = true
+	settings.KNOCKOUT = true
+
+	if err := database.Init(); err != nil {
+		panic(fmt.Sprintf("Failed to initialize database: %s", err))
+	}
+
+	beatmaps := database.LoadBeatmaps(*noDbCheck, nil)
+
+	database.Close()
+
+	encoder := json.NewEncoder(os.Stdout)
+
+	exitCode := 0
+
+	for _, path := range replays {
+		result := verifyReplay(path, beatmaps)
+
+		if !result.Match {
+			exitCode = 1
+		}
+
+		if err := encoder.Encode(result); err != nil {
+			panic(err)
+		}
+	}
+
+	return exitCode
+}
+
+func verifyReplay(path string, beatmaps []*beatmap.BeatMap) (result verifyResult) {
+	result.File = path
+
+	defer func() {
+		if err := recover(); err != nil {
+			log.Println("Failed to verify \"", path, "\":", err)
+
+			result.Error = fmt.Sprint(err)
+			result.Match = false
+		}
+	}()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		panic(err)
+	}
+
+	rp, err := rplpa.ParseReplay(data)
+	if err != nil {
+		panic(err)
+	}
+
+	result.Player = rp.Username
+	result.MD5 = rp.BeatmapMD5
+
+	if rp.PlayMode != 0 {
+		panic(errors.New("only osu!standard replays are supported"))
+	}
+
+	if rp.ReplayData == nil || len(rp.ReplayData) < 2 {
+		panic(errors.New("replay is missing input data"))
+	}
+
+	var bMap *beatmap.BeatMap
+
+	for _, b := range beatmaps {
+		if strings.EqualFold(b.MD5, rp.BeatmapMD5) {
+			bMap = b
+			break
+		}
+	}
+
+	if bMap == nil {
+		panic(fmt.Errorf("beatmap with md5 %s not found", rp.BeatmapMD5))
+	}
+
+	result.Beatmap = fmt.Sprintf("%s - %s [%s]", bMap.Artist, bMap.Name, bMap.Difficulty)
+
+	mods, modsNew := getReplayMods(rp)
+
+	defer bMap.Clear()
+
+	bMap.Clear()
+	bMap.Pauses = nil
+
+	if modsNew != nil {
+		bMap.Diff.SetMods2(modsNew)
+	} else {
+		bMap.Diff.SetMods(mods)
+	}
+
+	beatmap.ParseTimingPointsAndPauses(bMap)
+	beatmap.ParseObjects(bMap, false, false, false)
+
+	if len(bMap.HitObjects) == 0 {
+		panic(errors.New("beatmap doesn't have any hit objects"))
+	}
+
+	for _, o := range bMap.HitObjects {
+		o.DisableAudioSubmission(true)
+	}
+
+	settings.REPLAY = path
+
+	controller := dance.NewReplayController().(*dance.ReplayController)
+	controller.SetBeatMap(bMap)
+	controller.InitCursors()
+
+	ruleset := controller.GetRuleset()
+	cursor := controller.GetCursors()[0]
+	diff := ruleset.GetPlayerDifficulty(cursor)
+
+	result.Mods = diff.GetModString()
+
+	lazer := diff.CheckModActive(difficulty.Lazer)
+
+	switch {
+	case lazer:
+		result.Scoring = "lazer"
+	case diff.CheckModActive(difficulty.ScoreV2):
+		result.Scoring = "v2"
+	default:
+		result.Scoring = "v1"
+	}
+
+	expected := verifyScore{
+		Score:     int64(rp.Score),
+		MaxCombo:  int64(rp.MaxCombo),
+		Count300:  int64(rp.Count300),
+		Count100:  int64(rp.Count100),
+		Count50:   int64(rp.Count50),
+		CountMiss: int64(rp.CountMiss),
+		CountGeki: int64(rp.CountGeki),
+		CountKatu: int64(rp.CountKatu),
+	}
+
+	fields := []*verifyField{
+		{name: "score", get: func(s verifyScore) int64 { return s.Score }},
+		{name: "maxCombo", get: func(s verifyScore) int64 { return s.MaxCombo }},
+		{name: "count300", get: func(s verifyScore) int64 { return s.Count300 }, judgement: true},
+		{name: "count100", get: func(s verifyScore) int64 { return s.Count100 }, judgement: true},
+		{name: "count50", get: func(s verifyScore) int64 { return s.Count50 }, judgement: true},
+		{name: "countMiss", get: func(s verifyScore) int64 { return s.CountMiss }, judgement: true},
+	}
+
+	if !lazer { // lazer doesn't store gekis and katus for osu!standard
+		fields = append(fields,
+			&verifyField{name: "countGeki", get: func(s verifyScore) int64 { return s.CountGeki }},
+			&verifyField{name: "countKatu", get: func(s verifyScore) int64 { return s.CountKatu }},
+		)
+	}
+
+	if rp.ScoreInfo != nil {
+		if tails, ok := rp.ScoreInfo.Statistics[rplpa.LazerSliderTailHit]; ok {
+			expected.SliderTails = &tails
+
+			fields = append(fields, &verifyField{name: "sliderTails", get: func(s verifyScore) int64 { return *s.SliderTails }})
+		}
+	}
+
+	// Judgements aren't saved in replays, so the best we can do is to find the first judgement after which a value can't end up as the expected one:
+	// any value went over it, or a judgement count can't reach it with the objects that are left
+	ruleset.AddListener(func(_ *graphics.Cursor, judgementResult osu.JudgementResult, score osu.Score) {
+		current := toVerifyScore(score)
+
+		remaining := int64(len(bMap.HitObjects)) - (current.Count300 + current.Count100 + current.Count50 + current.CountMiss)
+
+		for _, f := range fields {
+			if f.divergence != nil {
+				continue
+			}
+
+			if f.get(current) > f.get(expected) || (f.judgement && f.get(current)+remaining < f.get(expected)) {
+				f.divergence = &judgementResult
+			}
+		}
+	})
+
+	startTime := -min(1800, bMap.Diff.Preempt)
+	endTime := bMap.HitObjects[len(bMap.HitObjects)-1].GetEndTime() + float64(bMap.Diff.Hit50) + 1000
+
+	for time := startTime; time <= endTime && !ruleset.IsEnded(); time++ {
+		controller.Update(time, 1)
+	}
+
+	score := ruleset.GetScore(cursor)
+	actual := toVerifyScore(score)
+
+	result.Expected = &expected
+	result.Actual = &actual
+	result.Accuracy = score.Accuracy * 100
+	result.Grade = score.Grade.String()
+	result.PP = &score.PP
+	result.Failed = ruleset.IsFailed(cursor)
+
+	for _, f := range fields {
+		if f.get(expected) == f.get(actual) {
+			continue
+		}
+
+		mismatch := verifyMismatch{
+			Field:    f.name,
+			Expected: f.get(expected),
+			Actual:   f.get(actual),
+		}
+
+		if f.divergence != nil {
+			mismatch.Object = &f.divergence.Number
+			mismatch.Time = &f.divergence.Time
+		}
+
+		result.Mismatches = append(result.Mismatches, mismatch)
+	}
+
+	result.Match = len(result.Mismatches) == 0
+
+	return
+}
+
+func toVerifyScore(score osu.Score) verifyScore {
+	sliderTails := int64(score.SliderEnd)
+
+	return verifyScore{
+		Score:       score.Score,
+		MaxCombo:    int64(score.Combo),
+		Count300:    int64(score.Count300),
+		Count100:    int64(score.Count100),
+		Count50:     int64(score.Count50),
+		CountMiss:   int64(score.CountMiss),
+		CountGeki:   int64(score.CountGeki),
+		CountKatu:   int64(score.CountKatu),
+		SliderTails: &sliderTails,
+	}
+}
@@ -10,10 +10,73 @@ import (
 	"unsafe"
 )
 
+type Stem int
+
+// Stems are parts of the mix that can be recorded separately, samples are mixed into StemUI unless set otherwise
+const (
+	StemMusic Stem = iota
+	StemHitsounds
+	StemStoryboard
+	StemUI
+	StemCount
+)
+
+var stemNames = [StemCount]string{"music", "hitsounds", "storyboard", "ui"}
+
+func (stem Stem) String() string {
+	return stemNames[stem]
+}
+
+// stemMixers are created only offscreen, the final mix is the sum of their output
+var stemMixers [StemCount]C.HSTREAM
+
+var stemBuffer []byte
+
+func getMixer(stem Stem) C.HSTREAM {
+	if stemMixers[stem] != 0 {
+		return stemMixers[stem]
+	}
+
+	return masterMixer
+}
+
+// getClockMixer returns the mixer whose position advances with the audio output
+func getClockMixer() C.HSTREAM {
+	return getMixer(StemMusic)
+}
+
 func GetMixerRequiredBufferSize(seconds float64) int {
 	return int(C.BASS_ChannelSeconds2Bytes(masterMixer, C.double(seconds)))
 }
 
 func ProcessMixer(buffer []byte) {
-	C.BASS_ChannelGetData(masterMixer, unsafe.Pointer(&buffer[0]), C.DWORD(len(buffer)))
+	ProcessStems(buffer, nil)
+}
+
+// ProcessStems fills buffer with the final mix and stems with the output of each stem. If stems is nil, only the final mix is returned.
+func ProcessStems(buffer []byte, stems [][]byte) {
+	if stemMixers[0] == 0 {
+		C.BASS_ChannelGetData(masterMixer, unsafe.Pointer(&buffer[0]), C.DWORD(len(buffer)))
+		return
+	}
+
+	if len(stemBuffer) != len(buffer) {
+		stemBuffer = make([]byte, len(buffer))
+	}
+
+	mix := unsafe.Slice((*float32)(unsafe.Pointer(&buffer[0])), len(buffer)/4)
+	clear(mix)
+
+	for i, mixer := range stemMixers {
+		data := stemBuffer
+		if stems != nil {
+			data = stems[i]
+		}
+
+		C.BASS_ChannelGetData(mixer, unsafe.Pointer(&data[0]), C.DWORD(len(data)))
+
+		for j, v := range unsafe.Slice((*float32)(unsafe.Pointer(&data[0])), len(data)/4) {
+			mix[j] += v
+		}
+	}
 }
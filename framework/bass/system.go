@@ -83,6 +83,11 @@ func Init(offscreen bool) {
 
 		if !offscreen {
 			C.BASS_ChannelPlay(masterMixer, 0)
+		} else {
+			// Each stem gets its own mixer, so it can be recorded separately
+			for i := range stemMixers {
+				stemMixers[i] = C.BASS_Mixer_StreamCreate(C.DWORD(sampleRate), 2, C.DWORD(mixerFlags))
+			}
 		}
 	} else {
 		err := GetError()
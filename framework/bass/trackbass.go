@@ -87,7 +87,7 @@ func (track *TrackBass) AddSilence(seconds float64) {
 func (track *TrackBass) Play() {
 	track.SetVolume(settings.Audio.GeneralVolume * settings.Audio.MusicVolume)
 
-	C.BASS_Mixer_StreamAddChannel(masterMixer, track.channel, C.BASS_MIXER_CHAN_NORAMPIN|C.BASS_MIXER_CHAN_BUFFER)
+	C.BASS_Mixer_StreamAddChannel(getMixer(StemMusic), track.channel, C.BASS_MIXER_CHAN_NORAMPIN|C.BASS_MIXER_CHAN_BUFFER)
 
 	track.playing = true
 	track.addedToMixer = true
@@ -98,7 +98,7 @@ func (track *TrackBass) PlayV(volume float64) {
 
 	track.playing = true
 
-	C.BASS_Mixer_StreamAddChannel(masterMixer, track.channel, C.BASS_MIXER_CHAN_NORAMPIN|C.BASS_MIXER_CHAN_BUFFER)
+	C.BASS_Mixer_StreamAddChannel(getMixer(StemMusic), track.channel, C.BASS_MIXER_CHAN_NORAMPIN|C.BASS_MIXER_CHAN_BUFFER)
 	track.addedToMixer = true
 }
 
@@ -199,6 +199,25 @@ func (track *TrackBass) GetSpeed() float64 {
 	return track.speed * track.relativeFrequency
 }
 
+// GetSampleRate returns the rate at which decoded data should be played, it includes frequency changes
+func (track *TrackBass) GetSampleRate() int {
+	return int(track.baseFrequency * track.relativeFrequency)
+}
+
+func (track *TrackBass) GetChannels() int {
+	var info C.BASS_CHANNELINFO
+
+	C.BASS_ChannelGetInfo(track.channel, &info)
+
+	return int(info.chans)
+}
+
+// GetData decodes the track with tempo and pitch changes as 32-bit float samples, it can't be used while the track is playing.
+// Returns the number of bytes written, -1 when the end is reached.
+func (track *TrackBass) GetData(buffer []byte) int {
+	return int(int32(C.BASS_ChannelGetData(track.channel, unsafe.Pointer(&buffer[0]), C.DWORD(len(buffer))|C.BASS_DATA_FLOAT)))
+}
+
 func (track *TrackBass) GetState() int {
 	if !track.addedToMixer {
 		return MusicStopped
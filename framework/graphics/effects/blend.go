@@ -3,6 +3,7 @@ package effects
 import (
 	"github.com/wieku/danser-go/framework/assets"
 	"github.com/wieku/danser-go/framework/graphics/attribute"
+	"github.com/wieku/danser-go/framework/graphics/blend"
 	"github.com/wieku/danser-go/framework/graphics/buffer"
 	"github.com/wieku/danser-go/framework/graphics/shader"
 	"github.com/wieku/danser-go/framework/graphics/texture"
@@ -71,7 +72,7 @@ func NewBlend(width, height, frames int, weights []float32) *Blend {
 		effect.blendShader.SetUniformArr("weights", i, v/sum)
 	}
 
-	effect.multiTexture = texture.NewTextureMultiLayerFormat(width, height, texture.RGB, 0, frames)
+	effect.multiTexture = texture.NewTextureMultiLayerFormat(width, height, texture.RGBA, 0, frames)
 
 	for i := 0; i < frames; i++ {
 		effect.fbos = append(effect.fbos, buffer.NewFrameLayer(effect.multiTexture, i))
@@ -99,11 +100,17 @@ func (effect *Blend) Blend() {
 
 	viewport.Push(effect.width, effect.height)
 
+	// Frames may be transparent, so the result has to replace the target instead of being blended with it
+	blend.Push()
+	blend.Disable()
+
 	effect.blendShader.Bind()
 	effect.vao.Bind()
 	effect.vao.Draw()
 	effect.vao.Unbind()
 	effect.blendShader.Unbind()
 
+	blend.Pop()
+
 	viewport.Pop()
 }
@@ -177,6 +177,12 @@ func (sprite *Sprite) ClearTransformationsOfType(transformationType animation.Tr
 	}
 }
 
+func (sprite *Sprite) RemoveTransforms(transformations []*animation.Transformation) {
+	sprite.transforms = slices.DeleteFunc(sprite.transforms, func(t *animation.Transformation) bool {
+		return slices.Contains(transformations, t)
+	})
+}
+
 func (sprite *Sprite) AdjustTimesToTransformations() {
 	if len(sprite.transforms) == 0 {
 		return
@@ -134,6 +134,7 @@ func (dec *VideoDecoder) StartFFmpeg(millis int64) {
 	}
 
 	dec.running = true
+	dec.finished = false
 
 	dec.decodingQueue = make(chan Frame, BufferSize)
 	dec.readyQueue = make(chan Frame, BufferSize)
@@ -57,7 +57,7 @@ func (video *Video) SetStartTime(startTime float64) {
 func (video *Video) Update(time float64) {
 	video.Sprite.Update(time)
 
-	if video.decoder == nil || video.decoder.HasFinished() {
+	if video.decoder == nil {
 		return
 	}
 
@@ -68,11 +68,16 @@ func (video *Video) Update(time float64) {
 
 	delta := 1000.0 / video.decoder.Metadata.FPS
 
-	if time < video.lastTime || video.lastTime+1000 < time {
+	// Finished decoder is restarted only when going back in time
+	if time < video.lastTime || (video.lastTime+1000 < time && !video.decoder.HasFinished()) {
 		video.decoder.StartFFmpeg(int64(time))
 		video.lastTime = time - delta
 	}
 
+	if video.decoder.HasFinished() {
+		return
+	}
+
 	if video.lastTime+delta < time {
 		video.lastTime += delta
 
@@ -15,7 +15,14 @@ import (
 	"strings"
 )
 
+var logFile *os.File
+
 func StartLogging(logName string) {
+	StartLoggingTo(logName, os.Stdout)
+}
+
+// StartLoggingTo works like StartLogging but mirrors the log to a given writer instead of stdout
+func StartLoggingTo(logName string, console io.Writer) {
 	log.Println(build.ProgramName, "version:", build.VERSION)
 
 	file, err := os.Create(filepath.Join(env.DataDir(), logName+".log"))
@@ -23,11 +30,18 @@ func StartLogging(logName string) {
 		panic(err)
 	}
 
+	logFile = file
+
 	log.SetOutput(file)
 
 	PrintPlatformInfo()
 
-	log.SetOutput(io.MultiWriter(os.Stdout, file))
+	log.SetOutput(io.MultiWriter(console, file))
+}
+
+// SetLogConsole changes the writer the log is mirrored to
+func SetLogConsole(console io.Writer) {
+	log.SetOutput(io.MultiWriter(console, logFile))
 }
 
 func PrintPlatformInfo() {
@@ -0,0 +1,274 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const maxPayload = 1 << 16
+
+const writeTimeout = 5 * time.Second
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+const (
+	finBit  = 0x80
+	rsvBits = 0x70
+	maskBit = 0x80
+)
+
+const maxControlPayload = 125
+
+var ErrClosed = errors.New("websocket: connection closed")
+
+// closeError is a reason to drop the connection, its code is sent to the client in the close frame
+type closeError struct {
+	code uint16
+	text string
+}
+
+func (e *closeError) Error() string {
+	return "websocket: " + e.text
+}
+
+var (
+	errProtocol   = &closeError{1002, "protocol error"}
+	errFragmented = &closeError{1003, "fragmented messages are not supported"}
+	errTooBig     = &closeError{1009, "message too big"}
+)
+
+// Conn is a minimal server side RFC 6455 connection. Only unfragmented text messages can be sent,
+// messages received from the client are discarded apart from control frames. Fragmented messages close the connection.
+type Conn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+
+	mutex  sync.Mutex
+	closed bool
+}
+
+// Upgrade performs the opening handshake and takes over the underlying connection
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+
+	if r.Method != http.MethodGet || !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") || key == "" {
+		http.Error(w, "websocket upgrade expected", http.StatusBadRequest)
+		return nil, errors.New("websocket: not a websocket handshake")
+	}
+
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return nil, errors.New("websocket: unsupported version")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return nil, errors.New("websocket: response doesn't support hijacking")
+	}
+
+	netConn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha1.Sum([]byte(key + acceptGUID))
+
+	_, err = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(hash[:]) + "\r\n\r\n")
+	if err == nil {
+		err = rw.Flush()
+	}
+
+	if err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	return &Conn{
+		conn:   netConn,
+		reader: rw.Reader,
+	}, nil
+}
+
+func headerContains(header http.Header, name, value string) bool {
+	for _, v := range header.Values(name) {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), value) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// WriteText sends a single text message, it's safe to call from multiple goroutines
+func (c *Conn) WriteText(data []byte) error {
+	return c.writeFrame(opText, data)
+}
+
+func (c *Conn) writeFrame(opCode byte, payload []byte) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return ErrClosed
+	}
+
+	header := make([]byte, 2, 10)
+	header[0] = finBit | opCode
+
+	switch length := len(payload); {
+	case length < 126:
+		header[1] = byte(length)
+	case length <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+
+	_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+
+	if _, err := c.conn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ReadLoop handles control frames sent by the client and returns when the connection is closed
+func (c *Conn) ReadLoop() error {
+	for {
+		opCode, payload, err := c.readFrame()
+		if err != nil {
+			if cErr, ok := err.(*closeError); ok {
+				c.closeWith(binary.BigEndian.AppendUint16(nil, cErr.code))
+			} else {
+				c.Close()
+			}
+
+			return err
+		}
+
+		switch opCode {
+		case opClose:
+			c.Close()
+			return nil
+		case opPing:
+			if err = c.writeFrame(opPong, payload); err != nil {
+				c.Close()
+				return err
+			}
+		}
+	}
+}
+
+func (c *Conn) readFrame() (byte, []byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(c.reader, header); err != nil {
+		return 0, nil, err
+	}
+
+	opCode := header[0] & 0x0F
+	masked := header[1]&maskBit > 0
+	length := uint64(header[1] & 0x7F)
+
+	// No extensions are negotiated and clients have to mask their frames
+	if header[0]&rsvBits != 0 || !masked {
+		return 0, nil, errProtocol
+	}
+
+	switch opCode {
+	case opContinuation:
+		return 0, nil, errFragmented
+	case opText, opBinary:
+		if header[0]&finBit == 0 {
+			return 0, nil, errFragmented
+		}
+	case opClose, opPing, opPong:
+		if header[0]&finBit == 0 || length > maxControlPayload {
+			return 0, nil, errProtocol
+		}
+	default:
+		return 0, nil, errProtocol
+	}
+
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(c.reader, ext); err != nil {
+			return 0, nil, err
+		}
+
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(c.reader, ext); err != nil {
+			return 0, nil, err
+		}
+
+		length = binary.BigEndian.Uint64(ext)
+	}
+
+	if length > maxPayload {
+		return 0, nil, errTooBig
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+		return 0, nil, err
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return 0, nil, err
+	}
+
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return opCode, payload, nil
+}
+
+// Close sends a close frame and closes the underlying connection
+func (c *Conn) Close() error {
+	return c.closeWith(nil)
+}
+
+// closeWith sends a close frame with given payload, e.g. a status code, and closes the underlying connection
+func (c *Conn) closeWith(payload []byte) error {
+	_ = c.writeFrame(opClose, payload)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return nil
+	}
+
+	c.closed = true
+
+	return c.conn.Close()
+}
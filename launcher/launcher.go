@@ -451,7 +451,7 @@ func (l *launcher) loadBeatmaps() {
 	} else {
 		bSplash := "Loading maps...\nThis may take a while...\n\n"
 
-		beatmaps := database.LoadBeatmaps(launcherConfig.SkipMapUpdate, func(stage database.ImportStage, processed, target int) {
+		beatmaps := database.LoadWatchableBeatmaps(launcherConfig.SkipMapUpdate, func(stage database.ImportStage, processed, target int) {
 			switch stage {
 			case database.Discovery:
 				l.splashText = bSplash + "Searching for .osu files...\n\n"
@@ -1268,6 +1268,10 @@ func (l *launcher) drawLowerPanel() {
 							}
 						})
 					}
+				} else if (l.bld.currentMode == Knockout || l.bld.currentMode == Play) && l.bld.currentMap.Mode != 0 {
+					goroutines.Run(func() {
+						showMessage(mError, "osu!taiko and osu!mania maps can be only watched with autoplay or a single replay")
+					})
 				} else {
 					if l.selectWindow != nil {
 						l.selectWindow.stopPreview()
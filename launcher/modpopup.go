@@ -33,13 +33,16 @@ func (m *modPopup) drawModMenu() {
 	handleDragScroll()
 	imgui.PushStyleVarVec2(imgui.StyleVarCellPadding, vec2(10, 10))
 
+	rateAdjust := difficulty.DoubleTime | difficulty.Nightcore | difficulty.HalfTime | difficulty.Daycore
+	variableRate := difficulty.WindUp | difficulty.WindDown | difficulty.AdaptiveSpeed
+
 	if imgui.BeginTable("mfa", 6) {
 		m.drawRow("Reduction:", func() {
 			m.modCheckbox(difficulty.Easy, difficulty.HardRock, difficulty.None)
 
 			m.modCheckbox(difficulty.NoFail, difficulty.SuddenDeath|difficulty.Perfect|difficulty.Relax|difficulty.Relax2, difficulty.None)
 
-			m.modCheckboxMulti(difficulty.HalfTime, difficulty.Daycore, difficulty.DoubleTime|difficulty.Nightcore, difficulty.None)
+			m.modCheckboxMulti(difficulty.HalfTime, difficulty.Daycore, difficulty.DoubleTime|difficulty.Nightcore|variableRate, difficulty.None)
 		})
 
 		m.drawRow("Increase:", func() {
@@ -47,7 +50,7 @@ func (m *modPopup) drawModMenu() {
 
 			m.modCheckboxMulti(difficulty.SuddenDeath, difficulty.Perfect, difficulty.NoFail|difficulty.Relax|difficulty.Relax2, difficulty.None)
 
-			m.modCheckboxMulti(difficulty.DoubleTime, difficulty.Nightcore, difficulty.HalfTime|difficulty.Daycore, difficulty.None)
+			m.modCheckboxMulti(difficulty.DoubleTime, difficulty.Nightcore, difficulty.HalfTime|difficulty.Daycore|variableRate, difficulty.None)
 
 			m.modCheckbox(difficulty.Hidden, difficulty.None, difficulty.None)
 
@@ -72,6 +75,12 @@ func (m *modPopup) drawModMenu() {
 			m.modCheckbox(difficulty.Classic, difficulty.ScoreV2, difficulty.Lazer)
 		})
 
+		m.drawRow("Fun:", func() {
+			m.modCheckbox(difficulty.WindUp, rateAdjust|variableRate&^difficulty.WindUp, difficulty.Lazer)
+			m.modCheckbox(difficulty.WindDown, rateAdjust|variableRate&^difficulty.WindDown, difficulty.Lazer)
+			m.modCheckbox(difficulty.AdaptiveSpeed, rateAdjust|variableRate&^difficulty.AdaptiveSpeed, difficulty.Lazer)
+		})
+
 		imgui.EndTable()
 	}
 
@@ -98,6 +107,8 @@ func (m *modPopup) drawModMenu() {
 func (m *modPopup) drawModSettings() {
 	m.settingsDrawn = false
 	m.tryDrawSpeedSettings()
+	m.tryDrawTimeRampSettings()
+	m.tryDrawAdaptiveSpeedSettings()
 	m.tryDrawEasySettings()
 	m.tryDrawClassicSettings()
 	m.tryDrawFlashlightSettings()
@@ -127,6 +138,35 @@ func (m *modPopup) tryDrawSpeedSettings() {
 	})
 }
 
+func (m *modPopup) tryDrawTimeRampSettings() {
+	m.drawSettingsBase(difficulty.WindUp|difficulty.WindDown, func() {
+		conf, _ := difficulty.GetModConfig[difficulty.TimeRampSettings](m.bld.diff)
+
+		if m.bld.diff.CheckModActive(difficulty.WindUp) {
+			sliderFloatReset2("Initial rate", 1, &conf.InitialRate, 0.5, 1.95, "%.2f")
+			sliderFloatReset2("Final rate", 1.5, &conf.FinalRate, 0.55, 2, "%.2f")
+		} else {
+			sliderFloatReset2("Initial rate", 1, &conf.InitialRate, 0.55, 2, "%.2f")
+			sliderFloatReset2("Final rate", 0.75, &conf.FinalRate, 0.5, 1.95, "%.2f")
+		}
+
+		checkboxOption("Adjust pitch", &conf.AdjustPitch)
+
+		difficulty.SetModConfig(m.bld.diff, conf)
+	})
+}
+
+func (m *modPopup) tryDrawAdaptiveSpeedSettings() {
+	m.drawSettingsBase(difficulty.AdaptiveSpeed, func() {
+		conf, _ := difficulty.GetModConfig[difficulty.AdaptiveSpeedSettings](m.bld.diff)
+
+		sliderFloatReset2("Initial rate", 1, &conf.InitialRate, 0.5, 2, "%.2f")
+		checkboxOption("Adjust pitch", &conf.AdjustPitch)
+
+		difficulty.SetModConfig(m.bld.diff, conf)
+	})
+}
+
 func (m *modPopup) tryDrawEasySettings() {
 	m.drawSettingsBase(difficulty.Easy, func() {
 		conf, _ := difficulty.GetModConfig[difficulty.EasySettings](m.bld.diff)
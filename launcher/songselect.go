@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"github.com/AllenDang/cimgui-go/imgui"
 	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/database"
 	"github.com/wieku/danser-go/app/settings"
 	"github.com/wieku/danser-go/framework/bass"
 	"github.com/wieku/danser-go/framework/graphics/texture"
@@ -16,6 +18,7 @@ import (
 	"math"
 	"math/rand"
 	"path/filepath"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
@@ -34,6 +37,29 @@ const (
 
 var sortMethods = []SortBy{Title, Artist, Creator, DateAdded, Difficulty}
 
+// starFilterRegex matches star rating filters in the search string, e.g. "stars>5.5" or "sr<=7"
+var starFilterRegex = regexp.MustCompile(`^(?:stars|sr)(<=|>=|<|>|=)(\d+(?:\.\d+)?)$`)
+
+type starFilter struct {
+	operator string
+	value    float64
+}
+
+func (f starFilter) matches(stars float64) bool {
+	switch f.operator {
+	case "<":
+		return stars < f.value
+	case "<=":
+		return stars <= f.value
+	case ">":
+		return stars > f.value
+	case ">=":
+		return stars >= f.value
+	default:
+		return math.Abs(stars-f.value) < 0.005
+	}
+}
+
 func (s SortBy) String() string {
 	switch s {
 	case Title:
@@ -104,6 +130,11 @@ type songSelectPopup struct {
 
 	comboOpened bool
 	scrolling   bool
+
+	// Star ratings are shown, sorted and filtered under mods selected in the builder
+	starKey        database.StarKey
+	starGeneration int
+	starFiltered   bool
 }
 
 func newSongSelectPopup(bld *builder, beatmaps []*beatmap.BeatMap) *songSelectPopup {
@@ -133,6 +164,24 @@ func (m *songSelectPopup) setBeatmaps(beatmaps []*beatmap.BeatMap) {
 }
 
 func (m *songSelectPopup) update() {
+	if key := database.NewStarKey(m.bld.diff, settings.Gameplay.PPVersion); key != m.starKey {
+		m.starKey = key
+
+		if m.opened {
+			m.requestStars()
+		}
+
+		m.search()
+	}
+
+	if _, _, _, generation := database.GetStarProgress(); generation != m.starGeneration {
+		m.starGeneration = generation
+
+		if launcherConfig.SortMapsBy == Difficulty || m.starFiltered {
+			m.search()
+		}
+	}
+
 	cT := qpc.GetMilliTimeF()
 
 	m.volume.Update(cT)
@@ -212,6 +261,13 @@ func (m *songSelectPopup) drawSongSelect() {
 		ImIO.SetFontGlobalScale(1)
 		imgui.PopFont()
 
+		if key, processed, target, _ := database.GetStarProgress(); key != nil {
+			imgui.SameLine()
+
+			imgui.AlignTextToFramePadding()
+			imgui.TextUnformatted(fmt.Sprintf("Calculating star ratings for %s: %d / %d", key.String(), processed, target))
+		}
+
 		imgui.TableNextColumn()
 
 		if imgui.Button("Random") {
@@ -511,6 +567,20 @@ func (m *songSelectPopup) showMapTooltip(bMap *beatmap.BeatMap) {
 		sR = mutils.FormatWOZeros(bMap.Stars, 2)
 	}
 
+	var modLabel, modSR string
+
+	if m.starKey.Mods != difficulty.None || m.starKey.Speed != 1 {
+		modLabel = m.starKey.Mods.String() + ": "
+		if m.starKey.Speed != 1 {
+			modLabel = fmt.Sprintf("%s %sx: ", m.starKey.Mods.String(), mutils.FormatWOZeros(m.starKey.Speed, 2))
+		}
+
+		modSR = "..."
+		if stars, ok := database.GetStars(bMap, m.starKey); ok {
+			modSR = mutils.FormatWOZeros(stars, 2)
+		}
+	}
+
 	bpm := fmt.Sprintf("%.0f", bMap.MinBPM)
 	if math.Abs(bMap.MinBPM-bMap.MaxBPM) > 0.01 {
 		bpm = fmt.Sprintf("%.0f - %.0f", bMap.MinBPM, bMap.MaxBPM)
@@ -528,7 +598,7 @@ func (m *songSelectPopup) showMapTooltip(bMap *beatmap.BeatMap) {
 		}
 
 		tRow("Stars: ", sR)
-		tRow("", "")
+		tRow(modLabel, modSR)
 
 		tRow("Objects: ", "%d", bMap.Circles+bMap.Sliders+bMap.Spinners)
 		tRow("AR: ", mutils.FormatWOZeros(bMap.Diff.GetAR(), 2))
@@ -640,19 +710,47 @@ func (m *songSelectPopup) search() {
 	m.sizeCalculated = 0
 	m.searchResults = m.searchResults[:0]
 
-	sString := strings.ToLower(m.searchStr)
+	var words []string
+	var filters []starFilter
+
+	for _, word := range strings.Fields(strings.ToLower(m.searchStr)) {
+		if match := starFilterRegex.FindStringSubmatch(word); match != nil {
+			value, _ := strconv.ParseFloat(match[2], 64)
+			filters = append(filters, starFilter{operator: match[1], value: value})
+
+			continue
+		}
+
+		words = append(words, word)
+	}
+
+	sString := strings.Join(words, " ")
+
+	m.starFiltered = len(filters) > 0
 
 	foundMaps := make([]*beatmap.BeatMap, 0, len(m.beatmaps))
+	stars := make(map[*beatmap.BeatMap]float64)
 
 	for _, b := range m.beatmaps {
 		if sString != "" && !strings.Contains(b.name, sString) {
 			continue
 		}
 
+		sR, ok := database.GetStars(b.bMap, m.starKey)
+		if !ok {
+			sR = -1
+		}
+
+		if len(filters) > 0 && (!ok || slices.ContainsFunc(filters, func(f starFilter) bool { return !f.matches(sR) })) {
+			continue
+		}
+
+		stars[b.bMap] = sR
+
 		foundMaps = append(foundMaps, b.bMap)
 	}
 
-	sortMaps(foundMaps, launcherConfig.SortMapsBy)
+	sortMaps(foundMaps, launcherConfig.SortMapsBy, stars)
 
 	for _, b := range foundMaps {
 		if len(m.searchResults) == 0 || m.searchResults[len(m.searchResults)-1].bMaps[0].Dir != b.Dir {
@@ -669,9 +767,22 @@ func (m *songSelectPopup) search() {
 func (m *songSelectPopup) open() {
 	m.focusTheMap = true
 
+	m.requestStars()
+
 	m.popup.open()
 }
 
+// requestStars calculates missing star ratings under selected mods in background
+func (m *songSelectPopup) requestStars() {
+	bMaps := make([]*beatmap.BeatMap, 0, len(m.beatmaps))
+
+	for _, b := range m.beatmaps {
+		bMaps = append(bMaps, b.bMap)
+	}
+
+	database.RequestStarRatings(bMaps, m.starKey)
+}
+
 func compareStrings(l, r string) int {
 	lRa := []rune(l)
 	rRa := []rune(r)
@@ -696,7 +807,8 @@ func compareStrings(l, r string) int {
 	return -1
 }
 
-func sortMaps(bMaps []*beatmap.BeatMap, sortBy SortBy) {
+// sortMaps sorts beatmaps, stars holds star ratings under selected mods, -1 if they are not calculated yet
+func sortMaps(bMaps []*beatmap.BeatMap, sortBy SortBy, stars map[*beatmap.BeatMap]float64) {
 	slices.SortStableFunc(bMaps, func(b1, b2 *beatmap.BeatMap) int {
 		var res int
 
@@ -714,7 +826,7 @@ func sortMaps(bMaps []*beatmap.BeatMap, sortBy SortBy) {
 				res = 0
 			}
 		case Difficulty:
-			res = cmp.Compare(b1.Stars, b2.Stars)
+			res = cmp.Compare(stars[b1], stars[b2])
 		}
 
 		if !launcherConfig.SortAscending {
@@ -735,6 +847,6 @@ func sortMaps(bMaps []*beatmap.BeatMap, sortBy SortBy) {
 			return res
 		}
 
-		return cmp.Compare(b1.Stars, b2.Stars) // Don't flip grouped difficulties
+		return cmp.Compare(stars[b1], stars[b2]) // Don't flip grouped difficulties
 	})
 }
@@ -111,7 +111,7 @@ func (m *timePopup) drawStrainGraph() {
 			}()
 
 			beatmap.ParseTimingPointsAndPauses(m.timeCMap)
-			beatmap.ParseObjects(m.timeCMap, true, false)
+			beatmap.ParseObjects(m.timeCMap, true, false, false)
 
 			m.peaks = performance.GetDifficultyCalculator().CalculateStrainPeaks(m.timeCMap.HitObjects, m.timeCMap.Diff)
 
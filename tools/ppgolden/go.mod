@@ -0,0 +1,3 @@
+module github.com/wieku/danser-go/tools/ppgolden
+
+go 1.18
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// referenceScore is a score simulated with osu-tools for every fixture map and mod combination
+type referenceScore struct {
+	name string
+	mods []string
+	args []string
+}
+
+var referenceScores = []referenceScore{
+	{
+		name: "97.5_2",
+		mods: []string{"NM", "HD", "HR", "DT", "EZHT", "HDFL", "HRDT"},
+		args: []string{"-a", "97.5", "-X", "2"},
+	},
+	{
+		name: "fc",
+		mods: []string{"NM", "HDDT", "HRFL"},
+	},
+}
+
+// referenceDump is the output of "simulate osu <map> -j" command of osu-tools
+type referenceDump struct {
+	Score struct {
+		Mods []struct {
+			Acronym string `json:"acronym"`
+		} `json:"mods"`
+		Accuracy   float64        `json:"accuracy"`
+		Combo      int            `json:"combo"`
+		Statistics map[string]int `json:"statistics"`
+	} `json:"score"`
+
+	Performance struct {
+		Aim        float64 `json:"aim"`
+		Speed      float64 `json:"speed"`
+		Accuracy   float64 `json:"accuracy"`
+		Flashlight float64 `json:"flashlight"`
+		PP         float64 `json:"pp"`
+	} `json:"performance_attributes"`
+
+	Difficulty struct {
+		StarRating                float64 `json:"star_rating"`
+		MaxCombo                  int     `json:"max_combo"`
+		Aim                       float64 `json:"aim_difficulty"`
+		Speed                     float64 `json:"speed_difficulty"`
+		SpeedNoteCount            float64 `json:"speed_note_count"`
+		AimDifficultStrainCount   float64 `json:"aim_difficult_strain_count"`
+		SpeedDifficultStrainCount float64 `json:"speed_difficult_strain_count"`
+		Flashlight                float64 `json:"flashlight_difficulty"`
+		SliderFactor              float64 `json:"slider_factor"`
+		Circles                   int     `json:"hit_circle_count"`
+		Sliders                   int     `json:"slider_count"`
+		Spinners                  int     `json:"spinner_count"`
+	} `json:"difficulty_attributes"`
+}
+
+// Golden types mirror api.PerfScore, api.Attributes and api.PPv2Results of danser
+
+type goldenScore struct {
+	Accuracy     float64
+	MaxCombo     int
+	CountGreat   int
+	CountOk      int
+	CountMeh     int
+	CountMiss    int
+	SliderBreaks int
+	SliderEnd    int
+}
+
+type goldenAttributes struct {
+	Total                     float64
+	Aim                       float64
+	Speed                     float64
+	SpeedNoteCount            float64
+	AimDifficultStrainCount   float64
+	SpeedDifficultStrainCount float64
+	Flashlight                float64
+	SliderFactor              float64
+	ObjectCount               int
+	Circles                   int
+	Sliders                   int
+	Spinners                  int
+	MaxCombo                  int
+}
+
+type goldenPP struct {
+	Aim, Speed, Acc, Flashlight, Total float64
+}
+
+// goldenCase is an expected result read by difficulty calculator tests
+type goldenCase struct {
+	Map        string           `json:"map"`
+	Mods       string           `json:"mods"`
+	Score      goldenScore      `json:"score"`
+	Attributes goldenAttributes `json:"attributes"`
+	PP         goldenPP         `json:"pp"`
+}
+
+func main() {
+	references := flag.String("r", "../../app/rulesets/osu/performance/testdata/reference", "directory with osu-tools reference dumps, <pp version>/<map>/<mods>_<score>.json")
+	output := flag.String("o", "../../app/rulesets/osu/performance/testdata/golden", "directory for golden files")
+	maps := flag.String("maps", "../../app/rulesets/osu/performance/testdata/maps", "directory with fixture maps")
+	calculator := flag.String("calculator", "", "path to PerformanceCalculator project of osu-tools, if set reference dumps of -version are generated first")
+	version := flag.String("version", "", "pp version the osu-tools build given by -calculator implements, e.g. 241007")
+	flag.Parse()
+
+	if *calculator != "" {
+		if *version == "" {
+			flag.Usage()
+			os.Exit(1)
+		}
+
+		generateReferences(*calculator, *maps, filepath.Join(*references, *version))
+	}
+
+	versionDirs, err := os.ReadDir(*references)
+	if err != nil {
+		panic(err)
+	}
+
+	if err = os.MkdirAll(*output, 0755); err != nil {
+		panic(err)
+	}
+
+	for _, dir := range versionDirs {
+		if !dir.IsDir() {
+			continue
+		}
+
+		cases := readReferences(filepath.Join(*references, dir.Name()))
+
+		data, err := json.MarshalIndent(cases, "", "\t")
+		if err != nil {
+			panic(err)
+		}
+
+		path := filepath.Join(*output, dir.Name()+".json")
+
+		if err = os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+			panic(err)
+		}
+
+		log.Println(fmt.Sprintf("Written %d cases to %s", len(cases), path))
+	}
+}
+
+// generateReferences runs osu-tools for every fixture map and reference score
+func generateReferences(calculator, mapsDir, output string) {
+	build := exec.Command("dotnet", "build", "-c", "Release", calculator)
+	build.Stdout, build.Stderr = os.Stderr, os.Stderr
+
+	if err := build.Run(); err != nil {
+		panic(fmt.Errorf("failed to build osu-tools: %w", err))
+	}
+
+	maps, err := filepath.Glob(filepath.Join(mapsDir, "*.osu"))
+	if err != nil {
+		panic(err)
+	}
+
+	for _, mapPath := range maps {
+		mapName := strings.TrimSuffix(filepath.Base(mapPath), ".osu")
+
+		if err = os.MkdirAll(filepath.Join(output, mapName), 0755); err != nil {
+			panic(err)
+		}
+
+		for _, score := range referenceScores {
+			for _, mods := range score.mods {
+				args := []string{"run", "--no-build", "-c", "Release", "--project", calculator, "--", "simulate", "osu", mapPath, "-j"}
+
+				// danser calculates scores without Lazer mod with classic slider accuracy, osu!lazer needs Classic mod for that
+				args = append(args, "-m", "CL")
+
+				if mods != "NM" {
+					for i := 0; i+1 < len(mods); i += 2 {
+						args = append(args, "-m", mods[i:i+2])
+					}
+				}
+
+				args = append(args, score.args...)
+
+				simulate := exec.Command("dotnet", args...)
+				simulate.Stderr = os.Stderr
+
+				data, err := simulate.Output()
+				if err != nil {
+					panic(fmt.Errorf("osu-tools failed on %s %s: %w", mapName, mods, err))
+				}
+
+				path := filepath.Join(output, mapName, mods+"_"+score.name+".json")
+
+				if err = os.WriteFile(path, data, 0644); err != nil {
+					panic(err)
+				}
+
+				log.Println("Written", path)
+			}
+		}
+	}
+}
+
+// readReferences converts reference dumps of a single pp version to golden cases
+func readReferences(dir string) (cases []goldenCase) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*", "*.json"))
+	if err != nil {
+		panic(err)
+	}
+
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			panic(err)
+		}
+
+		var dump referenceDump
+
+		if err = json.Unmarshal(data, &dump); err != nil {
+			panic(fmt.Errorf("failed to parse %s: %w", path, err))
+		}
+
+		cases = append(cases, toGoldenCase(filepath.Base(filepath.Dir(path))+".osu", dump))
+	}
+
+	return
+}
+
+func toGoldenCase(mapName string, dump referenceDump) goldenCase {
+	// Statistics keys are HitResult names, depending on osu-tools version they are either "LargeTickMiss" or "large_tick_miss"
+	statistics := make(map[string]int)
+	for key, value := range dump.Score.Statistics {
+		statistics[strings.ToLower(strings.ReplaceAll(key, "_", ""))] = value
+	}
+
+	var mods string
+
+	for _, mod := range dump.Score.Mods {
+		if mod.Acronym != "CL" {
+			mods += mod.Acronym
+		}
+	}
+
+	diff := dump.Difficulty
+
+	sliderEnd := diff.Sliders
+	if count, ok := statistics["slidertailhit"]; ok {
+		sliderEnd = count
+	}
+
+	return goldenCase{
+		Map:  mapName,
+		Mods: mods,
+		Score: goldenScore{
+			Accuracy:     dump.Score.Accuracy / 100,
+			MaxCombo:     dump.Score.Combo,
+			CountGreat:   statistics["great"],
+			CountOk:      statistics["ok"],
+			CountMeh:     statistics["meh"],
+			CountMiss:    statistics["miss"],
+			SliderBreaks: statistics["largetickmiss"],
+			SliderEnd:    sliderEnd,
+		},
+		Attributes: goldenAttributes{
+			Total:                     diff.StarRating,
+			Aim:                       diff.Aim,
+			Speed:                     diff.Speed,
+			SpeedNoteCount:            diff.SpeedNoteCount,
+			AimDifficultStrainCount:   diff.AimDifficultStrainCount,
+			SpeedDifficultStrainCount: diff.SpeedDifficultStrainCount,
+			Flashlight:                diff.Flashlight,
+			SliderFactor:              diff.SliderFactor,
+			ObjectCount:               diff.Circles + diff.Sliders + diff.Spinners,
+			Circles:                   diff.Circles,
+			Sliders:                   diff.Sliders,
+			Spinners:                  diff.Spinners,
+			MaxCombo:                  diff.MaxCombo,
+		},
+		PP: goldenPP{
+			Aim:        dump.Performance.Aim,
+			Speed:      dump.Performance.Speed,
+			Acc:        dump.Performance.Accuracy,
+			Flashlight: dump.Performance.Flashlight,
+			Total:      dump.Performance.PP,
+		},
+	}
+}